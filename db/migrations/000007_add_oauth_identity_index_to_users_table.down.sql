@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_oauth_identity;
+
+ALTER TABLE users
+ADD CONSTRAINT users_oauth_id_key UNIQUE (oauth_id);
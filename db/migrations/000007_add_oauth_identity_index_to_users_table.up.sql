@@ -0,0 +1,5 @@
+-- Subjects are only unique within a provider
+ALTER TABLE users
+DROP CONSTRAINT IF EXISTS users_oauth_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oauth_identity ON users (oauth_provider, oauth_id);
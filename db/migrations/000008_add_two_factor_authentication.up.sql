@@ -0,0 +1,13 @@
+ALTER TABLE users
+ADD COLUMN totp_secret BYTEA,
+ADD COLUMN totp_enabled_at TIMESTAMPTZ,
+ADD COLUMN totp_last_step BIGINT;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id UUID DEFAULT gen_random_uuid () PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL, -- SHA-256 of the code, the code itself is never stored
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
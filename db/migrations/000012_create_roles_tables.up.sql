@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(100) PRIMARY KEY, -- resource:action, e.g. users:manage
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions (name) ON DELETE CASCADE ON UPDATE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL REFERENCES roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX idx_user_roles_role ON user_roles (role);
+
+INSERT INTO roles (name, description)
+VALUES ('admin', 'Manages users and their roles');
+
+INSERT INTO permissions (name, description)
+VALUES ('users:manage', 'List users and change their roles');
+
+INSERT INTO role_permissions (role, permission)
+VALUES ('admin', 'users:manage');
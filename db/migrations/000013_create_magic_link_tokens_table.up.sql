@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS magic_link_tokens (
+    id UUID DEFAULT gen_random_uuid () PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 of the token, the token itself is never stored
+    nonce_hash CHAR(64) NOT NULL, -- SHA-256 of the nonce cookie of the browser that asked for the link
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_magic_link_tokens_user_id ON magic_link_tokens (user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    id UUID DEFAULT gen_random_uuid () PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 of the token, the token itself is never stored
+    role VARCHAR(50) REFERENCES roles (name) ON DELETE SET NULL ON UPDATE CASCADE, -- assigned on sign up
+    invited_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_invitations_email ON invitations (LOWER(email));
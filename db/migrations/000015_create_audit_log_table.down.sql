@@ -0,0 +1,9 @@
+DELETE FROM permissions WHERE name = 'audit:read';
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+
+DROP FUNCTION IF EXISTS reject_audit_log_change;
+
+DROP TABLE IF EXISTS audit_log;
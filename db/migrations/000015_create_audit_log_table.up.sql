@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(100) NOT NULL, -- resource.event, e.g. user.signin
+    actor_id UUID, -- not a foreign key, so that the entries outlive the users
+    target_id VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address INET,
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at);
+
+CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id);
+
+CREATE INDEX idx_audit_log_target_id ON audit_log (target_id);
+
+CREATE INDEX idx_audit_log_action ON audit_log (action);
+
+-- Entries can be added but never changed or removed
+CREATE OR REPLACE FUNCTION reject_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION reject_audit_log_change();
+
+CREATE TRIGGER audit_log_no_truncate
+BEFORE TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_log_change();
+
+INSERT INTO permissions (name, description)
+VALUES ('audit:read', 'View and export the audit log');
+
+INSERT INTO role_permissions (role, permission)
+VALUES ('admin', 'audit:read');
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS remember_tokens (
+    selector VARCHAR(32) PRIMARY KEY, -- looks the token up, the validator is checked against the hash
+    validator_hash CHAR(64) NOT NULL, -- SHA-256 of the validator, the validator itself is never stored
+    family_id UUID NOT NULL DEFAULT gen_random_uuid (), -- shared by the tokens that replaced each other since the sign in
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    family_expires_at TIMESTAMPTZ NOT NULL, -- set at the sign in, no token of the family outlives it however often it is replaced
+    rotated_at TIMESTAMPTZ, -- set when the token is replaced, kept to detect its reuse
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_remember_tokens_family_id ON remember_tokens (family_id);
+
+CREATE INDEX idx_remember_tokens_user_id ON remember_tokens (user_id);
+
+CREATE INDEX idx_remember_tokens_expires_at ON remember_tokens (expires_at);
@@ -1,12 +1,18 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/audit"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/auth"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/client"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/html"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/middleware"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/lockout"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
 	"github.com/ferdiebergado/goexpress"
 )
 
@@ -16,22 +22,38 @@ type App struct {
 	router         *goexpress.Router
 	htmlTemplate   *html.Template
 	sessionManager session.Manager
+	mailer         mail.Mailer
+	lockoutStore   lockout.Store
+	auditLog       *audit.Log
+	authService    auth.Service
 }
 
-func New(cfg *config.Config, conn *sql.DB, router *goexpress.Router, htmlTmpl *html.Template, sessMgr session.Manager) *App {
+func New(cfg *config.Config, conn *sql.DB, router *goexpress.Router, htmlTmpl *html.Template, sessMgr session.Manager, mailer mail.Mailer, lockoutStore lockout.Store, auditLog *audit.Log) *App {
 	return &App{
 		cfg:            cfg,
 		db:             conn,
 		router:         router,
 		htmlTemplate:   htmlTmpl,
 		sessionManager: sessMgr,
+		mailer:         mailer,
+		lockoutStore:   lockoutStore,
+		auditLog:       auditLog,
 	}
 }
 
-func (a *App) registerGlobalMiddlewares() {
+// Routes that never need the signed in user, like the ones polled by monitoring
+var skipUserPaths = []string{"/api/health"}
+
+func (a *App) registerGlobalMiddlewares(authService auth.Service) {
 	a.router.Use(goexpress.LogRequest)
 	a.router.Use(goexpress.StripTrailingSlashes)
-	a.router.Use(goexpress.Middleware(auth.SessionMiddleware(a.cfg.Session, a.sessionManager)))
+	a.router.Use(goexpress.Middleware(middleware.RequestID()))
+	a.router.Use(goexpress.Middleware(client.Middleware(a.cfg.Server.TrustedProxies)))
+	a.router.Use(goexpress.Middleware(auth.SessionMiddleware(a.cfg.Session, a.sessionManager, authService)))
+	a.router.Use(goexpress.Middleware(auth.BearerMiddleware(authService)))
+	a.router.Use(goexpress.Middleware(auth.AuthorizationMiddleware(authService, skipUserPaths...)))
+	a.router.Use(goexpress.Middleware(auth.CurrentUserMiddleware(authService, skipUserPaths...)))
+	a.router.Use(goexpress.Middleware(middleware.CSRF(a.cfg.Session)))
 	a.router.Use(goexpress.RecoverFromPanic)
 }
 
@@ -41,14 +63,29 @@ func (a *App) AddBaseHandler() *BaseHandler {
 	return NewHandler(a.router, service, a.cfg, a.htmlTemplate)
 }
 
-func (a *App) AddAuthHandler() *auth.Handler {
+func (a *App) NewAuthService() auth.Service {
 	repo := auth.NewAuthRepo(&a.cfg.DB, a.db)
-	service := auth.NewAuthService(a.cfg, repo)
-	return auth.NewHandler(a.cfg, a.router, service, a.htmlTemplate, a.sessionManager)
+	return auth.NewAuthService(a.cfg, repo, a.mailer, a.auditLog)
+}
+
+func (a *App) AddAuthHandler(service auth.Service) *auth.Handler {
+	providers := auth.NewOIDCProviders(a.cfg, nil)
+	return auth.NewHandler(a.cfg, a.router, service, a.htmlTemplate, a.sessionManager, providers, a.lockoutStore, a.auditLog)
 }
 
 func (a *App) SetupRouter() {
-	a.registerGlobalMiddlewares()
+	a.authService = a.NewAuthService()
+
+	a.registerGlobalMiddlewares(a.authService)
 	registerBaseRoutes(a.router, a.AddBaseHandler(), a.sessionManager)
-	auth.RegisterAuthRoutes(a.router, a.AddAuthHandler(), a.sessionManager)
+	auth.RegisterAuthRoutes(a.router, a.AddAuthHandler(a.authService), a.sessionManager)
+}
+
+// Waits for the work that the handlers left running after their responses, see auth.Service.Shutdown
+func (a *App) Shutdown(ctx context.Context) error {
+	if a.authService == nil {
+		return nil
+	}
+
+	return a.authService.Shutdown(ctx)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/auth"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/html"
@@ -27,8 +28,8 @@ func NewHandler(router *goexpress.Router, service Service, cfg *config.Config, h
 	}
 }
 
-func (h *BaseHandler) HandleDashboard(w http.ResponseWriter, _ *http.Request) {
-	h.htmlTemplate.Render(w, "dashboard.html", nil)
+func (h *BaseHandler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
+	h.htmlTemplate.Render(w, "dashboard.html", auth.NewPageData(r, "Dashboard"))
 }
 
 func (h *BaseHandler) HandleDBStats(w http.ResponseWriter, _ *http.Request) {
@@ -7,7 +7,7 @@ import (
 )
 
 func registerBaseRoutes(router *goexpress.Router, handler *BaseHandler, sessMgr session.Manager) {
-	router.Get("/dashboard", handler.HandleDashboard, goexpress.Middleware(auth.RequireUserMiddleware(sessMgr)))
+	router.Get("/dashboard", handler.HandleDashboard, goexpress.Middleware(auth.RequireUserMiddleware(handler.config.Session, sessMgr)))
 	router.Get("/dbstats", handler.HandleDBStats)
 	router.Get("/api/health", handler.HandleHealthCheck)
 }
@@ -2,18 +2,26 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/audit"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/auth"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/html"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/server"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/lockout"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/logging"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/worker"
 	"github.com/ferdiebergado/goexpress"
 )
 
@@ -24,7 +32,19 @@ func Run(ctx context.Context) error {
 	slog.Info("Running application...")
 
 	// Load config
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if len(cfg.Auth.SigningKey) == 0 {
+		slog.Warn("APP_KEY is not set, using a random key. Links sent by email will stop working on restart.")
+		key, err := security.GenerateRandomBytes(32)
+		if err != nil {
+			return err
+		}
+		cfg.Auth.SigningKey = key
+	}
 
 	// Connect to the database.
 	conn, err := db.Connect(ctx, cfg.DB)
@@ -34,35 +54,92 @@ func Run(ctx context.Context) error {
 
 	// WaitGroup to wait for all shutdown tasks to complete
 	var wg sync.WaitGroup
-	wg.Add(3)
 
 	// Register OS Signal Listener
 	dbSignalCtx, dbCancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer dbCancel()
 
-	// Goroutine to handle database connection closure on signal
-	go db.WaitDisconnect(dbSignalCtx, &wg, conn)
-
 	// Create the application
 	idleConnsClosed := make(chan struct{})
-	sessionManager := session.NewDatabaseSession(cfg.Session, conn)
+	sessionManager, err := session.New(cfg.Session, conn)
+	if err != nil {
+		return err
+	}
+
+	// Goroutine to purge expired sessions until shutdown, when the store keeps them
+	if sweeper, ok := sessionManager.(session.Sweeper); ok {
+		wg.Add(1)
+		go worker.RunEvery(dbSignalCtx, &wg, cfg.Session.CleanUpInterval, "Session sweeper", sweeper.DeleteExpired)
+	}
+
+	lockoutStore, err := lockout.New(cfg.Lockout, conn)
+	if err != nil {
+		return err
+	}
+
+	// Goroutine to purge expired sign in failures until shutdown
+	wg.Add(1)
+	go worker.RunEvery(dbSignalCtx, &wg, cfg.Lockout.CleanUpInterval, "Lockout sweeper", func(ctx context.Context) (int64, error) {
+		return lockoutStore.DeleteExpired(ctx, cfg.Lockout.Window)
+	})
+
+	authRepo := auth.NewAuthRepo(&cfg.DB, conn)
+
+	// Goroutine to remove deleted accounts after their grace period until shutdown
+	wg.Add(1)
+	go worker.RunEvery(dbSignalCtx, &wg, cfg.Auth.AccountPurgeInterval, "Account purger", func(ctx context.Context) (int64, error) {
+		return authRepo.PurgeDeletedUsers(ctx, time.Now().Add(-cfg.Auth.AccountPurgeAfter))
+	})
+
+	// Goroutine to remove expired remember me tokens until shutdown
+	wg.Add(1)
+	go worker.RunEvery(dbSignalCtx, &wg, cfg.Session.CleanUpInterval, "Remember me token purger", authRepo.PurgeExpiredRememberTokens)
+
+	auditLog := audit.New(cfg.Audit, audit.NewDatabaseStore(conn))
+
+	// Stopped only after the server, so that the events of the requests that were still running are written
+	auditCtx, auditCancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer auditCancel()
+
+	// Goroutine to write the audit log in the background until shutdown
+	wg.Add(1)
+	go auditLog.Run(auditCtx, &wg)
+
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		return err
+	}
+
 	htmlTemplate := html.NewTemplate(&cfg.HTML)
 	router := goexpress.New()
-	application := New(cfg, conn, router, htmlTemplate, sessionManager)
+	application := New(cfg, conn, router, htmlTemplate, sessionManager, mailer, lockoutStore, auditLog)
 	application.SetupRouter()
 
 	// Start the httpServer
 	httpServer := server.New(&cfg.Server, router)
 
 	// Goroutine to handle server shutdown on signal
+	wg.Add(1)
 	go httpServer.WaitForShutdown(&wg, idleConnsClosed)
 
 	// Start the server
 	go httpServer.Start()
 
-	// Block until both database and server shutdown are complete
+	// Block until the server and the background jobs are done
 	<-idleConnsClosed // Wait for server to shut down
-	wg.Wait()         // Wait for all shutdown tasks (including database)
+
+	// Mails still being sent for the last requests use the database and record events in the audit log
+	tasksCtx, tasksCancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.Server.ShutdownTimeout)
+	if err := application.Shutdown(tasksCtx); err != nil {
+		slog.Error("Background tasks did not finish before shutdown", "error", err)
+	}
+	tasksCancel()
+
+	auditCancel() // No requests are left to record events of
+	wg.Wait()     // Wait for all shutdown tasks, including the last audit log flush
+
+	// Closed last, since the background jobs and the audit log use it until they stop
+	db.Disconnect(conn)
 	slog.Info("All shutdown tasks completed. Exiting.")
 
 	return nil
@@ -1,6 +1,8 @@
 package user
 
 import (
+	"time"
+
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
 )
 
@@ -13,9 +15,10 @@ const (
 
 type User struct {
 	db.Model
-	Email         string     `json:"email"`
-	OAuthProvider *string    `json:"oauth_provider,omitempty"`
-	OAuthID       *string    `json:"oauth_id,omitempty"`
-	PasswordHash  *string    `json:"-"`
-	AuthMethod    AuthMethod `json:"auth_method"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	OAuthProvider   *string    `json:"oauth_provider,omitempty"`
+	OAuthID         *string    `json:"oauth_id,omitempty"`
+	PasswordHash    *string    `json:"-"`
+	AuthMethod      AuthMethod `json:"auth_method"`
 }
@@ -0,0 +1,223 @@
+// Package audit keeps an append-only record of the security relevant events, like sign ins and role changes.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/client"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/middleware"
+)
+
+// Event is something that happened that the admins may need to look into later.
+type Event struct {
+	Action   string         // resource.event, e.g. user.signin
+	ActorID  string         // Empty when nobody is signed in, e.g. for a failed sign in
+	TargetID string         // What the event is about, e.g. the user whose role was changed
+	Payload  map[string]any // Details of the event, never secrets like passwords or tokens
+}
+
+// Entry is an event as it is stored, along with where its request came from.
+type Entry struct {
+	ID          int64           `json:"id"`
+	Action      string          `json:"action"`
+	ActorID     string          `json:"actor_id,omitempty"`
+	ActorEmail  string          `json:"actor_email,omitempty"` // Set when listing, while the actor exists
+	TargetID    string          `json:"target_id,omitempty"`
+	TargetEmail string          `json:"target_email,omitempty"` // Set when listing, when the target is an existing user
+	IPAddress   string          `json:"ip_address,omitempty"`
+	UserAgent   string          `json:"user_agent,omitempty"`
+	RequestID   string          `json:"request_id,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Filter selects the entries to list. The zero value selects all of them.
+type Filter struct {
+	Action string    // The action or its resource, e.g. user matches user.signin
+	Actor  string    // Id or email of the actor
+	Target string    // Id of the target, or email of the target user
+	From   time.Time // Inclusive, zero for no lower bound
+	To     time.Time // Exclusive, zero for no upper bound
+	Limit  int       // 0 for no limit
+	Offset int
+}
+
+// Recorder records events. Implementations must not hold up the caller.
+type Recorder interface {
+	Record(ctx context.Context, event Event)
+}
+
+// Store keeps the entries. Entries can only be added.
+type Store interface {
+	// Saves the entries in one go.
+	Append(ctx context.Context, entries []Entry) error
+
+	// Lists the entries that match the filter, newest first.
+	List(ctx context.Context, filter Filter) ([]Entry, error)
+
+	// Calls fn with each entry that matches the filter, newest first, without loading them all at once.
+	// Stops at the first error of fn.
+	Export(ctx context.Context, filter Filter, fn func(Entry) error) error
+}
+
+// Log records the events in the background, so that a slow database never holds up a request.
+//
+// Events are queued and written in batches by Run. When the queue is full, or a batch cannot be
+// written, the entries go to the application log instead, so that they are not lost without a trace.
+// So do the events recorded after Run returned.
+type Log struct {
+	store Store
+	cfg   config.AuditConfig
+	queue chan Entry
+
+	mu      sync.RWMutex // Held for writing while the writer stops, so that no entry is queued after its last flush
+	stopped bool
+}
+
+var _ Recorder = (*Log)(nil)
+
+func New(cfg config.AuditConfig, store Store) *Log {
+	return &Log{
+		store: store,
+		cfg:   cfg,
+		queue: make(chan Entry, max(cfg.BufferSize, 1)),
+	}
+}
+
+// Queues the event with the client and the id of the request in the context.
+func (l *Log) Record(ctx context.Context, event Event) {
+	info, _ := client.FromContext(ctx)
+
+	entry := Entry{
+		Action:    event.Action,
+		ActorID:   event.ActorID,
+		TargetID:  event.TargetID,
+		IPAddress: info.IP,
+		UserAgent: info.UserAgent,
+		RequestID: middleware.RequestIDFromContext(ctx),
+		Payload:   json.RawMessage("{}"),
+		CreatedAt: time.Now(),
+	}
+
+	if len(event.Payload) > 0 {
+		if payload, err := json.Marshal(event.Payload); err != nil {
+			slog.Error("failed to encode the audit payload", "action", event.Action, "error", err)
+		} else {
+			entry.Payload = payload
+		}
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.stopped {
+		slog.Warn("audit log writer is stopped, the entry was not stored", entryAttrs(entry)...)
+		return
+	}
+
+	select {
+	case l.queue <- entry:
+	default:
+		slog.Warn("audit log queue is full, the entry was not stored", entryAttrs(entry)...)
+	}
+}
+
+// Writes the queued entries until the context is done, then writes the entries that are left.
+func (l *Log) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	slog.Info("Audit log writer started", "buffer_size", cap(l.queue))
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.mu.Lock()
+			l.stopped = true
+			l.mu.Unlock()
+
+			// Written with a context of their own, since this one is done
+			for l.flush(context.Background()) > 0 {
+			}
+
+			slog.Info("Audit log writer stopped.")
+			return
+		case entry := <-l.queue:
+			batch := l.collect(entry)
+			l.write(ctx, batch)
+		}
+	}
+}
+
+// Writes a batch of the entries that are waiting, returns how many there were
+func (l *Log) flush(ctx context.Context) int {
+	select {
+	case entry := <-l.queue:
+		batch := l.collect(entry)
+		l.write(ctx, batch)
+		return len(batch)
+	default:
+		return 0
+	}
+}
+
+// Takes the entries that are waiting behind the first one, up to the batch size
+func (l *Log) collect(first Entry) []Entry {
+	batch := []Entry{first}
+
+	for len(batch) < max(l.cfg.BatchSize, 1) {
+		select {
+		case entry := <-l.queue:
+			batch = append(batch, entry)
+		default:
+			return batch
+		}
+	}
+
+	return batch
+}
+
+func (l *Log) write(ctx context.Context, batch []Entry) {
+	ctx = context.WithoutCancel(ctx)
+
+	if l.cfg.WriteTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.cfg.WriteTimeout)
+		defer cancel()
+	}
+
+	if err := l.store.Append(ctx, batch); err != nil {
+		slog.Error("failed to write the audit log", "count", len(batch), "error", err)
+
+		for _, entry := range batch {
+			slog.Warn("audit log entry was not stored", entryAttrs(entry)...)
+		}
+	}
+}
+
+// Lists the stored entries that match the filter, newest first.
+func (l *Log) List(ctx context.Context, filter Filter) ([]Entry, error) {
+	return l.store.List(ctx, filter)
+}
+
+// Calls fn with each stored entry that matches the filter, newest first.
+func (l *Log) Export(ctx context.Context, filter Filter, fn func(Entry) error) error {
+	return l.store.Export(ctx, filter, fn)
+}
+
+func entryAttrs(entry Entry) []any {
+	return []any{
+		"action", entry.Action,
+		"actor_id", entry.ActorID,
+		"target_id", entry.TargetID,
+		"ip_address", entry.IPAddress,
+		"user_agent", entry.UserAgent,
+		"request_id", entry.RequestID,
+		"payload", string(entry.Payload),
+		"created_at", entry.CreatedAt,
+	}
+}
@@ -0,0 +1,212 @@
+//go:build !integration
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/client"
+)
+
+// Keeps the entries in memory. Append blocks until release is closed when it is set.
+type fakeStore struct {
+	mu      sync.Mutex
+	entries []Entry
+	batches int
+	release chan struct{}
+	err     error
+}
+
+func (s *fakeStore) Append(_ context.Context, entries []Entry) error {
+	if s.release != nil {
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	s.entries = append(s.entries, entries...)
+	s.batches++
+
+	return nil
+}
+
+func (s *fakeStore) List(context.Context, Filter) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Entry(nil), s.entries...), nil
+}
+
+func (s *fakeStore) Export(ctx context.Context, filter Filter, fn func(Entry) error) error {
+	entries, _ := s.List(ctx, filter)
+
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func testConfig(bufferSize int) config.AuditConfig {
+	return config.AuditConfig{
+		BufferSize:   bufferSize,
+		BatchSize:    10,
+		WriteTimeout: time.Second,
+	}
+}
+
+// Starts the writer and returns a function that stops it and waits until it is done
+func run(l *Log) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go l.Run(ctx, &wg)
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func TestLogRecord(t *testing.T) {
+	store := &fakeStore{}
+	l := New(testConfig(10), store)
+
+	ctx := client.WithInfo(context.Background(), client.Info{IP: "203.0.113.7", UserAgent: "test-agent"})
+
+	l.Record(ctx, Event{
+		Action:   "role.assigned",
+		ActorID:  "admin",
+		TargetID: "user",
+		Payload:  map[string]any{"role": "admin"},
+	})
+	l.Record(context.Background(), Event{Action: "user.signin_failed"})
+
+	run(l)()
+
+	entries, _ := l.List(context.Background(), Filter{})
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	got := entries[0]
+
+	if got.Action != "role.assigned" || got.ActorID != "admin" || got.TargetID != "user" {
+		t.Errorf("unexpected entry %+v", got)
+	}
+
+	if got.IPAddress != "203.0.113.7" || got.UserAgent != "test-agent" {
+		t.Errorf("expected the client of the context, got %q %q", got.IPAddress, got.UserAgent)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(got.Payload, &payload); err != nil || payload["role"] != "admin" {
+		t.Errorf("unexpected payload %s: %v", got.Payload, err)
+	}
+
+	if string(entries[1].Payload) != "{}" {
+		t.Errorf("expected an empty payload object, got %s", entries[1].Payload)
+	}
+
+	if got.CreatedAt.IsZero() {
+		t.Error("expected the time of the event")
+	}
+}
+
+func TestLogRecordDoesNotBlock(t *testing.T) {
+	store := &fakeStore{release: make(chan struct{})}
+	l := New(testConfig(2), store)
+	stop := run(l)
+
+	done := make(chan struct{})
+
+	go func() {
+		// The store is stuck, so most of these do not fit in the queue
+		for range 50 {
+			l.Record(context.Background(), Event{Action: "user.signin"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record waited for the store")
+	}
+
+	close(store.release)
+	stop()
+
+	entries, _ := l.List(context.Background(), Filter{})
+
+	if len(entries) == 0 || len(entries) > 3 {
+		t.Errorf("expected the entries that fit in the queue to be stored, got %d", len(entries))
+	}
+}
+
+func TestLogRunWritesBatches(t *testing.T) {
+	store := &fakeStore{}
+	l := New(testConfig(100), store)
+
+	for range 25 {
+		l.Record(context.Background(), Event{Action: "user.signin"})
+	}
+
+	run(l)()
+
+	entries, _ := l.List(context.Background(), Filter{})
+
+	if len(entries) != 25 {
+		t.Fatalf("expected 25 entries, got %d", len(entries))
+	}
+
+	if store.batches != 3 {
+		t.Errorf("expected 3 batches of at most 10, got %d", store.batches)
+	}
+}
+
+func TestLogWriteFailure(t *testing.T) {
+	store := &fakeStore{err: errors.New("database is down")}
+	l := New(testConfig(10), store)
+
+	l.Record(context.Background(), Event{Action: "user.signin"})
+
+	// The entry goes to the application log and the writer keeps going
+	run(l)()
+
+	entries, _ := l.List(context.Background(), Filter{})
+
+	if len(entries) != 0 {
+		t.Errorf("expected no stored entries, got %d", len(entries))
+	}
+}
+
+func TestLogRecordAfterStop(t *testing.T) {
+	store := &fakeStore{}
+	l := New(testConfig(10), store)
+
+	run(l)()
+
+	// Goes to the application log instead of a queue that nobody reads anymore
+	l.Record(context.Background(), Event{Action: "user.signout"})
+
+	if len(l.queue) != 0 {
+		t.Errorf("expected nothing to be queued after the writer stopped, got %d", len(l.queue))
+	}
+}
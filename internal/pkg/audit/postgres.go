@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DatabaseStore keeps the entries in the audit_log table, which rejects updates and deletes.
+type DatabaseStore struct {
+	db *sql.DB
+}
+
+var _ Store = (*DatabaseStore)(nil)
+
+func NewDatabaseStore(db *sql.DB) Store {
+	return &DatabaseStore{db: db}
+}
+
+// Columns of an entry, in the order of the placeholders of appendQuery
+const entryColumns = 8
+
+const appendQuery = `
+INSERT INTO audit_log (action, actor_id, target_id, ip_address, user_agent, request_id, payload, created_at)
+VALUES `
+
+func (d *DatabaseStore) Append(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var q strings.Builder
+	q.WriteString(appendQuery)
+
+	args := make([]any, 0, len(entries)*entryColumns)
+
+	for i, e := range entries {
+		if i > 0 {
+			q.WriteString(", ")
+		}
+
+		n := i * entryColumns
+		fmt.Fprintf(&q, "($%d, NULLIF($%d, '')::uuid, $%d, NULLIF($%d, '')::inet, $%d, $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8)
+
+		args = append(args, e.Action, e.ActorID, e.TargetID, e.IPAddress, e.UserAgent, e.RequestID, []byte(e.Payload), e.CreatedAt)
+	}
+
+	if _, err := d.db.ExecContext(ctx, q.String(), args...); err != nil {
+		return fmt.Errorf("append audit entries: %w", err)
+	}
+
+	return nil
+}
+
+// The emails are looked up when listing, so that the entries keep the ids only
+const listQuery = `
+SELECT a.id, a.action, COALESCE(a.actor_id::text, ''), COALESCE(actor.email, ''),
+a.target_id, COALESCE(target.email, ''), COALESCE(HOST(a.ip_address), ''),
+a.user_agent, a.request_id, a.payload, a.created_at
+FROM audit_log a
+LEFT JOIN users actor ON actor.id = a.actor_id
+LEFT JOIN users target ON target.id::text = a.target_id
+WHERE ($1 = '' OR a.action = $1 OR STARTS_WITH(a.action, $1 || '.'))
+AND ($2 = '' OR a.actor_id::text = $2 OR LOWER(actor.email) = LOWER($2))
+AND ($3 = '' OR a.target_id = $3 OR LOWER(target.email) = LOWER($3))
+AND ($4::timestamptz IS NULL OR a.created_at >= $4)
+AND ($5::timestamptz IS NULL OR a.created_at < $5)
+ORDER BY a.id DESC
+LIMIT NULLIF($6, 0) OFFSET $7
+`
+
+func (d *DatabaseStore) List(ctx context.Context, filter Filter) ([]Entry, error) {
+	entries := []Entry{}
+
+	err := d.Export(ctx, filter, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (d *DatabaseStore) Export(ctx context.Context, filter Filter, fn func(Entry) error) error {
+	rows, err := d.db.QueryContext(ctx, listQuery,
+		strings.TrimSpace(filter.Action), strings.TrimSpace(filter.Actor), strings.TrimSpace(filter.Target),
+		nullTime(filter.From), nullTime(filter.To), filter.Limit, filter.Offset)
+
+	if err != nil {
+		return fmt.Errorf("list audit entries: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Entry
+		var payload []byte
+
+		if err := rows.Scan(&e.ID, &e.Action, &e.ActorID, &e.ActorEmail, &e.TargetID, &e.TargetEmail,
+			&e.IPAddress, &e.UserAgent, &e.RequestID, &payload, &e.CreatedAt); err != nil {
+			return fmt.Errorf("scan audit entry: %w", err)
+		}
+
+		e.Payload = payload
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,70 @@
+//go:build integration
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+)
+
+func TestDatabaseStore(t *testing.T) {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("can't load the config: %v", err)
+	}
+
+	conn, err := db.Connect(ctx, cfg.DB)
+	if err != nil {
+		t.Fatalf("can't connect to the database: %v", err)
+	}
+
+	defer conn.Close()
+
+	store := NewDatabaseStore(conn)
+	action := "test." + time.Now().Format("150405.000000")
+
+	entries := []Entry{
+		{Action: action + ".first", TargetID: "target-1", IPAddress: "203.0.113.7", Payload: json.RawMessage(`{"n":1}`), CreatedAt: time.Now()},
+		{Action: action + ".second", TargetID: "target-2", Payload: json.RawMessage(`{}`), CreatedAt: time.Now()},
+	}
+
+	if err := store.Append(ctx, entries); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	got, err := store.List(ctx, Filter{Action: action})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	if len(got) != 2 || got[0].Action != action+".second" {
+		t.Fatalf("expected both entries newest first, got %+v", got)
+	}
+
+	if got[1].IPAddress != "203.0.113.7" || string(got[1].Payload) != `{"n": 1}` {
+		t.Errorf("unexpected entry %+v", got[1])
+	}
+
+	got, err = store.List(ctx, Filter{Action: action, Target: "target-1"})
+	if err != nil || len(got) != 1 {
+		t.Errorf("expected one entry for the target, got %d: %v", len(got), err)
+	}
+
+	got, err = store.List(ctx, Filter{Action: action, From: time.Now().Add(time.Hour)})
+	if err != nil || len(got) != 0 {
+		t.Errorf("expected no entries from the future, got %d: %v", len(got), err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "DELETE FROM audit_log WHERE action LIKE $1", action+"%"); err == nil {
+		t.Error("expected the entries to be append-only")
+	}
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+	"github.com/ferdiebergado/gopherkit/http/request"
+)
+
+const profileEmailPath = profilePath + "/email"
+
+type accountData struct {
+	response.PageData
+	HasPassword    bool
+	PurgeAfterDays int
+}
+
+// Renders a page of the account forms of the signed in user
+func (h *Handler) renderAccountPage(w http.ResponseWriter, r *http.Request, page, title string) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	hasPassword, err := h.service.HasPassword(r.Context(), userID)
+
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			response.RenderError(w, r, errtypes.AuthenticationError(err))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	data := &accountData{
+		PageData:       NewPageData(r, title),
+		HasPassword:    hasPassword,
+		PurgeAfterDays: int(h.config.Auth.AccountPurgeAfter.Hours() / 24),
+	}
+
+	h.htmlTemplate.Render(w, page, data)
+}
+
+func (h *Handler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	h.renderAccountPage(w, r, "change-password.html", "Change Password")
+}
+
+func (h *Handler) HandleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	h.renderAccountPage(w, r, "change-email.html", "Change Email")
+}
+
+func (h *Handler) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	h.renderAccountPage(w, r, "delete-account.html", "Delete Account")
+}
+
+// Changes the password of the signed in user and signs out their other sessions
+func (h *Handler) HandleChangePasswordForm(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	params, err := request.JSON[ChangePasswordParams](r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	err = h.checkPasswordLocked(r, userID, func() error {
+		return h.service.ChangePassword(r.Context(), userID, params)
+	})
+
+	if err != nil {
+		renderAccountError(w, r, err)
+		return
+	}
+
+	msg := "Your password has been changed. Your other sessions have been signed out."
+
+	// Sessions opened with the old password may belong to whoever made the user change it
+	if sessionID, err := h.sessionManager.ExtractSessionID(r); err != nil {
+		slog.Error("failed to read the session after a password change", "user_id", userID, "error", err)
+	} else if revoked, err := revokeUserSessions(r.Context(), h.sessionManager, userID, sessionID); err != nil {
+		slog.Error("failed to revoke sessions after a password change", "user_id", userID, "error", err)
+	} else if !revoked {
+		msg = "Your password has been changed. Your other sessions stay signed in until they expire."
+	}
+
+	h.forgetUser(w, r, userID, true)
+
+	res := &response.APIResponse[map[string]string]{
+		Message: msg,
+		Data: &map[string]string{
+			"redirectUrl": profilePath,
+		},
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Sends a link that confirms the new email of the signed in user
+func (h *Handler) HandleChangeEmailForm(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	params, err := request.JSON[ChangeEmailParams](r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	err = h.checkPasswordLocked(r, userID, func() error {
+		return h.service.RequestEmailChange(r.Context(), userID, params)
+	})
+
+	if err != nil {
+		renderAccountError(w, r, err)
+		return
+	}
+
+	res := &response.APIResponse[any]{
+		Message: "A confirmation link has been sent to your new email address.",
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Changes the email from the signed link sent to the new address
+func (h *Handler) HandleConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	params := ConfirmEmailChangeParams{
+		UserID:    query.Get("id"),
+		Email:     query.Get("email"),
+		Expires:   query.Get("expires"),
+		Signature: query.Get("signature"),
+	}
+
+	status := "email-changed"
+
+	if err := h.service.ConfirmEmailChange(r.Context(), params); err != nil {
+		var emailErr *EmailExistsError
+
+		switch {
+		case errors.Is(err, ErrInvalidEmailChangeLink):
+			status = "email-invalid"
+		case errors.As(err, &emailErr):
+			status = "email-taken"
+		default:
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+	}
+
+	// The link may be opened in a browser without a session
+	if userID, _ := FromContext(r.Context()); userID == "" {
+		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, profilePath+"?status="+status, http.StatusSeeOther)
+}
+
+// Deletes the account of the signed in user and signs them out everywhere
+func (h *Handler) HandleDeleteAccountForm(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	params, err := request.JSON[DeleteAccountParams](r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	err = h.checkPasswordLocked(r, userID, func() error {
+		_, err := h.service.DeleteAccount(r.Context(), userID, params)
+		return err
+	})
+
+	if err != nil {
+		renderAccountError(w, r, err)
+		return
+	}
+
+	if _, err := revokeUserSessions(r.Context(), h.sessionManager, userID, ""); err != nil {
+		slog.Error("failed to revoke sessions after account deletion", "user_id", userID, "error", err)
+	}
+
+	h.forgetUser(w, r, userID, false)
+
+	clearCookie(w, h.config.Session.SessionName, true)
+	clearCookie(w, h.config.Session.CSRFName, false)
+
+	res := &response.APIResponse[map[string]string]{
+		Message: "Your account has been deleted.",
+		Data: &map[string]string{
+			"redirectUrl": "/",
+		},
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Renders the errors of the account forms as field errors where the form can show them
+func renderAccountError(w http.ResponseWriter, r *http.Request, err error) {
+	var inputErr *validation.Error
+	if errors.As(err, &inputErr) {
+		response.RenderError(w, r, errtypes.ValidationError(*inputErr))
+		return
+	}
+
+	var emailErr *EmailExistsError
+	if errors.As(err, &emailErr) {
+		response.RenderError(w, r, errtypes.ValidationError(*fieldError("email", emailErr.Error())))
+		return
+	}
+
+	if errors.Is(err, ErrUserNotFound) {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	// The account may be locked out after too many wrong passwords, anything else is a server error
+	renderLockoutError(w, r, err)
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+)
+
+var ErrEmailChanged = errors.New("the email was changed since the link was sent")
+
+type AccountStore interface {
+	FindPasswordHash(ctx context.Context, userID string) (string, error)
+	ChangeEmail(ctx context.Context, userID, oldEmail, newEmail string) error
+	DeleteUser(ctx context.Context, u *user.User, mode db.DeleteMode) error
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// Returns the password hash of the user, empty when the user has no password
+func (r *repo) FindPasswordHash(ctx context.Context, userID string) (string, error) {
+	const q = "SELECT COALESCE(password_hash, '') FROM users WHERE id = $1 AND deleted_at IS NULL"
+
+	var hash string
+
+	if err := r.db.QueryRowContext(ctx, q, userID).Scan(&hash); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// Replaces the email of the user with a verified one, only when the email was not changed since it was read
+func (r *repo) ChangeEmail(ctx context.Context, userID, oldEmail, newEmail string) error {
+	const q = "UPDATE users SET email = $3, email_verified_at = NOW() WHERE id = $1 AND email = $2 AND deleted_at IS NULL"
+
+	res, err := r.db.ExecContext(ctx, q, userID, oldEmail, newEmail)
+
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return &EmailExistsError{Email: newEmail}
+		}
+
+		return fmt.Errorf("change email: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return fmt.Errorf("change email: %w", err)
+	}
+
+	if n == 0 {
+		return ErrEmailChanged
+	}
+
+	return nil
+}
+
+// Deletes the user. A soft delete keeps the row and sets the DeletedAt of the user, a hard delete removes it
+// along with everything that references it.
+func (r *repo) DeleteUser(ctx context.Context, u *user.User, mode db.DeleteMode) error {
+	if mode == db.HardDelete {
+		if _, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", u.ID); err != nil {
+			return fmt.Errorf("delete user: %w", err)
+		}
+
+		return nil
+	}
+
+	const q = "UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at"
+
+	if err := r.db.QueryRowContext(ctx, q, u.ID).Scan(&u.DeletedAt); err != nil {
+		return fmt.Errorf("soft delete user: %w", err)
+	}
+
+	return nil
+}
+
+// Removes the users that were soft deleted before the given time
+func (r *repo) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE deleted_at < $1", deletedBefore)
+
+	if err != nil {
+		return 0, fmt.Errorf("purge deleted users: %w", err)
+	}
+
+	return res.RowsAffected()
+}
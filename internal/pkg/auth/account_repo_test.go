@@ -0,0 +1,83 @@
+//go:build integration
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+)
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("can't load the config: %v", err)
+	}
+
+	conn, err := db.Connect(ctx, cfg.DB)
+	if err != nil {
+		t.Fatalf("can't connect to the database: %v", err)
+	}
+
+	defer conn.Close()
+
+	repo := NewAuthRepo(&cfg.DB, conn)
+	email := fmt.Sprintf("purge-%d@example.com", time.Now().UnixNano())
+
+	u, err := repo.SignUp(ctx, SignUpParams{Email: email, Password: "hash"})
+	if err != nil {
+		t.Fatalf("sign up: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := conn.ExecContext(ctx, "DELETE FROM users WHERE id = $1", u.ID); err != nil {
+			t.Errorf("delete user: %v", err)
+		}
+	})
+
+	if err := repo.DeleteUser(ctx, u, db.SoftDelete); err != nil {
+		t.Fatalf("soft delete user: %v", err)
+	}
+
+	if !u.DeletedAt.Valid {
+		t.Fatal("expected the deletion time to be set")
+	}
+
+	if _, err := repo.FindUser(ctx, u.ID); err == nil {
+		t.Error("expected the soft deleted user not to be found")
+	}
+
+	exists := func() bool {
+		var n int
+
+		if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE id = $1", u.ID).Scan(&n); err != nil {
+			t.Fatalf("count users: %v", err)
+		}
+
+		return n == 1
+	}
+
+	if _, err := repo.PurgeDeletedUsers(ctx, u.DeletedAt.Time.Add(-time.Hour)); err != nil {
+		t.Fatalf("purge deleted users: %v", err)
+	}
+
+	if !exists() {
+		t.Fatal("expected the user to be kept until the grace period is over")
+	}
+
+	if _, err := repo.PurgeDeletedUsers(ctx, u.DeletedAt.Time.Add(time.Second)); err != nil {
+		t.Fatalf("purge deleted users: %v", err)
+	}
+
+	if exists() {
+		t.Error("expected the user to be purged")
+	}
+}
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+var ErrInvalidEmailChangeLink = errors.New("the email change link is invalid or has expired")
+
+// Purpose of the signature of the links that confirm a new email
+const changeEmailPurpose = "change-email"
+
+const (
+	msgCurrentPasswordIncorrect = "Current password is incorrect."
+	msgNoPassword               = "Your account has no password, use forgot password to set one."
+)
+
+type ChangePasswordParams struct {
+	CurrentPassword      string `json:"current_password"`
+	Password             string `json:"password"`
+	PasswordConfirmation string `json:"password_confirmation"`
+}
+
+type ChangeEmailParams struct {
+	Email           string `json:"email"`
+	CurrentPassword string `json:"current_password"`
+}
+
+type ConfirmEmailChangeParams struct {
+	UserID    string
+	Email     string
+	Expires   string
+	Signature string
+}
+
+type DeleteAccountParams struct {
+	Email           string `json:"email"`
+	CurrentPassword string `json:"current_password"`
+}
+
+// Replaces the password of the user after checking the current one.
+func (s *service) ChangePassword(ctx context.Context, userID string, params ChangePasswordParams) error {
+	u, err := s.FindUser(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	form := validation.NewForm(params)
+	form.Required("Password", "PasswordConfirmation")
+	form.PasswordsMatch("Password", "PasswordConfirmation")
+	form.Password("Password", s.policy, u.Email)
+
+	if !form.IsValid() {
+		return &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	hash, err := s.checkCurrentPassword(ctx, userID, params.CurrentPassword)
+
+	if err != nil {
+		if errors.Is(err, errNoPassword) {
+			return fieldError("current_password", msgNoPassword)
+		}
+
+		return err
+	}
+
+	newHash, err := s.hasher.Hash(params.Password)
+
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
+	if err := s.repo.UpdatePasswordHash(ctx, userID, hash, newHash); err != nil {
+		return err
+	}
+
+	s.record(ctx, actionPasswordChanged, userID, "", nil)
+
+	return nil
+}
+
+// Sends a link to the new email that replaces the email of the user once opened.
+//
+// The current address is told about the request, so that a stolen session cannot take over the account quietly.
+func (s *service) RequestEmailChange(ctx context.Context, userID string, params ChangeEmailParams) error {
+	u, err := s.FindUser(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	form := validation.NewForm(params)
+	form.Required("Email")
+	form.IsEmail("Email")
+
+	if !form.IsValid() {
+		return &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	email := strings.TrimSpace(params.Email)
+
+	if strings.EqualFold(email, u.Email) {
+		return fieldError("email", "This is already your email address.")
+	}
+
+	if _, err := s.repo.FindUserIDByEmail(ctx, email); err == nil {
+		return &EmailExistsError{Email: email}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("find user: %w", err)
+	}
+
+	if _, err := s.checkCurrentPassword(ctx, userID, params.CurrentPassword); err != nil && !errors.Is(err, errNoPassword) {
+		return err
+	}
+
+	msg := mail.Message{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Someone asked to use this address for their account.\r\n\r\n"+
+			"Open the link below to confirm the change. It expires in %s.\r\n\r\n%s\r\n\r\n"+
+			"If you did not request this, you can ignore this message.\r\n",
+			s.cfg.Auth.VerificationTTL, s.emailChangeLink(userID, u.Email, email)),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send email change link: %w", err)
+	}
+
+	notice := mail.Message{
+		To:      u.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("A link to change the email address of your account to %s has been sent to that address.\r\n\r\n"+
+			"If you did not request this, change your password and sign out your other sessions.\r\n", email),
+	}
+
+	// The link is already on its way, a missing notice should not fail the request
+	if err := s.mailer.Send(ctx, notice); err != nil {
+		slog.Error("failed to notify the current email of an email change", "user_id", userID, "error", err)
+	}
+
+	return nil
+}
+
+// Builds the signed link that confirms the new email of the user.
+func (s *service) emailChangeLink(userID, oldEmail, newEmail string) string {
+	expires := strconv.FormatInt(time.Now().Add(s.cfg.Auth.VerificationTTL).Unix(), 10)
+	signature := security.Sign(s.cfg.Auth.SigningKey, changeEmailPurpose, userID, oldEmail, newEmail, expires)
+
+	query := url.Values{}
+	query.Set("id", userID)
+	query.Set("email", newEmail)
+	query.Set("expires", expires)
+	query.Set("signature", signature)
+
+	return s.cfg.Server.BaseURL + profileEmailPath + "/confirm?" + query.Encode()
+}
+
+// Replaces the email of a user from the parameters of a signed link.
+func (s *service) ConfirmEmailChange(ctx context.Context, params ConfirmEmailChangeParams) error {
+	expires, err := strconv.ParseInt(params.Expires, 10, 64)
+
+	if err != nil || time.Now().Unix() > expires {
+		return ErrInvalidEmailChangeLink
+	}
+
+	u, err := s.FindUser(ctx, params.UserID)
+
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrInvalidEmailChangeLink
+		}
+
+		return err
+	}
+
+	// The current email is part of the signature, so only the latest change can be confirmed
+	if !security.VerifySignature(s.cfg.Auth.SigningKey, params.Signature, changeEmailPurpose, params.UserID, u.Email, params.Email, params.Expires) {
+		return ErrInvalidEmailChangeLink
+	}
+
+	if err := s.repo.ChangeEmail(ctx, params.UserID, u.Email, params.Email); err != nil {
+		if errors.Is(err, ErrEmailChanged) {
+			return ErrInvalidEmailChangeLink
+		}
+
+		return err
+	}
+
+	s.record(ctx, actionEmailChanged, params.UserID, "", map[string]any{"old_email": u.Email, "new_email": params.Email})
+
+	return nil
+}
+
+// Soft deletes the account of the user. The account is purged for good once the grace period is over.
+func (s *service) DeleteAccount(ctx context.Context, userID string, params DeleteAccountParams) (*user.User, error) {
+	u, err := s.FindUser(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	form := validation.NewForm(params)
+	form.Required("Email")
+
+	if !form.IsValid() {
+		return nil, &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(params.Email), u.Email) {
+		return nil, fieldError("email", "Enter the email address of your account to confirm.")
+	}
+
+	if _, err := s.checkCurrentPassword(ctx, userID, params.CurrentPassword); err != nil && !errors.Is(err, errNoPassword) {
+		return nil, err
+	}
+
+	if err := s.repo.DeleteUser(ctx, u, db.SoftDelete); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actionUserDeleted, userID, "", map[string]any{"email": u.Email})
+
+	purgeAt := u.DeletedAt.Time.Add(s.cfg.Auth.AccountPurgeAfter)
+
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Your account has been deleted",
+		Body: fmt.Sprintf("Your account has been deleted and you have been signed out.\r\n\r\n"+
+			"Your data will be removed for good on %s. Contact us before then if this was a mistake.\r\n",
+			purgeAt.Format(time.RFC1123)),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		slog.Error("failed to send account deletion notice", "user_id", userID, "error", err)
+	}
+
+	return u, nil
+}
+
+// Reports whether the user has a password, users who signed up with a provider have none.
+func (s *service) HasPassword(ctx context.Context, userID string) (bool, error) {
+	hash, err := s.repo.FindPasswordHash(ctx, userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
+
+		return false, fmt.Errorf("find password hash: %w", err)
+	}
+
+	return hash != "", nil
+}
+
+var (
+	// Returned by checkCurrentPassword for users who signed up with a provider
+	errNoPassword = errors.New("user has no password")
+
+	// Returned by checkCurrentPassword along with the field error, so that the handlers can count it as a failed sign in
+	errCurrentPasswordIncorrect = errors.New("current password is incorrect")
+)
+
+// Checks the current password of the user and returns its hash.
+// A wrong password is reported as a field error of current_password.
+func (s *service) checkCurrentPassword(ctx context.Context, userID, password string) (string, error) {
+	hash, err := s.repo.FindPasswordHash(ctx, userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+
+		return "", fmt.Errorf("find password hash: %w", err)
+	}
+
+	if hash == "" {
+		if password != "" {
+			return "", fieldError("current_password", msgNoPassword)
+		}
+
+		return "", errNoPassword
+	}
+
+	if password == "" {
+		return "", fieldError("current_password", "This field is required.")
+	}
+
+	match, _, err := s.hasher.Verify(password, hash)
+
+	if err != nil {
+		return "", fmt.Errorf("verify password: %w", err)
+	}
+
+	if !match {
+		return "", errors.Join(errCurrentPasswordIncorrect, fieldError("current_password", msgCurrentPasswordIncorrect))
+	}
+
+	return hash, nil
+}
+
+// Returns a validation error with a single message for the field
+func fieldError(field, msg string) *validation.Error {
+	return &validation.Error{
+		Errors: validation.Errors{
+			field: {msg},
+		},
+	}
+}
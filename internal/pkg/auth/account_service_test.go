@@ -0,0 +1,82 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		params DeleteAccountParams
+		field  string // Field of the expected validation error, empty when the account is deleted
+	}{
+		{"Should require the email", DeleteAccountParams{CurrentPassword: testPassword}, "email"},
+		{"Should reject another email", DeleteAccountParams{Email: "someone@example.com", CurrentPassword: testPassword}, "email"},
+		{"Should require the password", DeleteAccountParams{Email: testEmail}, "current_password"},
+		{"Should reject a wrong password", DeleteAccountParams{Email: testEmail, CurrentPassword: "wrong password"}, "current_password"},
+		{"Should delete the account", DeleteAccountParams{Email: " Jane@Example.com ", CurrentPassword: testPassword}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepo()
+			svc, mailer, auditLog := newTestService(testConfig(), repo)
+			setTestPassword(t, svc, repo)
+
+			u, err := svc.DeleteAccount(ctx, testUserID, tt.params)
+
+			if tt.field != "" {
+				var inputErr *validation.Error
+				if !errors.As(err, &inputErr) || len(inputErr.Errors[tt.field]) == 0 {
+					t.Fatalf("expected a %s field error, got %v", tt.field, err)
+				}
+
+				if repo.users[testUserID].DeletedAt.Valid {
+					t.Error("expected the account to be kept")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("delete account: %v", err)
+			}
+
+			if !u.DeletedAt.Valid || !repo.users[testUserID].DeletedAt.Valid {
+				t.Fatal("expected the account to be soft deleted")
+			}
+
+			if _, err := svc.FindUser(ctx, testUserID); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("expected the deleted user not to be found, got %v", err)
+			}
+
+			if actions := auditLog.actions(); len(actions) != 1 || actions[0] != actionUserDeleted {
+				t.Errorf("expected the deletion to be recorded, got %v", actions)
+			}
+
+			if sent := mailer.messages(); len(sent) != 1 || sent[0].To != testEmail {
+				t.Errorf("expected a deletion notice sent to %s, got %+v", testEmail, sent)
+			}
+		})
+	}
+}
+
+func TestDeleteAccountCountsWrongPasswords(t *testing.T) {
+	repo := newFakeRepo()
+	svc, _, _ := newTestService(testConfig(), repo)
+	setTestPassword(t, svc, repo)
+
+	_, err := svc.DeleteAccount(context.Background(), testUserID, DeleteAccountParams{Email: testEmail, CurrentPassword: "wrong password"})
+
+	if !errors.Is(err, errCurrentPasswordIncorrect) {
+		t.Errorf("expected errCurrentPasswordIncorrect for the lockout, got %v", err)
+	}
+}
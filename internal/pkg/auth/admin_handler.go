@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/gopherkit/http/request"
+)
+
+const adminUsersPath = "/admin/users"
+
+type adminUsersData struct {
+	response.PageData
+	Users    []UserRoles
+	Roles    []Role
+	PrevPage int // 0 when this is the first page
+	NextPage int // 0 when this is the last page
+	Message  string
+}
+
+// Messages shown on the user management page, keyed by the status query parameter
+var adminMessages = map[string]string{
+	"role-assigned":       "The role has been assigned.",
+	"role-removed":        "The role has been removed.",
+	"role-not-found":      ErrRoleNotFound.Error(),
+	"own-roles":           ErrOwnRoles.Error(),
+	"impersonate-self":    ErrImpersonateSelf.Error(),
+	"impersonate-admin":   ErrImpersonateAdmin.Error(),
+	"user-not-found":      ErrUserNotFound.Error(),
+	"impersonation-ended": "You are no longer impersonating a user.",
+}
+
+type RoleParams struct {
+	Role string `json:"role"`
+}
+
+// Lists the users with their roles
+func (h *Handler) HandleAdminUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	users, err := h.service.UserRoles(r.Context(), page)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if r.Header.Get("content-type") == "application/json" {
+		response.RenderJSON(w, http.StatusOK, &response.APIResponse[[]UserRoles]{Data: &users})
+		return
+	}
+
+	roles, err := h.service.Roles(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	data := &adminUsersData{
+		PageData: NewPageData(r, "Users"),
+		Users:    users,
+		Roles:    roles,
+		PrevPage: page - 1,
+		Message:  adminMessages[r.URL.Query().Get("status")],
+	}
+
+	if len(users) == usersPerPage {
+		data.NextPage = page + 1
+	}
+
+	h.htmlTemplate.Render(w, "admin-users.html", data)
+}
+
+// Assigns a role to a user
+func (h *Handler) HandleAssignRole(w http.ResponseWriter, r *http.Request) {
+	actorID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	isJSON := r.Header.Get("content-type") == "application/json"
+	params := RoleParams{Role: r.PostFormValue("role")}
+
+	if isJSON {
+		if params, err = request.JSON[RoleParams](r); err != nil {
+			response.RenderError(w, r, errtypes.BadRequest(err))
+			return
+		}
+	}
+
+	err = h.service.AssignRole(r.Context(), actorID, r.PathValue("id"), params.Role)
+	h.renderRoleChange(w, r, err, "role-assigned", "Role assigned.")
+}
+
+// Removes a role from a user
+func (h *Handler) HandleRemoveRole(w http.ResponseWriter, r *http.Request) {
+	actorID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	err = h.service.RemoveRole(r.Context(), actorID, r.PathValue("id"), r.PathValue("role"))
+	h.renderRoleChange(w, r, err, "role-removed", "Role removed.")
+}
+
+func (h *Handler) renderRoleChange(w http.ResponseWriter, r *http.Request, err error, status, msg string) {
+	isJSON := r.Header.Get("content-type") == "application/json"
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRoleNotFound):
+			status = "role-not-found"
+
+			if isJSON {
+				response.RenderError(w, r, errtypes.NotFoundError(err))
+				return
+			}
+		case errors.Is(err, ErrUserNotFound):
+			status = "user-not-found"
+
+			if isJSON {
+				response.RenderError(w, r, errtypes.NotFoundError(err))
+				return
+			}
+		case errors.Is(err, ErrOwnRoles):
+			status = "own-roles"
+
+			if isJSON {
+				response.RenderError(w, r, errtypes.ForbiddenError(err))
+				return
+			}
+		default:
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+	}
+
+	if !isJSON {
+		http.Redirect(w, r, adminUsersPath+"?status="+status, http.StatusSeeOther)
+		return
+	}
+
+	response.RenderJSON(w, http.StatusOK, &response.APIResponse[any]{Message: msg})
+}
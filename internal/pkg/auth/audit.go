@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"maps"
+	"net/http"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/audit"
+)
+
+// Actions recorded in the audit log
+const (
+	actionSignUp             = "user.signup"
+	actionSignIn             = "user.signin"
+	actionSignInFailed       = "user.signin_failed"
+	actionSignOut            = "user.signout"
+	actionPasswordChanged    = "user.password_changed"
+	actionPasswordReset      = "user.password_reset"
+	actionEmailChanged       = "user.email_changed"
+	actionUserDeleted        = "user.deleted"
+	actionTwoFactorEnabled   = "user.two_factor_enabled"
+	actionTwoFactorDisabled  = "user.two_factor_disabled"
+	actionOAuthLinked        = "user.oauth_linked"
+	actionSessionRevoked     = "session.revoked"
+	actionRememberTokenReuse = "session.remember_token_reused"
+	actionPasskeyAdded       = "passkey.added"
+	actionPasskeyRemoved     = "passkey.removed"
+	actionTokenCreated       = "token.created"
+	actionTokenRevoked       = "token.revoked"
+	actionRoleAssigned       = "role.assigned"
+	actionRoleRemoved        = "role.removed"
+	actionInvitationCreated  = "invitation.created"
+	actionInvitationRevoked  = "invitation.revoked"
+	actionImpersonationStart = "impersonation.start"
+	actionImpersonationStop  = "impersonation.stop"
+)
+
+// How a user signed in, recorded with actionSignIn
+const (
+	signInPassword   = "password"
+	signInPasskey    = "passkey"
+	signInMagicLink  = "magic_link"
+	signInOAuth      = "oauth"
+	signInRememberMe = "remember_me"
+)
+
+// AuditLog records the security events and lets the admins look them up.
+type AuditLog interface {
+	audit.Recorder
+	List(ctx context.Context, filter audit.Filter) ([]audit.Entry, error)
+	Export(ctx context.Context, filter audit.Filter, fn func(audit.Entry) error) error
+}
+
+// Records an event of the request in the audit log. See newEvent.
+func (h *Handler) record(r *http.Request, action, actorID, targetID string, payload map[string]any) {
+	h.auditLog.Record(r.Context(), newEvent(r.Context(), action, actorID, targetID, payload))
+}
+
+// Records an event of the service in the audit log. See newEvent.
+func (s *service) record(ctx context.Context, action, actorID, targetID string, payload map[string]any) {
+	s.auditor.Record(ctx, newEvent(ctx, action, actorID, targetID, payload))
+}
+
+// Returns the event of the user, who acts on their own account when there is no target.
+// Events caused by an admin who is impersonating the user are marked with the id of the admin.
+func newEvent(ctx context.Context, action, actorID, targetID string, payload map[string]any) audit.Event {
+	if targetID == "" {
+		targetID = actorID
+	}
+
+	if principal, ok := PrincipalFromContext(ctx); ok && principal.Impersonated() && principal.UserID == actorID {
+		payload = maps.Clone(payload)
+
+		if payload == nil {
+			payload = make(map[string]any)
+		}
+
+		payload["impersonator_id"] = principal.ImpersonatorID
+	}
+
+	return audit.Event{
+		Action:   action,
+		ActorID:  actorID,
+		TargetID: targetID,
+		Payload:  payload,
+	}
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/audit"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+)
+
+const adminAuditPath = "/admin/audit"
+
+// Entries listed on a page of the audit log
+const auditEntriesPerPage = 50
+
+// Format of the dates of the audit log filter
+const auditDateLayout = "2006-01-02"
+
+var errAuditDate = errors.New("dates must be in the YYYY-MM-DD format")
+
+// Actions suggested by the filter of the audit log page
+var auditActions = []string{
+	"user", "session", "passkey", "token", "role", "invitation", "impersonation",
+	actionSignUp, actionSignIn, actionSignInFailed, actionSignOut,
+	actionPasswordChanged, actionPasswordReset, actionEmailChanged, actionUserDeleted,
+	actionTwoFactorEnabled, actionTwoFactorDisabled, actionOAuthLinked, actionSessionRevoked, actionRememberTokenReuse,
+	actionPasskeyAdded, actionPasskeyRemoved, actionTokenCreated, actionTokenRevoked,
+	actionRoleAssigned, actionRoleRemoved, actionInvitationCreated, actionInvitationRevoked,
+	actionImpersonationStart, actionImpersonationStop,
+}
+
+type auditLogData struct {
+	response.PageData
+	Entries   []audit.Entry
+	Actions   []string
+	Query     url.Values // The filter, to fill the form and the export link
+	ExportURL string
+	PrevURL   string // Empty when this is the first page
+	NextURL   string // Empty when this is the last page
+}
+
+// Lists the entries of the audit log that match the filter in the query, a page at a time
+func (h *Handler) HandleAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter, err := auditFilter(query)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.InvalidRequestError(err))
+		return
+	}
+
+	page, err := strconv.Atoi(query.Get("page"))
+
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	filter.Limit = auditEntriesPerPage
+	filter.Offset = (page - 1) * auditEntriesPerPage
+
+	entries, err := h.auditLog.List(r.Context(), filter)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if r.Header.Get("content-type") == "application/json" {
+		response.RenderJSON(w, http.StatusOK, &response.APIResponse[[]audit.Entry]{Data: &entries})
+		return
+	}
+
+	query.Del("page")
+
+	data := &auditLogData{
+		PageData:  NewPageData(r, "Audit Log"),
+		Entries:   entries,
+		Actions:   auditActions,
+		Query:     query,
+		ExportURL: adminAuditPath + "/export?" + query.Encode(),
+	}
+
+	if page > 1 {
+		data.PrevURL = auditPageURL(query, page-1)
+	}
+
+	if len(entries) == auditEntriesPerPage {
+		data.NextURL = auditPageURL(query, page+1)
+	}
+
+	h.htmlTemplate.Render(w, "admin-audit.html", data)
+}
+
+// Downloads all the entries of the audit log that match the filter in the query as a JSON array
+func (h *Handler) HandleExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r.URL.Query())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.InvalidRequestError(err))
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s.json", time.Now().Format("20060102-150405"))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// Streamed, so that a large log is never held in memory. Once the first entry is sent
+	// the status cannot change, so a failure after it leaves the array unterminated.
+	enc := json.NewEncoder(w)
+	sep := "["
+
+	err = h.auditLog.Export(r.Context(), filter, func(e audit.Entry) error {
+		if _, err := w.Write([]byte(sep)); err != nil {
+			return err
+		}
+
+		sep = ","
+
+		return enc.Encode(e)
+	})
+
+	if err != nil {
+		// Nothing was sent yet, so the error can still be shown
+		if sep == "[" {
+			w.Header().Del("Content-Disposition")
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		slog.Error("failed to export the audit log", "error", err)
+		return
+	}
+
+	if sep == "[" {
+		_, _ = w.Write([]byte("[]\n"))
+		return
+	}
+
+	_, _ = w.Write([]byte("]\n"))
+}
+
+// Reads the filter of the audit log from the query. The to date is included.
+func auditFilter(query url.Values) (audit.Filter, error) {
+	filter := audit.Filter{
+		Action: query.Get("action"),
+		Actor:  query.Get("actor"),
+		Target: query.Get("target"),
+	}
+
+	if from := query.Get("from"); from != "" {
+		t, err := time.Parse(auditDateLayout, from)
+
+		if err != nil {
+			return filter, errAuditDate
+		}
+
+		filter.From = t
+	}
+
+	if to := query.Get("to"); to != "" {
+		t, err := time.Parse(auditDateLayout, to)
+
+		if err != nil {
+			return filter, errAuditDate
+		}
+
+		filter.To = t.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
+
+func auditPageURL(query url.Values, page int) string {
+	q := maps.Clone(query)
+	q.Set("page", strconv.Itoa(page))
+
+	return adminAuditPath + "?" + q.Encode()
+}
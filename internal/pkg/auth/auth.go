@@ -10,16 +10,20 @@ type SignUpParams struct {
 	Email                string `json:"email"`
 	Password             string `json:"password"`
 	PasswordConfirmation string `json:"password_confirmation"`
+	Invitation           string `json:"invitation"` // Token of the invitation, required in invite-only mode
 }
 
 type SignInParams struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Remember bool   `json:"remember"` // Keep the browser signed in after the session expires
 }
 
 type OAuthParams struct {
 	OAuthProvider string `json:"oauth_provider"`
 	OAuthID       string `json:"oauth_id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type SignInResult struct {
@@ -30,4 +34,9 @@ type SignInResult struct {
 type Authenticator interface {
 	SignUp(context.Context, SignUpParams) (*user.User, error)
 	SignIn(context.Context, string) (*SignInResult, error)
+	SignUpOAuth(context.Context, OAuthParams) (*user.User, error)
+	FindByOAuth(ctx context.Context, provider, id string) (string, error)
+	LinkOAuth(ctx context.Context, userID string, params OAuthParams) error
+	UpdatePasswordHash(ctx context.Context, userID, oldHash, newHash string) error
+	FindUser(ctx context.Context, userID string) (*user.User, error)
 }
@@ -3,23 +3,158 @@ package auth
 import (
 	"context"
 	"errors"
+	"slices"
+	"sync"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
 )
 
 type ctxKey string
 
-const userKey ctxKey = "userID"
+const principalKey ctxKey = "principal"
 
 var ErrUserNotInContext = errors.New("no user in context")
 
+// Principal is the authenticated user of a request.
+type Principal struct {
+	UserID string
+
+	// Set when the request was authenticated by a personal access token instead of a session
+	AccessToken *AccessToken
+
+	// Set when an admin is impersonating the user, to the id of the admin
+	ImpersonatorID string
+
+	// Set by AuthorizationMiddleware
+	access *lazyAccess
+
+	// Set by CurrentUserMiddleware
+	user *lazyUser
+}
+
+// Loads the user of a request when it is first needed, then keeps it for the rest of the request
+type lazyUser struct {
+	once sync.Once
+	load func() (*user.User, error)
+	user *user.User
+	err  error
+}
+
+func (l *lazyUser) get() (*user.User, error) {
+	l.once.Do(func() {
+		l.user, l.err = l.load()
+	})
+
+	return l.user, l.err
+}
+
+// Loads the roles of a request and the permissions that they grant when they are first needed,
+// then keeps them for the rest of the request
+type lazyAccess struct {
+	once        sync.Once
+	load        func() ([]string, []string, error)
+	roles       []string
+	permissions []string
+	err         error
+}
+
+func (l *lazyAccess) get() ([]string, []string, error) {
+	l.once.Do(func() {
+		l.roles, l.permissions, l.err = l.load()
+	})
+
+	return l.roles, l.permissions, l.err
+}
+
+// Returns the user of the principal, loading it from the database on the first call of the request
+func (p *Principal) User() (*user.User, error) {
+	if p == nil || p.user == nil {
+		return nil, ErrUserNotInContext
+	}
+
+	return p.user.get()
+}
+
+// Reports whether an admin is acting as the user
+func (p *Principal) Impersonated() bool {
+	return p != nil && p.ImpersonatorID != ""
+}
+
+// Returns the roles of the principal, loading them from the database on the first call of the request.
+// A principal that went through no AuthorizationMiddleware has none.
+func (p *Principal) Roles() ([]string, error) {
+	if p == nil || p.access == nil {
+		return nil, nil
+	}
+
+	roles, _, err := p.access.get()
+	return roles, err
+}
+
+// Returns the permissions granted by the roles of the principal, see Roles
+func (p *Principal) Permissions() ([]string, error) {
+	if p == nil || p.access == nil {
+		return nil, nil
+	}
+
+	_, permissions, err := p.access.get()
+	return permissions, err
+}
+
+// Reports whether the principal was granted the permission by any of its roles
+func (p *Principal) Can(permission string) (bool, error) {
+	permissions, err := p.Permissions()
+	return slices.Contains(permissions, permission), err
+}
+
+func (p *Principal) HasRole(role string) (bool, error) {
+	roles, err := p.Roles()
+	return slices.Contains(roles, role), err
+}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// Returns the signed in user. Needs CurrentUserMiddleware.
+func CurrentUser(ctx context.Context) (*user.User, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.UserID == "" {
+		return nil, ErrUserNotInContext
+	}
+
+	return principal.User()
+}
+
 func WithUser(ctx context.Context, userID string) context.Context {
-	return context.WithValue(ctx, userKey, userID)
+	return WithPrincipal(ctx, &Principal{UserID: userID})
 }
 
 func FromContext(ctx context.Context) (string, error) {
-	userID, ok := ctx.Value(userKey).(string)
+	principal, ok := PrincipalFromContext(ctx)
 	if !ok {
 		return "", ErrUserNotInContext
 	}
 
-	return userID, nil
+	return principal.UserID, nil
+}
+
+// Stores the access token that authenticated the request along with its user
+func WithAccessToken(ctx context.Context, token *AccessToken) context.Context {
+	return WithPrincipal(ctx, &Principal{UserID: token.UserID, AccessToken: token})
+}
+
+// Retrieves the access token that authenticated the request, if it was not authenticated by a session
+func AccessTokenFromContext(ctx context.Context) (*AccessToken, bool) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.AccessToken == nil {
+		return nil, false
+	}
+
+	return principal.AccessToken, true
 }
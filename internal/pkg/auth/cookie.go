@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+)
+
+// Sends the session id and the csrf token cookies to the client
+func setSessionCookies(w http.ResponseWriter, cfg config.SessionConfig, sessionID, csrfToken string) {
+	setSessionCookie(w, cfg, sessionID)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cfg.CSRFName,
+		Value:    csrfToken,
+		Expires:  time.Now().Add(cfg.SessionDuration),
+		HttpOnly: false,
+		SameSite: cfg.SameSite,
+		Path:     "/",
+	})
+}
+
+// Sends the session id cookie to the client
+func setSessionCookie(w http.ResponseWriter, cfg config.SessionConfig, sessionID string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cfg.SessionName,
+		Value:    sessionID,
+		Expires:  time.Now().Add(cfg.SessionDuration),
+		HttpOnly: true,
+		SameSite: cfg.SameSite,
+		Path:     "/",
+	})
+}
+
+// Expires the cookie with the given name on the client
+func clearCookie(w http.ResponseWriter, name string, httpOnly bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: httpOnly,
+		Path:     "/",
+	})
+}
+
+// Sends the remember me token cookie to the client
+func setRememberCookie(w http.ResponseWriter, cfg config.SessionConfig, token string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cfg.RememberName,
+		Value:    token,
+		Expires:  expiresAt,
+		HttpOnly: true,
+		SameSite: cfg.SameSite,
+		Path:     "/",
+	})
+}
@@ -2,16 +2,19 @@ package auth
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/html"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/middleware"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/lockout"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/oidc"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
 	"github.com/ferdiebergado/goexpress"
@@ -24,20 +27,59 @@ type Handler struct {
 	service        Service
 	htmlTemplate   *html.Template
 	sessionManager session.Manager
+	providers      []*oidc.Provider
+	lockout        *signInLockout
+	auditLog       AuditLog
 }
 
-func NewHandler(cfg *config.Config, router *goexpress.Router, service Service, htmlTemplate *html.Template, sessMgr session.Manager) *Handler {
+func NewHandler(cfg *config.Config, router *goexpress.Router, service Service, htmlTemplate *html.Template, sessMgr session.Manager, providers []*oidc.Provider, lockoutStore lockout.Store, auditLog AuditLog) *Handler {
 	return &Handler{
 		config:         cfg,
 		router:         router,
 		service:        service,
 		htmlTemplate:   htmlTemplate,
 		sessionManager: sessMgr,
+		providers:      providers,
+		lockout:        newSignInLockout(cfg.Lockout, lockoutStore),
+		auditLog:       auditLog,
 	}
 }
 
-func (h *Handler) HandleSignUp(w http.ResponseWriter, _ *http.Request) {
-	h.htmlTemplate.Render(w, "signup.html", nil)
+type signUpData struct {
+	response.PageData
+	Invitation string
+	Email      string // Email of the invitation, the form is prefilled with it
+	Error      string // Why the user cannot sign up, the form is hidden when set
+}
+
+func (h *Handler) HandleSignUp(w http.ResponseWriter, r *http.Request) {
+	data := &signUpData{
+		PageData:   response.PageData{Title: "Sign Up"},
+		Invitation: r.URL.Query().Get("invitation"),
+	}
+
+	switch {
+	case h.service.RegistrationMode() == config.RegistrationClosed:
+		data.Error = "Sign up is closed."
+	case data.Invitation != "":
+		inv, err := h.service.Invitation(r.Context(), data.Invitation)
+
+		if err != nil {
+			if !errors.Is(err, ErrInvalidInvitation) {
+				response.RenderError(w, r, errtypes.ServerError(err))
+				return
+			}
+
+			data.Error = "The invitation is invalid or has expired. Ask for a new one."
+			break
+		}
+
+		data.Email = inv.Email
+	case h.service.RegistrationMode() == config.RegistrationInviteOnly:
+		data.Error = "Sign up is by invitation only. Open the link in your invitation to sign up."
+	}
+
+	h.htmlTemplate.Render(w, "signup.html", data)
 }
 
 func (h *Handler) HandleSignUpForm(w http.ResponseWriter, r *http.Request) {
@@ -51,6 +93,16 @@ func (h *Handler) HandleSignUpForm(w http.ResponseWriter, r *http.Request) {
 	u, err := h.service.SignUp(r.Context(), params)
 
 	if err != nil {
+		if errors.Is(err, ErrRegistrationClosed) || errors.Is(err, ErrInvitationRequired) {
+			response.RenderError(w, r, errtypes.DeniedError(err))
+			return
+		}
+
+		if errors.Is(err, ErrInvalidInvitation) {
+			response.RenderError(w, r, errtypes.InvalidRequestError(err))
+			return
+		}
+
 		var inputErr *validation.Error
 		if errors.As(err, &inputErr) {
 			valErr := errtypes.ValidationError(*inputErr)
@@ -80,16 +132,32 @@ func (h *Handler) HandleSignUpForm(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res := &response.APIResponse[user.User]{
-		Message: "Sign up successful!",
+		Message: "Sign up successful! Check your email for a link to verify your address.",
 		Data:    u,
 	}
 
+	if u.EmailVerifiedAt != nil {
+		res.Message = "Sign up successful! You can now sign in."
+	}
+
 	slog.Debug("sending response", "message", res.Message, "data", res.Data)
 	response.RenderJSON(w, http.StatusCreated, res)
 }
 
+type signInData struct {
+	response.PageData
+	Providers  []*oidc.Provider
+	SignUpOpen bool
+}
+
 func (h *Handler) HandleSignin(w http.ResponseWriter, _ *http.Request) {
-	h.htmlTemplate.Render(w, "signin.html", nil)
+	data := &signInData{
+		PageData:   response.PageData{Title: "Sign In"},
+		Providers:  h.providers,
+		SignUpOpen: h.service.RegistrationMode() == config.RegistrationOpen,
+	}
+
+	h.htmlTemplate.Render(w, "signin.html", data)
 }
 
 func (h *Handler) HandleSignInForm(w http.ResponseWriter, r *http.Request) {
@@ -101,6 +169,16 @@ func (h *Handler) HandleSignInForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Checked before the password, so that locked out attempts cost no hashing
+	if err := h.lockout.check(r.Context(), params.Email); err != nil {
+		if errors.Is(err, lockout.ErrLocked) {
+			h.record(r, actionSignInFailed, "", "", map[string]any{"email": params.Email, "reason": "locked_out"})
+		}
+
+		renderLockoutError(w, r, err)
+		return
+	}
+
 	userID, err := h.service.SignIn(r.Context(), params)
 
 	if err != nil {
@@ -112,6 +190,10 @@ func (h *Handler) HandleSignInForm(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if errors.Is(err, ErrUserPassInvalid) {
+			h.record(r, actionSignInFailed, "", "", map[string]any{"email": params.Email, "reason": "invalid_credentials"})
+
+			h.failSignIn(r, params.Email)
+
 			innerErr := errors.Unwrap(err)
 			authErr := errtypes.AuthenticationError(innerErr)
 			response.RenderError(w, r, authErr)
@@ -123,14 +205,11 @@ func (h *Handler) HandleSignInForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var redirectURL string
-
-	sessionData, err := h.sessionManager.LoadSession(r)
+	redirectURL, err := h.startSession(w, r, userID, signInPassword, params.Remember)
 
 	if err != nil {
-		redirectURL = "/dashboard"
-	} else {
-		redirectURL = sessionData.Flash["intendedUrl"]
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
 	}
 
 	res := &response.APIResponse[map[string]string]{
@@ -140,52 +219,229 @@ func (h *Handler) HandleSignInForm(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	sid, err := security.GenerateRandomBytesEncoded(64)
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Signs in the user who passed the first factor from the session of the request. See completeSignIn.
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, userID, method string, remember bool) (string, error) {
+	data := session.Data{}
+
+	if sessionData, err := h.sessionManager.LoadSession(r); err == nil {
+		data = *sessionData
+	}
+
+	return h.completeSignIn(w, r, data, userID, method, remember)
+}
+
+// Authenticates the session of the request as the given user.
+//
+// The data is the one of the session of the request, and only its intended url is used, as the url
+// where the user should be redirected to, which is returned. The authenticated session starts with
+// fresh data, so that nothing left by the anonymous session, e.g. a ceremony, outlives the sign in.
+// Every sign in ends here, which is when the failed attempts of the user are forgotten.
+// When remember is set, the browser is kept signed in with a remember me token whose family is linked to the session.
+func (h *Handler) startSessionWithData(w http.ResponseWriter, r *http.Request, data session.Data, userID string, remember bool) (string, error) {
+	redirectURL := defaultRedirectPath
+
+	if intendedURL := data.Flash["intendedUrl"]; intendedURL != "" {
+		redirectURL = intendedURL
+	}
+
+	authenticated := session.Data{}
+
+	var (
+		rememberToken *RememberToken
+		rememberValue string
+	)
+
+	if remember {
+		if rememberToken, rememberValue = h.rememberUser(r, userID); rememberToken != nil {
+			if err := session.Set(&authenticated, rememberFamilyKey, rememberToken.FamilyID); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if err := h.authenticateSession(w, r, authenticated, userID); err != nil {
+		return "", err
+	}
+
+	// Only sent once the session exists, so that the browser is not kept signed in by a sign in that failed
+	if rememberToken != nil {
+		setRememberCookie(w, h.config.Session, rememberValue, rememberToken.ExpiresAt)
+	}
+
+	h.succeedSignIn(r, userID)
+
+	return redirectURL, nil
+}
+
+// Saves the data as the session of the given user with a new csrf token.
+// The session id is regenerated to prevent session fixation.
+func (h *Handler) authenticateSession(w http.ResponseWriter, r *http.Request, data session.Data, userID string) error {
+	csrf, err := security.GenerateRandomBytesEncoded(64)
 
 	if err != nil {
-		serverError := errtypes.ServerError(err)
-		response.RenderError(w, r, serverError)
+		return fmt.Errorf("generate csrf token: %w", err)
+	}
+
+	data.UserID = userID
+	data.CSRFToken = csrf
+
+	oldSessionID, err := h.sessionManager.ExtractSessionID(r)
+
+	if err != nil {
+		return err
+	}
+
+	sid, err := h.sessionManager.Regenerate(r.Context(), oldSessionID, data)
+
+	if err != nil {
+		return err
+	}
+
+	setSessionCookies(w, h.config.Session, sid, csrf)
+
+	return nil
+}
+
+// Returns the data shared by the pages of signed in users
+func NewPageData(r *http.Request, title string) response.PageData {
+	data := response.PageData{
+		Title:     title,
+		CSRFToken: middleware.CSRFToken(r.Context()),
+	}
+
+	principal, ok := PrincipalFromContext(r.Context())
+
+	if !ok || principal.UserID == "" {
+		return data
+	}
+
+	data.Impersonating = principal.Impersonated()
+
+	// The page can still be shown without the user or their permissions, so that a slow query does not break every page
+	if permissions, err := principal.Permissions(); err == nil {
+		data.Permissions = permissions
+	} else {
+		slog.Error("failed to load the permissions of the signed in user", "user_id", principal.UserID, "error", err)
+	}
+
+	if u, err := principal.User(); err == nil {
+		data.User = u
+	} else if !errors.Is(err, ErrUserNotInContext) {
+		slog.Error("failed to load the signed in user", "user_id", principal.UserID, "error", err)
+	}
+
+	return data
+}
+
+type profileData struct {
+	response.PageData
+	Sessions           []ActiveSession
+	TwoFactorAvailable bool
+	TwoFactorEnabled   bool
+	Passkeys           []Passkey
+	AccessTokens       []AccessToken
+	Scopes             []string
+	Message            string
+}
+
+// Messages shown on the profile page, keyed by the status query parameter
+var profileMessages = map[string]string{
+	"2fa-disabled":    "Two-factor authentication has been disabled.",
+	"email-changed":   "Your email address has been changed.",
+	"email-invalid":   ErrInvalidEmailChangeLink.Error(),
+	"email-taken":     "The new email address is already used by another account.",
+	"2fa-invalid":     ErrInvalidSecondFactor.Error(),
+	"passkey-removed": "The passkey has been removed.",
+	"token-invalid":   "Enter a name and choose at least one scope for the access token.",
+	"token-revoked":   "The access token has been revoked.",
+}
+
+func (h *Handler) HandleProfile(w http.ResponseWriter, r *http.Request) {
+	u, err := CurrentUser(r.Context())
+
+	if err != nil {
+		if errors.Is(err, ErrUserNotInContext) || errors.Is(err, ErrUserNotFound) {
+			response.RenderError(w, r, errtypes.AuthenticationError(err))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
 		return
 	}
 
-	data := session.Data{
-		UserID: userID,
+	userID := u.ID
+
+	sessions, err := h.activeSessions(r, userID)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
 	}
 
-	if err := h.sessionManager.StoreSession(r.Context(), sid, data); err != nil {
+	twoFactorEnabled, err := h.service.TwoFactorEnabled(r.Context(), userID)
+
+	if err != nil {
 		response.RenderError(w, r, errtypes.ServerError(err))
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     h.config.Session.SessionName,
-		Value:    sid,
-		Expires:  time.Now().Add(h.config.Session.SessionDuration),
-		HttpOnly: true,
-		SameSite: h.config.Session.SameSite,
-		Path:     "/",
-	})
+	passkeys, err := h.service.Passkeys(r.Context(), userID)
 
-	csrf, err := security.GenerateRandomBytesEncoded(64)
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	tokens, err := h.service.AccessTokens(r.Context(), userID)
 
 	if err != nil {
-		serverError := errtypes.ServerError(err)
-		response.RenderError(w, r, serverError)
+		response.RenderError(w, r, errtypes.ServerError(err))
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     h.config.Session.CSRFName,
-		Value:    csrf,
-		Expires:  time.Now().Add(h.config.Session.SessionDuration),
-		HttpOnly: false,
-		SameSite: h.config.Session.SameSite,
-		Path:     "/",
-	})
+	data := &profileData{
+		PageData:           NewPageData(r, "Profile"),
+		Sessions:           sessions,
+		TwoFactorAvailable: h.service.TwoFactorAvailable(),
+		TwoFactorEnabled:   twoFactorEnabled,
+		Passkeys:           passkeys,
+		AccessTokens:       tokens,
+		Scopes:             AccessTokenScopes,
+		Message:            profileMessages[r.URL.Query().Get("status")],
+	}
 
-	response.RenderJSON(w, http.StatusOK, res)
+	h.htmlTemplate.Render(w, "profile.html", data)
 }
 
-func (h *Handler) HandleProfile(w http.ResponseWriter, _ *http.Request) {
-	h.htmlTemplate.Render(w, "profile.html", nil)
+// Signs out the user by deleting the session and expiring the session cookies
+func (h *Handler) HandleSignOut(w http.ResponseWriter, r *http.Request) {
+	if err := h.sessionManager.DestroySession(r); err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if userID, _ := FromContext(r.Context()); userID != "" {
+		h.record(r, actionSignOut, userID, "", nil)
+	}
+
+	h.forgetBrowser(w, r)
+	clearCookie(w, h.config.Session.SessionName, true)
+	clearCookie(w, h.config.Session.CSRFName, false)
+
+	if r.Header.Get("content-type") != "application/json" {
+		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+		return
+	}
+
+	res := &response.APIResponse[map[string]string]{
+		Message: "Logged out.",
+		Data: &map[string]string{
+			"redirectUrl": redirectPath,
+		},
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
 }
@@ -0,0 +1,69 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+func TestHandleSignOut(t *testing.T) {
+	cfg := testConfig()
+	svc, _, _ := newTestService(cfg, newFakeRepo())
+	sessMgr := session.NewMemorySession(cfg.Session)
+	h, auditLog := newTestHandler(cfg, svc, sessMgr)
+
+	sessionID, err := sessMgr.StoreSession(context.Background(), "signed-in", session.Data{UserID: testUserID})
+	if err != nil {
+		t.Fatalf("store session: %v", err)
+	}
+
+	// The cookies the browser got when it signed in
+	signIn := httptest.NewRecorder()
+	setSessionCookies(signIn, cfg.Session, sessionID, "csrf-token")
+	set := signIn.Result()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/signout", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: cfg.Session.SessionName, Value: sessionID})
+	req.AddCookie(&http.Cookie{Name: cfg.Session.CSRFName, Value: "csrf-token"})
+	req = req.WithContext(WithUser(req.Context(), testUserID))
+
+	rec := httptest.NewRecorder()
+	h.HandleSignOut(rec, req)
+	res := rec.Result()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, res.StatusCode)
+	}
+
+	for _, name := range []string{cfg.Session.SessionName, cfg.Session.CSRFName} {
+		cleared, sent := responseCookie(res, name), responseCookie(set, name)
+
+		if cleared == nil {
+			t.Errorf("expected the %s cookie to be cleared", name)
+			continue
+		}
+
+		if cleared.MaxAge >= 0 || cleared.Value != "" {
+			t.Errorf("expected the %s cookie to be expired, got %v", name, cleared)
+		}
+
+		if cleared.Path != sent.Path || cleared.Secure != sent.Secure || cleared.HttpOnly != sent.HttpOnly {
+			t.Errorf("expected the %s cookie to be cleared with the attributes it was set with, %v, got %v", name, sent, cleared)
+		}
+	}
+
+	if _, err := sessMgr.LoadSession(req); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("expected the session to be destroyed, got %v", err)
+	}
+
+	if actions := auditLog.actions(); len(actions) != 1 || actions[0] != actionSignOut {
+		t.Errorf("expected the sign out to be recorded, got %v", actions)
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+const impersonationKey = "impersonation"
+
+// The admin who is acting as the user of the session
+type impersonation struct {
+	AdminID   string    `json:"admin_id"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Signs the admin in as another user, keeping the admin in the session so that they can switch back
+func (h *Handler) HandleStartImpersonation(w http.ResponseWriter, r *http.Request) {
+	adminID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	isJSON := r.Header.Get("content-type") == "application/json"
+
+	target, err := h.service.ImpersonationTarget(r.Context(), adminID, r.PathValue("id"))
+
+	if err != nil {
+		var status string
+
+		switch {
+		case errors.Is(err, ErrImpersonateSelf):
+			status = "impersonate-self"
+
+			if isJSON {
+				response.RenderError(w, r, errtypes.ForbiddenError(err))
+				return
+			}
+		case errors.Is(err, ErrImpersonateAdmin):
+			status = "impersonate-admin"
+
+			if isJSON {
+				response.RenderError(w, r, errtypes.ForbiddenError(err))
+				return
+			}
+		case errors.Is(err, ErrUserNotFound):
+			status = "user-not-found"
+
+			if isJSON {
+				response.RenderError(w, r, errtypes.NotFoundError(err))
+				return
+			}
+		default:
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		http.Redirect(w, r, adminUsersPath+"?status="+status, http.StatusSeeOther)
+		return
+	}
+
+	imp := impersonation{
+		AdminID:   adminID,
+		StartedAt: time.Now(),
+	}
+
+	data := session.Data{}
+
+	if err := session.Set(&data, impersonationKey, imp); err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if err := h.authenticateSession(w, r, data, target.ID); err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	h.record(r, actionImpersonationStart, adminID, target.ID, nil)
+
+	h.renderImpersonationSwitch(w, r, defaultRedirectPath, "You are now impersonating "+target.Email+".")
+}
+
+// Ends the impersonation and signs the admin back in as themselves
+func (h *Handler) HandleStopImpersonation(w http.ResponseWriter, r *http.Request) {
+	data, err := h.sessionManager.LoadSession(r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	imp, ok, err := session.Get[impersonation](data, impersonationKey)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if !ok {
+		response.RenderError(w, r, errtypes.InvalidRequestError(ErrNotImpersonating))
+		return
+	}
+
+	targetID := data.UserID
+
+	if err := h.authenticateSession(w, r, session.Data{}, imp.AdminID); err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	h.record(r, actionImpersonationStop, imp.AdminID, targetID, map[string]any{
+		"duration": time.Since(imp.StartedAt).Round(time.Second).String(),
+	})
+
+	h.renderImpersonationSwitch(w, r, adminUsersPath+"?status=impersonation-ended", adminMessages["impersonation-ended"])
+}
+
+func (h *Handler) renderImpersonationSwitch(w http.ResponseWriter, r *http.Request, redirectURL, msg string) {
+	if r.Header.Get("content-type") != "application/json" {
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	res := &response.APIResponse[map[string]string]{
+		Message: msg,
+		Data: &map[string]string{
+			"redirectUrl": redirectURL,
+		},
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
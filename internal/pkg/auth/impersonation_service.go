@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
+)
+
+var (
+	ErrImpersonateSelf  = errors.New("you cannot impersonate yourself")
+	ErrImpersonateAdmin = errors.New("users who can manage users cannot be impersonated")
+	ErrImpersonating    = errors.New("this action is not available while impersonating a user")
+	ErrNotImpersonating = errors.New("you are not impersonating a user")
+)
+
+// Returns the user that the admin may impersonate.
+// Users who can manage users cannot be impersonated, so that an admin never acts with the rights of another admin.
+func (s *service) ImpersonationTarget(ctx context.Context, actorID, userID string) (*user.User, error) {
+	if actorID == userID {
+		return nil, ErrImpersonateSelf
+	}
+
+	target, err := s.FindUser(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, permissions, err := s.repo.UserAccess(ctx, target.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(permissions, PermissionManageUsers) {
+		return nil, ErrImpersonateAdmin
+	}
+
+	return target, nil
+}
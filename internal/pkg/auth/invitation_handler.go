@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+	"github.com/ferdiebergado/gopherkit/http/request"
+)
+
+const adminInvitationsPath = "/admin/invitations"
+
+type adminInvitationsData struct {
+	response.PageData
+	Invitations      []Invitation
+	Roles            []Role
+	RegistrationMode string
+	Message          string
+}
+
+// Messages shown on the invitations page, keyed by the status query parameter
+var invitationMessages = map[string]string{
+	"sent":           "The invitation has been sent.",
+	"invalid":        "Enter a valid email and an expiration of at most 90 days.",
+	"user-exists":    "A user with that email already exists.",
+	"role-not-found": ErrRoleNotFound.Error(),
+	"revoked":        "The invitation has been revoked.",
+	"not-found":      ErrInvitationNotFound.Error(),
+}
+
+// Lists the invitations that were not accepted
+func (h *Handler) HandleAdminInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.service.Invitations(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if r.Header.Get("content-type") == "application/json" {
+		response.RenderJSON(w, http.StatusOK, &response.APIResponse[[]Invitation]{Data: &invitations})
+		return
+	}
+
+	roles, err := h.service.Roles(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	data := &adminInvitationsData{
+		PageData:         NewPageData(r, "Invitations"),
+		Invitations:      invitations,
+		Roles:            roles,
+		RegistrationMode: h.service.RegistrationMode(),
+		Message:          invitationMessages[r.URL.Query().Get("status")],
+	}
+
+	h.htmlTemplate.Render(w, "admin-invitations.html", data)
+}
+
+// Invites the owner of an email to sign up
+func (h *Handler) HandleCreateInvitation(w http.ResponseWriter, r *http.Request) {
+	actorID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	isJSON := r.Header.Get("content-type") == "application/json"
+
+	var params CreateInvitationParams
+
+	if isJSON {
+		if params, err = request.JSON[CreateInvitationParams](r); err != nil {
+			response.RenderError(w, r, errtypes.BadRequest(err))
+			return
+		}
+	} else {
+		expiresIn, _ := strconv.Atoi(r.PostFormValue("expires_in"))
+
+		params = CreateInvitationParams{
+			Email:     r.PostFormValue("email"),
+			Role:      r.PostFormValue("role"),
+			ExpiresIn: expiresIn,
+		}
+	}
+
+	inv, err := h.service.CreateInvitation(r.Context(), actorID, params)
+
+	if err != nil {
+		var status string
+
+		var inputErr *validation.Error
+		var emailErr *EmailExistsError
+
+		switch {
+		case errors.As(err, &inputErr):
+			if isJSON {
+				response.RenderError(w, r, errtypes.ValidationError(*inputErr))
+				return
+			}
+			status = "invalid"
+		case errors.As(err, &emailErr):
+			if isJSON {
+				response.RenderError(w, r, errtypes.ValidationError(*fieldError("email", emailErr.Error())))
+				return
+			}
+			status = "user-exists"
+		case errors.Is(err, ErrRoleNotFound):
+			if isJSON {
+				response.RenderError(w, r, errtypes.ValidationError(*fieldError("role", err.Error())))
+				return
+			}
+			status = "role-not-found"
+		default:
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		http.Redirect(w, r, adminInvitationsPath+"?status="+status, http.StatusSeeOther)
+		return
+	}
+
+	if !isJSON {
+		http.Redirect(w, r, adminInvitationsPath+"?status=sent", http.StatusSeeOther)
+		return
+	}
+
+	res := &response.APIResponse[NewInvitation]{
+		Message: invitationMessages["sent"],
+		Data:    inv,
+	}
+
+	response.RenderJSON(w, http.StatusCreated, res)
+}
+
+// Revokes an invitation that was not accepted
+func (h *Handler) HandleRevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	isJSON := r.Header.Get("content-type") == "application/json"
+	status := "revoked"
+	id := r.PathValue("id")
+
+	if err := h.service.RevokeInvitation(r.Context(), id); err != nil {
+		if !errors.Is(err, ErrInvitationNotFound) {
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		if isJSON {
+			response.RenderError(w, r, errtypes.NotFoundError(err))
+			return
+		}
+
+		status = "not-found"
+	} else {
+		actorID, _ := FromContext(r.Context())
+		h.record(r, actionInvitationRevoked, actorID, id, nil)
+	}
+
+	if !isJSON {
+		http.Redirect(w, r, adminInvitationsPath+"?status="+status, http.StatusSeeOther)
+		return
+	}
+
+	response.RenderJSON(w, http.StatusOK, &response.APIResponse[any]{Message: invitationMessages["revoked"]})
+}
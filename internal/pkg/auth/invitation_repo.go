@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+)
+
+var ErrInvalidInvitation = errors.New("the invitation is invalid or has expired")
+
+type InvitationStore interface {
+	SaveInvitation(ctx context.Context, inv *Invitation, invitedBy, tokenHash string) error
+	FindInvitation(ctx context.Context, tokenHash string) (*Invitation, error)
+	ListInvitations(ctx context.Context) ([]Invitation, error)
+	DeleteInvitation(ctx context.Context, id string) (bool, error)
+	SignUpInvited(ctx context.Context, params SignUpParams, tokenHash string) (*user.User, error)
+}
+
+// Invitation lets the owner of the email sign up, with the role when it is set.
+type Invitation struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role,omitempty"`
+	InvitedBy string    `json:"invited_by,omitempty"` // Email of the admin who sent it
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Reports whether the invitation can no longer be accepted
+func (i Invitation) Expired() bool {
+	return !i.ExpiresAt.After(time.Now())
+}
+
+const saveInvitationQuery = `
+INSERT INTO invitations (email, token_hash, role, invited_by, expires_at)
+VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, '')::uuid, $5)
+RETURNING id, created_at
+`
+
+// Saves a new invitation sent by the user, replacing the pending invitations to the same email.
+// Sets the id and the creation time of the invitation.
+func (r *repo) SaveInvitation(ctx context.Context, inv *Invitation, invitedBy, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("begin save invitation: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const deleteQuery = "DELETE FROM invitations WHERE LOWER(email) = LOWER($1) AND accepted_at IS NULL"
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, inv.Email); err != nil {
+		return fmt.Errorf("delete previous invitations: %w", err)
+	}
+
+	row := tx.QueryRowContext(ctx, saveInvitationQuery, inv.Email, tokenHash, inv.Role, invitedBy, inv.ExpiresAt)
+
+	if err := row.Scan(&inv.ID, &inv.CreatedAt); err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return ErrRoleNotFound
+		}
+
+		return fmt.Errorf("save invitation: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Finds the pending invitation with the token, sql.ErrNoRows when it was accepted or has expired
+func (r *repo) FindInvitation(ctx context.Context, tokenHash string) (*Invitation, error) {
+	const q = `SELECT id, email, COALESCE(role, ''), expires_at, created_at FROM invitations
+WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > NOW()`
+
+	var inv Invitation
+
+	if err := r.db.QueryRowContext(ctx, q, tokenHash).Scan(&inv.ID, &inv.Email, &inv.Role, &inv.ExpiresAt, &inv.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &inv, nil
+}
+
+const listInvitationsQuery = `
+SELECT i.id, i.email, COALESCE(i.role, ''), COALESCE(u.email, ''), i.expires_at, i.created_at
+FROM invitations i
+LEFT JOIN users u ON u.id = i.invited_by
+WHERE i.accepted_at IS NULL
+ORDER BY i.created_at DESC
+`
+
+// Lists the invitations that were not accepted, newest first
+func (r *repo) ListInvitations(ctx context.Context) ([]Invitation, error) {
+	rows, err := r.db.QueryContext(ctx, listInvitationsQuery)
+
+	if err != nil {
+		return nil, fmt.Errorf("list invitations: %w", err)
+	}
+
+	defer rows.Close()
+
+	invitations := []Invitation{}
+
+	for rows.Next() {
+		var inv Invitation
+
+		if err := rows.Scan(&inv.ID, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.ExpiresAt, &inv.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan invitation: %w", err)
+		}
+
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, rows.Err()
+}
+
+// Deletes an invitation that was not accepted. Reports false when there is no such invitation.
+func (r *repo) DeleteInvitation(ctx context.Context, id string) (bool, error) {
+	const q = "DELETE FROM invitations WHERE id::text = $1 AND accepted_at IS NULL"
+
+	res, err := r.db.ExecContext(ctx, q, id)
+
+	if err != nil {
+		return false, fmt.Errorf("delete invitation: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("delete invitation: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+const acceptInvitationQuery = `
+UPDATE invitations SET accepted_at = NOW()
+WHERE token_hash = $1 AND LOWER(email) = LOWER($2) AND accepted_at IS NULL AND expires_at > NOW()
+RETURNING COALESCE(role, '')
+`
+
+// The invitation was sent to the email, so it is verified from the start
+const signUpInvitedQuery = `
+INSERT INTO users (email, password_hash, auth_method, email_verified_at)
+VALUES ($1, $2, $3, NOW())
+RETURNING id, email, email_verified_at, auth_method, created_at, updated_at
+`
+
+// Accepts the invitation and signs up its holder in one transaction, assigning the role of the invitation.
+// Returns ErrInvalidInvitation when the invitation is not pending or was sent to another email.
+func (r *repo) SignUpInvited(ctx context.Context, params SignUpParams, tokenHash string) (*user.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("begin sign up invited: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var role string
+
+	if err := tx.QueryRowContext(ctx, acceptInvitationQuery, tokenHash, params.Email).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidInvitation
+		}
+
+		return nil, fmt.Errorf("accept invitation: %w", err)
+	}
+
+	var u user.User
+
+	row := tx.QueryRowContext(ctx, signUpInvitedQuery, params.Email, params.Password, user.BasicAuth)
+
+	if err := row.Scan(&u.ID, &u.Email, &u.EmailVerifiedAt, &u.AuthMethod, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, &EmailExistsError{Email: params.Email}
+		}
+
+		return nil, fmt.Errorf("sign up invited: %w", err)
+	}
+
+	if role != "" {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO user_roles (user_id, role) VALUES ($1, $2)", u.ID, role); err != nil {
+			return nil, fmt.Errorf("assign invited role: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit sign up invited: %w", err)
+	}
+
+	return &u, nil
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+var (
+	ErrRegistrationClosed = errors.New("sign up is closed")
+	ErrInvitationRequired = errors.New("sign up is by invitation only")
+	ErrInvitationNotFound = errors.New("invitation not found")
+)
+
+// Longest expiry of an invitation, in days
+const invitationMaxDays = 90
+
+type CreateInvitationParams struct {
+	Email     string `json:"email"`
+	Role      string `json:"role"`       // Empty for no role
+	ExpiresIn int    `json:"expires_in"` // In days, 0 for the configured default
+}
+
+// NewInvitation is an invitation that was just sent. Link is returned once and never stored.
+type NewInvitation struct {
+	Invitation
+	Link string `json:"link"`
+}
+
+// Returns who can sign up, see config.RegistrationOpen
+func (s *service) RegistrationMode() string {
+	return s.cfg.Auth.RegistrationMode
+}
+
+// Invites the owner of the email to sign up and sends them the link
+func (s *service) CreateInvitation(ctx context.Context, actorID string, params CreateInvitationParams) (*NewInvitation, error) {
+	form := validation.NewForm(params)
+	form.Required("Email")
+	form.IsEmail("Email")
+
+	if params.ExpiresIn < 0 || params.ExpiresIn > invitationMaxDays {
+		form.Error.Add("expires_in", fmt.Sprintf("Expiration must be at most %d days.", invitationMaxDays))
+	}
+
+	if !form.IsValid() {
+		return nil, &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	email := strings.TrimSpace(params.Email)
+
+	if _, err := s.repo.FindUserIDByEmail(ctx, email); err == nil {
+		return nil, &EmailExistsError{Email: email}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("find user: %w", err)
+	}
+
+	ttl := s.cfg.Auth.InvitationTTL
+
+	if params.ExpiresIn > 0 {
+		ttl = time.Duration(params.ExpiresIn) * 24 * time.Hour
+	}
+
+	token, err := security.GenerateRandomBytesEncoded(32)
+
+	if err != nil {
+		return nil, fmt.Errorf("generate invitation token: %w", err)
+	}
+
+	inv := &NewInvitation{
+		Invitation: Invitation{
+			Email:     email,
+			Role:      strings.TrimSpace(params.Role),
+			ExpiresAt: time.Now().Add(ttl),
+		},
+		Link: s.cfg.Server.BaseURL + "/signup?invitation=" + url.QueryEscape(token),
+	}
+
+	if err := s.repo.SaveInvitation(ctx, &inv.Invitation, actorID, security.HashToken(token)); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actionInvitationCreated, actorID, inv.ID, map[string]any{"email": inv.Email, "role": inv.Role})
+
+	msg := mail.Message{
+		To:      email,
+		Subject: "You are invited to sign up",
+		Body: fmt.Sprintf("You have been invited to create an account.\r\n\r\n"+
+			"Open the link below to sign up. It expires on %s.\r\n\r\n%s\r\n",
+			inv.ExpiresAt.Format(time.RFC1123), inv.Link),
+	}
+
+	// The admin gets the link as well and can pass it on
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		slog.Error("failed to send invitation", "invitation_id", inv.ID, "error", err)
+	}
+
+	return inv, nil
+}
+
+// Finds the pending invitation with the token
+func (s *service) Invitation(ctx context.Context, token string) (*Invitation, error) {
+	if token == "" {
+		return nil, ErrInvalidInvitation
+	}
+
+	inv, err := s.repo.FindInvitation(ctx, security.HashToken(token))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidInvitation
+		}
+
+		return nil, fmt.Errorf("find invitation: %w", err)
+	}
+
+	return inv, nil
+}
+
+// Lists the invitations that were not accepted
+func (s *service) Invitations(ctx context.Context) ([]Invitation, error) {
+	return s.repo.ListInvitations(ctx)
+}
+
+func (s *service) RevokeInvitation(ctx context.Context, id string) error {
+	deleted, err := s.repo.DeleteInvitation(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+// Checks that the sign up is allowed by the registration mode
+func (s *service) checkRegistration(invitation string) error {
+	switch s.cfg.Auth.RegistrationMode {
+	case config.RegistrationClosed:
+		return ErrRegistrationClosed
+	case config.RegistrationInviteOnly:
+		if invitation == "" {
+			return ErrInvitationRequired
+		}
+	}
+
+	return nil
+}
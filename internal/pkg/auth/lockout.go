@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/client"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/lockout"
+)
+
+// Limits the failed sign ins of each account and from each client address.
+//
+// Accounts get progressively longer delays before they are locked out, addresses are only locked
+// after many more failures since they may be shared by a whole network.
+type signInLockout struct {
+	accounts  *lockout.Limiter
+	addresses *lockout.Limiter
+}
+
+func newSignInLockout(cfg config.LockoutConfig, store lockout.Store) *signInLockout {
+	return &signInLockout{
+		accounts: lockout.NewLimiter("account", store, lockout.Policy{
+			Threshold: cfg.MaxAttempts,
+			Window:    cfg.Window,
+			Duration:  cfg.Duration,
+			Delay:     cfg.Delay,
+		}),
+		addresses: lockout.NewLimiter("ip", store, lockout.Policy{
+			Threshold: cfg.MaxAttemptsPerIP,
+			Window:    cfg.Window,
+			Duration:  cfg.Duration,
+		}),
+	}
+}
+
+// Returns a *lockout.LockedError when the account or the address of the client is locked
+func (l *signInLockout) check(ctx context.Context, email string) error {
+	if err := l.accounts.Check(ctx, accountKey(email)); err != nil {
+		return err
+	}
+
+	if ip := clientIP(ctx); ip != "" {
+		return l.addresses.Check(ctx, ip)
+	}
+
+	return nil
+}
+
+// Counts a failed sign in against the account and the address of the client
+func (l *signInLockout) fail(ctx context.Context, email string) error {
+	err := l.accounts.Fail(ctx, accountKey(email))
+
+	if ip := clientIP(ctx); ip != "" {
+		err = errors.Join(err, l.addresses.Fail(ctx, ip))
+	}
+
+	return err
+}
+
+// Forgets the failed sign ins of the account once its owner signed in.
+// Failures from the address are kept, so that one valid account does not hide guesses at others.
+func (l *signInLockout) succeed(ctx context.Context, email string) error {
+	return l.accounts.Reset(ctx, accountKey(email))
+}
+
+// Checks the password of the signed in user under the lockout of their account, so that a session left open
+// cannot be used to guess it. A wrong password counts as a failed sign in. See checkCurrentPassword.
+func (h *Handler) checkPasswordLocked(r *http.Request, userID string, check func() error) error {
+	u, err := h.service.FindUser(r.Context(), userID)
+
+	if err != nil {
+		return err
+	}
+
+	if err := h.lockout.check(r.Context(), u.Email); err != nil {
+		return err
+	}
+
+	err = check()
+
+	if errors.Is(err, errCurrentPasswordIncorrect) {
+		h.failSignIn(r, u.Email)
+	}
+
+	return err
+}
+
+// Counts a failed sign in, logging the failures to do so
+func (h *Handler) failSignIn(r *http.Request, email string) {
+	if err := h.lockout.fail(r.Context(), email); err != nil && !errors.Is(err, lockout.ErrLocked) {
+		slog.Error("failed to record sign in failure", "error", err)
+	}
+}
+
+// Forgets the failed sign ins of the user once they are signed in
+func (h *Handler) succeedSignIn(r *http.Request, userID string) {
+	u, err := h.service.FindUser(r.Context(), userID)
+
+	if err == nil {
+		err = h.lockout.succeed(r.Context(), u.Email)
+	}
+
+	if err != nil {
+		slog.Error("failed to reset sign in failures", "user_id", userID, "error", err)
+	}
+}
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func clientIP(ctx context.Context) string {
+	info, _ := client.FromContext(ctx)
+	return info.IP
+}
+
+// Renders a lockout as 429 Too Many Requests with the time to wait in Retry-After
+func renderLockoutError(w http.ResponseWriter, r *http.Request, err error) {
+	var locked *lockout.LockedError
+
+	if errors.As(err, &locked) {
+		response.RenderError(w, r, errtypes.TooManyRequestsError(locked, locked.RetryAfter))
+		return
+	}
+
+	response.RenderError(w, r, errtypes.ServerError(err))
+}
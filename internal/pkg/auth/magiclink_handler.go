@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+	"github.com/ferdiebergado/gopherkit/http/request"
+)
+
+const (
+	magicLinkPath           = "/signin/magic-link"
+	magicLinkNonceCookie    = "magic_link_nonce"
+	magicLinkRedirectCookie = "magic_link_redirect"
+)
+
+type magicLinkData struct {
+	response.PageData
+	Error string
+}
+
+func (h *Handler) HandleMagicLink(w http.ResponseWriter, _ *http.Request) {
+	data := &magicLinkData{
+		PageData: response.PageData{Title: "Email Me a Sign In Link"},
+	}
+
+	h.htmlTemplate.Render(w, "magic-link.html", data)
+}
+
+// Sends a sign in link to the email and binds it to the browser with a nonce cookie
+func (h *Handler) HandleMagicLinkForm(w http.ResponseWriter, r *http.Request) {
+	params, err := request.JSON[MagicLinkParams](r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	nonce, err := security.GenerateRandomBytesEncoded(32)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(fmt.Errorf("generate magic link nonce: %w", err)))
+		return
+	}
+
+	if err := h.service.RequestMagicLink(r.Context(), params, nonce); err != nil {
+		var inputErr *validation.Error
+		if errors.As(err, &inputErr) {
+			response.RenderError(w, r, errtypes.ValidationError(*inputErr))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	// Set for unknown emails as well, so that the response does not tell them apart
+	h.setMagicLinkCookie(w, magicLinkNonceCookie, nonce)
+
+	// The strict session cookie is not sent when the link is opened from the mail client,
+	// so the page the user was headed to travels in a lax cookie instead
+	if sessionData, err := h.sessionManager.LoadSession(r); err == nil {
+		if intendedURL := sessionData.Flash["intendedUrl"]; isLocalPath(intendedURL) {
+			h.setMagicLinkCookie(w, magicLinkRedirectCookie, intendedURL)
+		}
+	}
+
+	res := &response.APIResponse[any]{
+		Message: "If an account exists for that email, a sign in link has been sent to it. Open it in this browser.",
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Signs in the user with the link sent by email, the same way as with a password
+func (h *Handler) HandleMagicLinkSignIn(w http.ResponseWriter, r *http.Request) {
+	var nonce string
+
+	if cookie, err := r.Cookie(magicLinkNonceCookie); err == nil {
+		nonce = cookie.Value
+	}
+
+	userID, err := h.service.SignInMagicLink(r.Context(), r.URL.Query().Get("token"), nonce)
+
+	if err != nil {
+		if errors.Is(err, ErrInvalidMagicLink) || errors.Is(err, ErrMagicLinkOtherDevice) {
+			data := &magicLinkData{
+				PageData: response.PageData{Title: "Email Me a Sign In Link"},
+				Error:    err.Error(),
+			}
+
+			h.htmlTemplate.Render(w, "magic-link.html", data)
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	data := session.Data{}
+
+	if sessionData, err := h.sessionManager.LoadSession(r); err == nil {
+		data = *sessionData
+	}
+
+	if cookie, err := r.Cookie(magicLinkRedirectCookie); err == nil && isLocalPath(cookie.Value) {
+		data.Flash = map[string]string{
+			"intendedUrl": cookie.Value,
+		}
+	}
+
+	redirectURL, err := h.completeSignIn(w, r, data, userID, signInMagicLink, false)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	clearMagicLinkCookie(w, magicLinkNonceCookie)
+	clearMagicLinkCookie(w, magicLinkRedirectCookie)
+
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+// Sends a cookie that lives as long as the sign in link and is only sent to its pages
+func (h *Handler) setMagicLinkCookie(w http.ResponseWriter, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Expires:  time.Now().Add(h.config.Auth.MagicLinkTTL),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode, // Sent when the link is opened from the mail client
+		Path:     magicLinkPath,
+	})
+}
+
+// Expires a cookie of the sign in links
+func clearMagicLinkCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Path:     magicLinkPath,
+	})
+}
+
+// Reports whether the url is a path of this site, so that redirecting to it cannot leave the site
+func isLocalPath(u string) bool {
+	return strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") && !strings.HasPrefix(u, "/\\")
+}
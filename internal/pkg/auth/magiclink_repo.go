@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type MagicLinkStore interface {
+	CreateMagicLinkToken(ctx context.Context, userID, tokenHash, nonceHash string, expiresAt time.Time) error
+	ConsumeMagicLinkToken(ctx context.Context, tokenHash, nonceHash string) (string, error)
+}
+
+// Saves a new sign in token for the user, discarding the tokens previously issued to them.
+func (r *repo) CreateMagicLinkToken(ctx context.Context, userID, tokenHash, nonceHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("begin create magic link token: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM magic_link_tokens WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("delete previous magic link tokens: %w", err)
+	}
+
+	const q = "INSERT INTO magic_link_tokens (user_id, token_hash, nonce_hash, expires_at) VALUES ($1, $2, $3, $4)"
+
+	if _, err := tx.ExecContext(ctx, q, userID, tokenHash, nonceHash, expiresAt); err != nil {
+		return fmt.Errorf("save magic link token: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+const consumeMagicLinkTokenQuery = `
+UPDATE magic_link_tokens SET used_at = NOW()
+WHERE token_hash = $1 AND nonce_hash = $2 AND used_at IS NULL AND expires_at > NOW()
+AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+RETURNING user_id
+`
+
+// Marks the sign in token as used when it was issued to the browser with the nonce.
+// Returns the id of its user, sql.ErrNoRows when there is no such unused token.
+func (r *repo) ConsumeMagicLinkToken(ctx context.Context, tokenHash, nonceHash string) (string, error) {
+	var userID string
+
+	if err := r.db.QueryRowContext(ctx, consumeMagicLinkTokenQuery, tokenHash, nonceHash).Scan(&userID); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+var ErrInvalidMagicLink = errors.New("the sign in link is invalid or has expired")
+var ErrMagicLinkOtherDevice = errors.New("open the sign in link in the browser where you asked for it")
+
+type MagicLinkParams struct {
+	Email string `json:"email"`
+}
+
+// Sends a single-use sign in link to the email if it belongs to a user.
+//
+// The link only works in the browser holding the nonce, so a link that leaks from the mailbox cannot be used elsewhere.
+// The token is bound to the nonce and mailed after this returns, so a request for an unknown email
+// gets the same answer, in the same time, as one for an account.
+func (s *service) RequestMagicLink(ctx context.Context, params MagicLinkParams, nonce string) error {
+	form := validation.NewForm(params)
+	form.Required("Email")
+	form.IsEmail("Email")
+
+	if !form.IsValid() {
+		return &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	s.background(ctx, "send sign in link", func(ctx context.Context) error {
+		return s.sendMagicLink(ctx, params.Email, nonce)
+	})
+
+	return nil
+}
+
+func (s *service) sendMagicLink(ctx context.Context, email, nonce string) error {
+	userID, err := s.repo.FindUserIDByEmail(ctx, email)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			slog.Debug("sign in link requested for unknown email")
+			return nil
+		}
+
+		return fmt.Errorf("find user: %w", err)
+	}
+
+	token, err := security.GenerateRandomBytesEncoded(32)
+
+	if err != nil {
+		return fmt.Errorf("generate magic link token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.cfg.Auth.MagicLinkTTL)
+
+	if err := s.repo.CreateMagicLinkToken(ctx, userID, security.HashToken(token), security.HashToken(nonce), expiresAt); err != nil {
+		return err
+	}
+
+	link := s.cfg.Server.BaseURL + magicLinkPath + "/confirm?token=" + url.QueryEscape(token)
+
+	msg := mail.Message{
+		To:      email,
+		Subject: "Your sign in link",
+		Body: fmt.Sprintf("Open the link below in the same browser to sign in. It expires in %s and works once.\r\n\r\n%s\r\n\r\n"+
+			"If you did not ask for it, you can ignore this message.\r\n",
+			s.cfg.Auth.MagicLinkTTL, link),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send magic link: %w", err)
+	}
+
+	return nil
+}
+
+// Consumes a sign in link opened in the browser holding the nonce. Returns the id of the user to sign in.
+func (s *service) SignInMagicLink(ctx context.Context, token, nonce string) (string, error) {
+	if nonce == "" {
+		return "", ErrMagicLinkOtherDevice
+	}
+
+	if token == "" {
+		return "", ErrInvalidMagicLink
+	}
+
+	userID, err := s.repo.ConsumeMagicLinkToken(ctx, security.HashToken(token), security.HashToken(nonce))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvalidMagicLink
+		}
+
+		return "", fmt.Errorf("consume magic link token: %w", err)
+	}
+
+	return userID, nil
+}
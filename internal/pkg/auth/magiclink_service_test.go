@@ -0,0 +1,107 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Returns the token of the link in the message
+func mailedToken(t *testing.T, body string) string {
+	t.Helper()
+
+	_, rest, found := strings.Cut(body, "token=")
+	if !found {
+		t.Fatalf("expected a link with a token in %q", body)
+	}
+
+	token, err := url.QueryUnescape(strings.Fields(rest)[0])
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+
+	return token
+}
+
+func TestRequestMagicLink(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	svc, mailer, _ := newTestService(testConfig(), repo)
+
+	if err := svc.RequestMagicLink(ctx, MagicLinkParams{Email: "nobody@example.com"}, "nonce"); err != nil {
+		t.Fatalf("expected an unknown email to be accepted, got %v", err)
+	}
+
+	if err := svc.RequestMagicLink(ctx, MagicLinkParams{Email: testEmail}, "nonce"); err != nil {
+		t.Fatalf("request magic link: %v", err)
+	}
+
+	svc.tasks.Wait()
+
+	sent := mailer.messages()
+
+	if len(sent) != 1 || sent[0].To != testEmail {
+		t.Fatalf("expected a single link sent to %s, got %+v", testEmail, sent)
+	}
+
+	if len(repo.magicLinks) != 1 {
+		t.Errorf("expected a single token, got %d", len(repo.magicLinks))
+	}
+}
+
+func TestSignInMagicLink(t *testing.T) {
+	ctx := context.Background()
+
+	// Returns the service and the token of a link requested from the browser holding the nonce
+	request := func(t *testing.T, nonce string) (*service, string) {
+		t.Helper()
+
+		svc, mailer, _ := newTestService(testConfig(), newFakeRepo())
+
+		if err := svc.RequestMagicLink(ctx, MagicLinkParams{Email: testEmail}, nonce); err != nil {
+			t.Fatalf("request magic link: %v", err)
+		}
+
+		svc.tasks.Wait()
+
+		sent := mailer.messages()
+		if len(sent) != 1 {
+			t.Fatalf("expected a link to be sent, got %d messages", len(sent))
+		}
+
+		return svc, mailedToken(t, sent[0].Body)
+	}
+
+	t.Run("signs in the browser holding the nonce once", func(t *testing.T) {
+		svc, token := request(t, "browser-nonce")
+
+		userID, err := svc.SignInMagicLink(ctx, token, "browser-nonce")
+		if err != nil || userID != testUserID {
+			t.Fatalf("expected to sign in %s, got %q, %v", testUserID, userID, err)
+		}
+
+		if _, err := svc.SignInMagicLink(ctx, token, "browser-nonce"); !errors.Is(err, ErrInvalidMagicLink) {
+			t.Errorf("expected the link to work once, got %v", err)
+		}
+	})
+
+	t.Run("rejects another browser", func(t *testing.T) {
+		svc, token := request(t, "browser-nonce")
+
+		if _, err := svc.SignInMagicLink(ctx, token, ""); !errors.Is(err, ErrMagicLinkOtherDevice) {
+			t.Errorf("expected ErrMagicLinkOtherDevice without a nonce, got %v", err)
+		}
+
+		if _, err := svc.SignInMagicLink(ctx, token, "other-nonce"); !errors.Is(err, ErrInvalidMagicLink) {
+			t.Errorf("expected ErrInvalidMagicLink with another nonce, got %v", err)
+		}
+
+		if _, err := svc.SignInMagicLink(ctx, token, "browser-nonce"); err != nil {
+			t.Errorf("expected the link to still work in its browser, got %v", err)
+		}
+	})
+}
@@ -1,22 +1,45 @@
 package auth
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/middleware"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
 )
 
-const redirectPath = "/signin"
+const (
+	redirectPath        = "/signin"
+	defaultRedirectPath = "/dashboard"
+)
+
+// RememberTokenRotator signs a browser back in with its remember me token.
+type RememberTokenRotator interface {
+	RotateRememberToken(ctx context.Context, token string) (*RememberToken, string, error)
+}
 
-func SessionMiddleware(cfg config.SessionConfig, sessMgr session.Manager) middleware.Middleware {
+// SessionMiddleware loads the session of the request into the context.
+//
+// When the session has expired, the remember me token of the browser, if any, opens a new session
+// and is replaced with the next token of its family.
+func SessionMiddleware(cfg config.SessionConfig, sessMgr session.Manager, rotator RememberTokenRotator) middleware.Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			sessionData, err := sessMgr.LoadSession(r)
 
+			if err != nil {
+				sessionData, err = restoreSession(w, r, cfg, sessMgr, rotator)
+			}
+
 			if err != nil {
 				slog.Debug("No data for session")
 				next.ServeHTTP(w, r)
@@ -25,13 +48,254 @@ func SessionMiddleware(cfg config.SessionConfig, sessMgr session.Manager) middle
 
 			slog.Debug("Session", "data", sessionData, "user_id", sessionData.UserID)
 
-			ctx := WithUser(r.Context(), sessionData.UserID)
+			principal := &Principal{UserID: sessionData.UserID}
+
+			if imp, ok, err := session.Get[impersonation](sessionData, impersonationKey); err != nil {
+				slog.Error("failed to read the impersonation of the session", "error", err)
+			} else if ok {
+				principal.ImpersonatorID = imp.AdminID
+			}
+
+			ctx := session.WithData(r.Context(), sessionData)
+			ctx = WithPrincipal(ctx, principal)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func RequireUserMiddleware(sessMgr session.Manager) middleware.Middleware {
+// Opens a new session for the user of the remember me token of the request
+func restoreSession(w http.ResponseWriter, r *http.Request, cfg config.SessionConfig, sessMgr session.Manager, rotator RememberTokenRotator) (*session.Data, error) {
+	cookie, err := r.Cookie(cfg.RememberName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The new session has a csrf token that the request cannot carry, so unsafe requests would be rejected anyway
+	if !middleware.IsSafeMethod(r.Method) {
+		return nil, ErrRememberTokenInvalid
+	}
+
+	ctx := r.Context()
+
+	next, token, err := rotator.RotateRememberToken(ctx, cookie.Value)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRememberTokenReused):
+			// Whoever else used the token may have opened a session with it
+			if _, err := revokeUserSessions(ctx, sessMgr, next.UserID, ""); err != nil {
+				slog.Error("failed to revoke sessions after a remember me token was reused", "user_id", next.UserID, "error", err)
+			}
+
+			slog.Warn("remember me token reused, its family was revoked", "user_id", next.UserID)
+			clearCookie(w, cfg.RememberName, true)
+		case errors.Is(err, ErrRememberTokenRotated):
+			// A concurrent request replaced it, and the browser keeps the cookie that it sets
+		case errors.Is(err, ErrRememberTokenInvalid):
+			clearCookie(w, cfg.RememberName, true)
+		default:
+			// The cookie is kept, since the token may still work once the database is back
+			slog.Error("failed to rotate the remember me token", "error", err)
+		}
+
+		return nil, err
+	}
+
+	// Sent first, since the old token no longer works even if the session cannot be saved
+	setRememberCookie(w, cfg, token, next.ExpiresAt)
+
+	userID := next.UserID
+
+	csrf, err := security.GenerateRandomBytesEncoded(64)
+
+	if err != nil {
+		return nil, fmt.Errorf("generate csrf token: %w", err)
+	}
+
+	data := session.Data{UserID: userID, CSRFToken: csrf}
+
+	if err := session.Set(&data, rememberFamilyKey, next.FamilyID); err != nil {
+		return nil, err
+	}
+
+	oldSessionID, err := sessMgr.ExtractSessionID(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sid, err := sessMgr.Regenerate(ctx, oldSessionID, data)
+
+	if err != nil {
+		slog.Error("failed to save the session restored by the remember me token", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	setSessionCookies(w, cfg, sid, csrf)
+
+	return &data, nil
+}
+
+// AccessTokenAuthenticator finds the personal access token sent by an API client.
+type AccessTokenAuthenticator interface {
+	AuthenticateAccessToken(ctx context.Context, token string) (*AccessToken, error)
+}
+
+// BearerMiddleware authenticates requests that carry a personal access token in the Authorization header.
+//
+// Safe requests need the read scope and the others the write scope.
+// Requests without a bearer token are left to the session.
+func BearerMiddleware(authenticator AccessTokenAuthenticator) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+
+			if !found || !strings.EqualFold(scheme, "Bearer") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			accessToken, err := authenticator.AuthenticateAccessToken(r.Context(), strings.TrimSpace(token))
+
+			if err != nil {
+				if errors.Is(err, ErrAccessTokenInvalid) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					response.RenderError(w, r, errtypes.AuthenticationError(err))
+					return
+				}
+
+				response.RenderError(w, r, errtypes.ServerError(err))
+				return
+			}
+
+			scope := ScopeWrite
+
+			if middleware.IsSafeMethod(r.Method) {
+				scope = ScopeRead
+			}
+
+			if !accessToken.HasScope(scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				response.RenderError(w, r, errtypes.ForbiddenError(ErrInsufficientScope))
+				return
+			}
+
+			ctx := middleware.WithoutCSRF(WithAccessToken(r.Context(), accessToken))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RoleLoader finds the roles of a user and the permissions that they grant.
+type RoleLoader interface {
+	UserAccess(ctx context.Context, userID string) (roles, permissions []string, err error)
+}
+
+// AuthorizationMiddleware makes the roles and permissions of the signed in user available through the principal.
+// They are only loaded when a handler asks for them, and at most once per request.
+// Place it after the middlewares that authenticate the request.
+// Requests whose path starts with any of skipPrefixes are left alone.
+func AuthorizationMiddleware(loader RoleLoader, skipPrefixes ...string) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+
+			if !ok || principal.UserID == "" || hasAnyPrefix(r.URL.Path, skipPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			userID := principal.UserID
+
+			authorized := *principal
+			authorized.access = &lazyAccess{
+				load: func() ([]string, []string, error) {
+					return loader.UserAccess(ctx, userID)
+				},
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, &authorized)))
+		})
+	}
+}
+
+// UserFinder finds a user by id.
+type UserFinder interface {
+	FindUser(ctx context.Context, userID string) (*user.User, error)
+}
+
+// CurrentUserMiddleware makes the signed in user available through CurrentUser.
+// The user is only loaded when a handler asks for it, and at most once per request.
+// Place it after the middlewares that authenticate the request.
+// Requests whose path starts with any of skipPrefixes are left alone.
+func CurrentUserMiddleware(finder UserFinder, skipPrefixes ...string) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+
+			if !ok || principal.UserID == "" || hasAnyPrefix(r.URL.Path, skipPrefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			userID := principal.UserID
+
+			withUser := *principal
+			withUser.user = &lazyUser{
+				load: func() (*user.User, error) {
+					return finder.FindUser(ctx, userID)
+				},
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(ctx, &withUser)))
+		})
+	}
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequirePermission lets through only users with a role that grants the permission.
+// Place it after RequireUserMiddleware.
+func RequirePermission(permission string) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+
+			if !ok || principal.UserID == "" {
+				response.RenderError(w, r, errtypes.AuthenticationError(ErrUserNotInContext))
+				return
+			}
+
+			can, err := principal.Can(permission)
+
+			if err != nil {
+				response.RenderError(w, r, errtypes.ServerError(err))
+				return
+			}
+
+			if !can {
+				slog.Warn("permission denied", "user_id", principal.UserID, "permission", permission, "path", r.URL.Path)
+				response.RenderError(w, r, errtypes.ForbiddenError(ErrPermissionDenied))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RequireUserMiddleware(cfg config.SessionConfig, sessMgr session.Manager) middleware.Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userID, err := FromContext(r.Context())
@@ -55,26 +319,97 @@ func RequireUserMiddleware(sessMgr session.Manager) middleware.Middleware {
 				sessionData, err := sessMgr.LoadSession(r)
 
 				if err != nil {
-					slog.Error("no session data")
-				} else {
-					sessionData.Flash = map[string]string{
-						"intendedUrl": r.URL.Path,
-					}
+					slog.Debug("no session data, starting an anonymous session")
+					sessionData = &session.Data{}
+				}
 
-					sessionID, err := sessMgr.ExtractSessionID(r)
+				sessionData.Flash = map[string]string{
+					"intendedUrl": r.URL.Path,
+				}
+
+				sessionID, err := sessMgr.ExtractSessionID(r)
 
-					if err != nil {
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				sessionID, err = sessMgr.StoreSession(r.Context(), sessionID, *sessionData)
+
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				setSessionCookie(w, cfg, sessionID)
+
+				http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BlockImpersonationMiddleware turns away admins who are impersonating the user,
+// so that the changes that only the user should make stay with the user.
+// Place it after RequireUserMiddleware.
+func BlockImpersonationMiddleware() middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, ok := PrincipalFromContext(r.Context()); ok && principal.Impersonated() {
+				slog.Warn("action blocked while impersonating", "user_id", principal.UserID, "admin_id", principal.ImpersonatorID, "path", r.URL.Path)
+				response.RenderError(w, r, errtypes.DeniedError(ErrImpersonating))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// VerificationChecker reports whether a user has verified their email address.
+type VerificationChecker interface {
+	IsEmailVerified(ctx context.Context, userID string) (bool, error)
+}
+
+// RequireVerifiedMiddleware lets through only users who verified their email address.
+// Place it after RequireUserMiddleware.
+func RequireVerifiedMiddleware(checker VerificationChecker) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, err := FromContext(r.Context())
+
+			if err != nil || userID == "" {
+				response.RenderError(w, r, errtypes.AuthenticationError(ErrUserNotInContext))
+				return
+			}
+
+			verified, err := checker.IsEmailVerified(r.Context(), userID)
+
+			if err != nil {
+				response.RenderError(w, r, errtypes.ServerError(err))
+				return
+			}
+
+			if !verified {
+				if r.Header.Get("content-type") == "application/json" {
+					type redirectData struct {
+						RedirectPath string `json:"redirect_path"`
 					}
 
-					if err := sessMgr.StoreSession(r.Context(), sessionID, *sessionData); err != nil {
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
+					data := &response.APIResponse[redirectData]{
+						Message: "Verify your email address to access this resource.",
+						Data: &redirectData{
+							RedirectPath: verifyEmailPath,
+						},
 					}
+					response.RenderJSON(w, http.StatusForbidden, data)
+					return
 				}
 
-				http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+				http.Redirect(w, r, verifyEmailPath, http.StatusSeeOther)
 				return
 			}
 
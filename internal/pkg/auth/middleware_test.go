@@ -0,0 +1,355 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/middleware"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+// Returns the cookie of the response with the name, nil when it sets none
+func responseCookie(res *http.Response, name string) *http.Cookie {
+	for _, c := range res.Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+var noContent = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+})
+
+func TestSessionMiddlewareRemember(t *testing.T) {
+	cfg := testConfig()
+	repo := newFakeRepo()
+	svc, _, _ := newTestService(cfg, repo)
+
+	var restored *session.Data
+
+	handler := SessionMiddleware(cfg.Session, session.NewMemorySession(cfg.Session), svc)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			restored, _ = session.FromContext(r.Context())
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+	serve := func(method, token string) *http.Response {
+		restored = nil
+
+		req := httptest.NewRequest(method, "/dashboard", nil)
+		req.AddCookie(&http.Cookie{Name: cfg.Session.RememberName, Value: token})
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Result()
+	}
+
+	first, token, err := svc.CreateRememberToken(context.Background(), testUserID)
+	if err != nil {
+		t.Fatalf("create remember token: %v", err)
+	}
+
+	t.Run("ignores unsafe requests", func(t *testing.T) {
+		res := serve(http.MethodPost, token)
+
+		if restored != nil || responseCookie(res, cfg.Session.RememberName) != nil {
+			t.Error("expected the session not to be restored")
+		}
+	})
+
+	t.Run("opens a session linked to the family of the token", func(t *testing.T) {
+		res := serve(http.MethodGet, token)
+
+		if restored == nil || restored.UserID != testUserID {
+			t.Fatalf("expected a session of %s, got %+v", testUserID, restored)
+		}
+
+		familyID, ok, err := session.Get[string](restored, rememberFamilyKey)
+		if err != nil || !ok || familyID != first.FamilyID {
+			t.Errorf("expected the session to be linked to the family %s, got %q", first.FamilyID, familyID)
+		}
+
+		next := responseCookie(res, cfg.Session.RememberName)
+		if next == nil || next.Value == token || responseCookie(res, cfg.Session.SessionName) == nil {
+			t.Errorf("expected the next token and a session cookie, got %v", res.Cookies())
+		}
+	})
+
+	t.Run("keeps the cookie of a token replaced by a concurrent request", func(t *testing.T) {
+		res := serve(http.MethodGet, token)
+
+		if restored != nil {
+			t.Error("expected the replaced token not to open a session")
+		}
+
+		if c := responseCookie(res, cfg.Session.RememberName); c != nil {
+			t.Errorf("expected the cookie to be left alone, got %v", c)
+		}
+	})
+
+	t.Run("clears the cookie of an invalid token", func(t *testing.T) {
+		res := serve(http.MethodGet, "invalid.token")
+
+		if c := responseCookie(res, cfg.Session.RememberName); c == nil || c.MaxAge >= 0 {
+			t.Errorf("expected the cookie to be cleared, got %v", c)
+		}
+	})
+}
+
+func TestBlockImpersonationMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		expected  int
+	}{
+		{"Should let anonymous requests through", nil, http.StatusNoContent},
+		{"Should let the user through", &Principal{UserID: testUserID}, http.StatusNoContent},
+		{"Should block an impersonating admin", &Principal{UserID: testUserID, ImpersonatorID: "admin"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/profile/password", nil)
+
+			if tt.principal != nil {
+				req = req.WithContext(WithPrincipal(req.Context(), tt.principal))
+			}
+
+			rec := httptest.NewRecorder()
+			BlockImpersonationMiddleware()(noContent).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestBearerMiddleware(t *testing.T) {
+	cfg := testConfig()
+	svc, _, _ := newTestService(cfg, newFakeRepo())
+
+	read := createTestAccessToken(t, svc, ScopeRead)
+	write := createTestAccessToken(t, svc, ScopeWrite)
+
+	// The csrf check comes after, so that mutating requests only pass when the token exempts them
+	handler := BearerMiddleware(svc)(middleware.CSRF(cfg.Session)(noContent))
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		expected int
+	}{
+		{"Should let a read token make safe requests", http.MethodGet, read.Token, http.StatusNoContent},
+		{"Should deny a read token mutating requests", http.MethodPost, read.Token, http.StatusForbidden},
+		{"Should deny a read token deleting", http.MethodDelete, read.Token, http.StatusForbidden},
+		{"Should deny a write token safe requests", http.MethodGet, write.Token, http.StatusForbidden},
+		{"Should let a write token make mutating requests without a csrf token", http.MethodPost, write.Token, http.StatusNoContent},
+		{"Should reject an invalid token", http.MethodGet, accessTokenPrefix + "invalid", http.StatusUnauthorized},
+		{"Should leave requests without a token to the csrf check", http.MethodPost, "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/notes", nil)
+			req.Header.Set("Content-Type", "application/json")
+
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+
+			if rec.Code != http.StatusNoContent && tt.token != "" && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected the WWW-Authenticate header to tell why the token was refused")
+			}
+		})
+	}
+}
+
+// Returns the same roles for every user and counts the calls
+type fakeRoleLoader struct {
+	roles       []string
+	permissions []string
+	err         error
+	calls       int
+}
+
+func (l *fakeRoleLoader) UserAccess(context.Context, string) ([]string, []string, error) {
+	l.calls++
+	return l.roles, l.permissions, l.err
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		loader   *fakeRoleLoader
+		expected int
+	}{
+		{"Should require a user", "", &fakeRoleLoader{}, http.StatusUnauthorized},
+		{"Should allow a granted permission", testUserID, &fakeRoleLoader{roles: []string{"admin"}, permissions: []string{"users.manage"}}, http.StatusNoContent},
+		{"Should deny a user without the permission", testUserID, &fakeRoleLoader{roles: []string{"editor"}, permissions: []string{"posts.edit"}}, http.StatusForbidden},
+		{"Should deny a user without roles", testUserID, &fakeRoleLoader{}, http.StatusForbidden},
+		{"Should fail when the roles cannot be loaded", testUserID, &fakeRoleLoader{err: errors.New("connection refused")}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			req = req.WithContext(WithPrincipal(req.Context(), &Principal{UserID: tt.userID}))
+
+			handler := AuthorizationMiddleware(tt.loader)(RequirePermission("users.manage")(noContent))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAuthorizationMiddlewareLoadsLazily(t *testing.T) {
+	loader := &fakeRoleLoader{roles: []string{"admin"}, permissions: []string{"users.manage"}}
+
+	var (
+		asks    int  // Times the handler asks for the role
+		isAdmin bool // What the handler was told
+	)
+
+	handler := AuthorizationMiddleware(loader, "/static/")(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		isAdmin = false
+
+		for range asks {
+			ok, err := principal.HasRole("admin")
+			if err != nil {
+				t.Errorf("has role: %v", err)
+			}
+
+			isAdmin = ok
+		}
+	}))
+
+	serve := func(path string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(WithPrincipal(req.Context(), &Principal{UserID: testUserID}))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve("/dashboard")
+
+	if loader.calls != 0 {
+		t.Errorf("expected no load when nothing asks for the roles, got %d", loader.calls)
+	}
+
+	asks = 3
+	serve("/dashboard")
+
+	if !isAdmin || loader.calls != 1 {
+		t.Errorf("expected the admin role from a single load per request, got %v from %d loads", isAdmin, loader.calls)
+	}
+
+	loader.calls = 0
+	serve("/static/app.css")
+
+	if isAdmin || loader.calls != 0 {
+		t.Errorf("expected skipped paths to have no roles, got %v from %d loads", isAdmin, loader.calls)
+	}
+}
+
+// Finds the test user and counts the calls
+type fakeUserFinder struct {
+	calls int
+}
+
+func (f *fakeUserFinder) FindUser(_ context.Context, userID string) (*user.User, error) {
+	f.calls++
+	return &user.User{Model: db.Model{ID: userID}, Email: testEmail}, nil
+}
+
+func TestCurrentUserMiddleware(t *testing.T) {
+	finder := &fakeUserFinder{}
+
+	var (
+		asks  int // Times the handler asks for the user
+		found *user.User
+		err   error
+	)
+
+	handler := CurrentUserMiddleware(finder, "/static/")(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		found, err = nil, nil
+
+		for range asks {
+			found, err = CurrentUser(r.Context())
+		}
+	}))
+
+	serve := func(path, userID string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+
+		if userID != "" {
+			req = req.WithContext(WithUser(req.Context(), userID))
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve("/dashboard", testUserID)
+
+	if finder.calls != 0 {
+		t.Errorf("expected no load when nothing asks for the user, got %d", finder.calls)
+	}
+
+	asks = 3
+	serve("/dashboard", testUserID)
+
+	if err != nil || found == nil || found.ID != testUserID || finder.calls != 1 {
+		t.Errorf("expected %s from a single load per request, got %v, %v from %d loads", testUserID, found, err, finder.calls)
+	}
+
+	serve("/dashboard", testUserID)
+
+	if finder.calls != 2 {
+		t.Errorf("expected the user to be loaded again for the next request, got %d loads", finder.calls)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		userID string
+	}{
+		{"Should not look up anonymous requests", "/dashboard", ""},
+		{"Should not look up skipped paths", "/static/app.css", testUserID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder.calls = 0
+			serve(tt.path, tt.userID)
+
+			if !errors.Is(err, ErrUserNotInContext) || finder.calls != 0 {
+				t.Errorf("expected %v without a lookup, got %v from %d loads", ErrUserNotInContext, err, finder.calls)
+			}
+		})
+	}
+}
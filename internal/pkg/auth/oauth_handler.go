@@ -0,0 +1,415 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/middleware"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/lockout"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/oidc"
+)
+
+const (
+	oauthPath       = "/auth/oidc"
+	oauthLinkPath   = oauthPath + "/link"
+	oauthFlowKey    = "oauth_flow"
+	oauthLinkKey    = "oauth_link"
+	oauthFlowCookie = "oauth_flow"
+	oauthFlowTTL    = 10 * time.Minute
+)
+
+var errOAuthFlowInvalid = errors.New("the sign-in request is invalid or has expired, please try again")
+
+// State of an authorization request, kept in the session until the provider redirects back
+type oauthFlow struct {
+	Provider  string    `json:"provider"`
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	Verifier  string    `json:"verifier"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Identity waiting for the owner of the account with the same email to confirm the link
+type oauthLink struct {
+	OAuthParams
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewOIDCProviders creates the configured OpenID Connect providers, redirecting back to the callback route of each.
+func NewOIDCProviders(cfg *config.Config, client *http.Client) []*oidc.Provider {
+	providers := make([]*oidc.Provider, 0, len(cfg.Auth.OIDCProviders))
+
+	for _, p := range cfg.Auth.OIDCProviders {
+		redirectURL := cfg.Server.BaseURL + oauthPath + "/" + p.Name + "/callback"
+		providers = append(providers, oidc.NewProvider(p, redirectURL, client))
+	}
+
+	return providers
+}
+
+func (h *Handler) provider(name string) (*oidc.Provider, bool) {
+	for _, p := range h.providers {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
+
+// Redirects the user to the provider to authenticate
+func (h *Handler) HandleOAuthStart(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.provider(r.PathValue("provider"))
+
+	if !ok {
+		response.RenderError(w, r, errtypes.NotFoundError(fmt.Errorf("unknown provider %q", r.PathValue("provider"))))
+		return
+	}
+
+	flow := oauthFlow{
+		Provider:  provider.Name(),
+		ExpiresAt: time.Now().Add(oauthFlowTTL),
+	}
+
+	for _, v := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		s, err := oidc.RandomString()
+
+		if err != nil {
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		*v = s
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), flow.State, flow.Nonce, flow.Verifier)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerUnavailableError(err))
+		return
+	}
+
+	data := &session.Data{}
+
+	if sessionData, err := h.sessionManager.LoadSession(r); err == nil {
+		data = sessionData
+	}
+
+	if err := session.Set(data, oauthFlowKey, flow); err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	sessionID, err := h.storeSession(r, *data)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	setSessionCookie(w, h.config.Session, sessionID)
+
+	// The session cookie is SameSite=Strict and will not be sent when the provider redirects back,
+	// so the session id also travels in a lax cookie scoped to the OAuth routes.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthFlowCookie,
+		Value:    sessionID,
+		MaxAge:   int(oauthFlowTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     oauthPath,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Completes the sign in when the provider redirects back with an authorization code
+func (h *Handler) HandleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	r = h.withFlowSession(r)
+
+	clearOAuthFlowCookie(w)
+
+	data, flow, err := h.takeOAuthFlow(r)
+
+	if err != nil {
+		response.RenderError(w, r, oauthError(err))
+		return
+	}
+
+	query := r.URL.Query()
+
+	if providerErr := query.Get("error"); providerErr != "" {
+		response.RenderError(w, r, oauthError(fmt.Errorf("provider returned %s: %s", providerErr, query.Get("error_description"))))
+		return
+	}
+
+	if flow.Provider != r.PathValue("provider") || subtle.ConstantTimeCompare([]byte(flow.State), []byte(query.Get("state"))) != 1 {
+		response.RenderError(w, r, oauthError(errOAuthFlowInvalid))
+		return
+	}
+
+	provider, ok := h.provider(flow.Provider)
+
+	if !ok {
+		response.RenderError(w, r, oauthError(errOAuthFlowInvalid))
+		return
+	}
+
+	claims, err := provider.Authenticate(r.Context(), query.Get("code"), flow.Verifier, flow.Nonce)
+
+	if err != nil {
+		response.RenderError(w, r, oauthError(err))
+		return
+	}
+
+	params := OAuthParams{
+		OAuthProvider: provider.Name(),
+		OAuthID:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+	}
+
+	result, err := h.service.SignInOAuth(r.Context(), params)
+
+	if err != nil {
+		if errors.Is(err, ErrOAuthEmailMissing) || errors.Is(err, ErrOAuthAccountConflict) {
+			response.RenderError(w, r, errtypes.ConflictError(err))
+			return
+		}
+
+		if errors.Is(err, ErrRegistrationClosed) || errors.Is(err, ErrInvitationRequired) {
+			response.RenderError(w, r, errtypes.DeniedError(err))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if result.LinkRequired {
+		link := oauthLink{OAuthParams: params, ExpiresAt: time.Now().Add(oauthFlowTTL)}
+
+		if err := session.Set(&data, oauthLinkKey, link); err != nil {
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		sessionID, err := h.storeSession(r, data)
+
+		if err != nil {
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		setSessionCookie(w, h.config.Session, sessionID)
+		h.renderOAuthRedirect(w, oauthLinkPath)
+		return
+	}
+
+	redirectURL, err := h.completeSignIn(w, r, data, result.UserID, signInOAuth, false)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	h.renderOAuthRedirect(w, redirectURL)
+}
+
+type oauthLinkData struct {
+	response.PageData
+	Email    string
+	Provider string
+	Error    string
+}
+
+// Asks the owner of the account with the same email to confirm linking the identity
+func (h *Handler) HandleOAuthLinkForm(w http.ResponseWriter, r *http.Request) {
+	link, ok := h.pendingOAuthLink(r)
+
+	if !ok {
+		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+		return
+	}
+
+	h.renderOAuthLink(w, r, link, "")
+}
+
+// Links the identity to the account with the same email once the password of the account is confirmed
+func (h *Handler) HandleOAuthLink(w http.ResponseWriter, r *http.Request) {
+	link, ok := h.pendingOAuthLink(r)
+
+	if !ok {
+		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+		return
+	}
+
+	// Confirming the link is one more way to guess the password of the account
+	if err := h.lockout.check(r.Context(), link.Email); err != nil {
+		if !errors.Is(err, lockout.ErrLocked) {
+			renderLockoutError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusTooManyRequests)
+		h.renderOAuthLink(w, r, link, err.Error())
+		return
+	}
+
+	userID, err := h.service.LinkOAuth(r.Context(), link.OAuthParams, r.PostFormValue("password"))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserPassInvalid):
+			h.failSignIn(r, link.Email)
+			w.WriteHeader(http.StatusUnauthorized)
+			h.renderOAuthLink(w, r, link, "The password is incorrect.")
+		case errors.Is(err, ErrOAuthAccountConflict):
+			w.WriteHeader(http.StatusConflict)
+			h.renderOAuthLink(w, r, link, err.Error())
+		default:
+			response.RenderError(w, r, errtypes.ServerError(err))
+		}
+		return
+	}
+
+	data, _ := session.FromContext(r.Context())
+	session.Delete(data, oauthLinkKey)
+
+	redirectURL, err := h.completeSignIn(w, r, *data, userID, signInOAuth, false)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+}
+
+func (h *Handler) renderOAuthLink(w http.ResponseWriter, r *http.Request, link *oauthLink, errMsg string) {
+	label := link.OAuthProvider
+
+	if provider, ok := h.provider(link.OAuthProvider); ok {
+		label = provider.Label()
+	}
+
+	data := &oauthLinkData{
+		PageData: response.PageData{
+			Title:     "Link Account",
+			CSRFToken: middleware.CSRFToken(r.Context()),
+		},
+		Email:    link.Email,
+		Provider: label,
+		Error:    errMsg,
+	}
+
+	h.htmlTemplate.Render(w, "oauth-link.html", data)
+}
+
+// Redirects with a page instead of a redirect response, so that the browser treats the
+// navigation as same-site and sends the SameSite=Strict session cookie.
+func (h *Handler) renderOAuthRedirect(w http.ResponseWriter, url string) {
+	data := &struct {
+		response.PageData
+		RedirectURL string
+	}{
+		PageData:    response.PageData{Title: "Signing In"},
+		RedirectURL: url,
+	}
+
+	h.htmlTemplate.Render(w, "oauth-redirect.html", data)
+}
+
+// Returns the session data of the request without its pending authorization request.
+func (h *Handler) takeOAuthFlow(r *http.Request) (session.Data, *oauthFlow, error) {
+	sessionData, err := h.sessionManager.LoadSession(r)
+
+	if err != nil {
+		return session.Data{}, nil, errOAuthFlowInvalid
+	}
+
+	flow, ok, err := session.Get[oauthFlow](sessionData, oauthFlowKey)
+
+	if err != nil || !ok || time.Now().After(flow.ExpiresAt) {
+		return session.Data{}, nil, errOAuthFlowInvalid
+	}
+
+	// The authorization request can only be completed once
+	session.Delete(sessionData, oauthFlowKey)
+
+	return *sessionData, &flow, nil
+}
+
+func (h *Handler) pendingOAuthLink(r *http.Request) (*oauthLink, bool) {
+	data, ok := session.FromContext(r.Context())
+
+	if !ok {
+		return nil, false
+	}
+
+	link, ok, err := session.Get[oauthLink](data, oauthLinkKey)
+
+	if err != nil || !ok || time.Now().After(link.ExpiresAt) {
+		return nil, false
+	}
+
+	return &link, true
+}
+
+// Stores the session data under the session id of the request and returns the id to send to the client
+func (h *Handler) storeSession(r *http.Request, data session.Data) (string, error) {
+	sessionID, err := h.sessionManager.ExtractSessionID(r)
+
+	if err != nil {
+		return "", err
+	}
+
+	return h.sessionManager.StoreSession(r.Context(), sessionID, data)
+}
+
+// Returns a copy of the request carrying the session id of the OAuth flow cookie in the session cookie
+func (h *Handler) withFlowSession(r *http.Request) *http.Request {
+	flowCookie, err := r.Cookie(oauthFlowCookie)
+
+	if err != nil {
+		return r
+	}
+
+	clone := r.Clone(r.Context())
+	clone.Header.Del("Cookie")
+
+	for _, c := range r.Cookies() {
+		if c.Name != h.config.Session.SessionName && c.Name != oauthFlowCookie {
+			clone.AddCookie(c)
+		}
+	}
+
+	clone.AddCookie(&http.Cookie{Name: h.config.Session.SessionName, Value: flowCookie.Value})
+
+	return clone
+}
+
+func clearOAuthFlowCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthFlowCookie,
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Path:     oauthPath,
+	})
+}
+
+func oauthError(err error) *errtypes.HTTPError {
+	slog.Debug("oauth sign in failed", "error", err)
+
+	// The details stay in the log, the client only learns that the flow failed
+	return errtypes.InvalidRequestError(errOAuthFlowInvalid)
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrOAuthEmailMissing    = errors.New("the sign-in provider did not share an email address")
+	ErrOAuthAccountConflict = errors.New("this account is already linked to another sign-in provider")
+)
+
+// OAuthSignInResult is the outcome of signing in with an OAuth identity.
+//
+// LinkRequired is set when an account with the same email exists. The identity is only linked
+// to it after the owner of the account confirms with their password.
+type OAuthSignInResult struct {
+	UserID       string
+	LinkRequired bool
+}
+
+// Signs in the user with the OAuth identity, signing them up when neither the identity nor the email is known
+// and sign up is open
+func (s *service) SignInOAuth(ctx context.Context, params OAuthParams) (*OAuthSignInResult, error) {
+	userID, err := s.repo.FindByOAuth(ctx, params.OAuthProvider, params.OAuthID)
+
+	if err == nil {
+		return &OAuthSignInResult{UserID: userID}, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("find oauth identity: %w", err)
+	}
+
+	if params.Email == "" {
+		return nil, ErrOAuthEmailMissing
+	}
+
+	existing, err := s.repo.SignIn(ctx, params.Email)
+
+	if err == nil {
+		// Without a password there is no way for the owner to confirm the link
+		if existing.Hash == "" {
+			return nil, ErrOAuthAccountConflict
+		}
+
+		return &OAuthSignInResult{LinkRequired: true}, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("find user: %w", err)
+	}
+
+	// Invitations are accepted on the sign up form, the identity can be linked afterwards
+	if err := s.checkRegistration(""); err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.SignUpOAuth(ctx, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actionSignUp, u.ID, "", map[string]any{"email": u.Email, "provider": params.OAuthProvider})
+
+	return &OAuthSignInResult{UserID: u.ID}, nil
+}
+
+// Links the OAuth identity to the account with the same email after checking the password of the account.
+// Returns the id of the user.
+func (s *service) LinkOAuth(ctx context.Context, params OAuthParams, password string) (string, error) {
+	existing, err := s.repo.SignIn(ctx, params.Email)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserPassInvalid
+		}
+
+		return "", fmt.Errorf("find user: %w", err)
+	}
+
+	if existing.Hash == "" {
+		return "", ErrOAuthAccountConflict
+	}
+
+	match, err := s.verifyPassword(ctx, existing.ID, password, existing.Hash)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !match {
+		return "", ErrUserPassInvalid
+	}
+
+	if err := s.repo.LinkOAuth(ctx, existing.ID, params); err != nil {
+		return "", err
+	}
+
+	s.record(ctx, actionOAuthLinked, existing.ID, "", map[string]any{"provider": params.OAuthProvider})
+
+	return existing.ID, nil
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/webauthn"
+	"github.com/ferdiebergado/gopherkit/http/request"
+)
+
+const (
+	passkeyRegistrationKey = "webauthn_registration"
+	passkeyLoginKey        = "webauthn_login"
+)
+
+var errCeremonyInvalid = errors.New("the passkey request is invalid or has expired, please try again")
+
+type PasskeyRegistrationParams struct {
+	Name       string                        `json:"name"`
+	Credential webauthn.RegistrationResponse `json:"credential"`
+}
+
+// Sends the options to create a passkey for the signed in user
+func (h *Handler) HandlePasskeyRegistrationBegin(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	options, ceremony, err := h.service.BeginPasskeyRegistration(r.Context(), userID)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if err := h.saveCeremony(w, r, passkeyRegistrationKey, ceremony); err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	res := &response.APIResponse[webauthn.CreationOptions]{
+		Message: "Create a passkey.",
+		Data:    options,
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Saves the passkey created by the authenticator of the signed in user
+func (h *Handler) HandlePasskeyRegistrationFinish(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	params, err := request.JSON[PasskeyRegistrationParams](r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	_, ceremony, err := h.takeCeremony(w, r, passkeyRegistrationKey)
+
+	if err != nil {
+		renderCeremonyError(w, r, err)
+		return
+	}
+
+	if err := h.service.FinishPasskeyRegistration(r.Context(), userID, ceremony, &params.Credential, params.Name); err != nil {
+		if errors.Is(err, ErrPasskeyInvalid) || errors.Is(err, ErrPasskeyExists) {
+			response.RenderError(w, r, errtypes.BadRequest(err))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	res := &response.APIResponse[map[string]string]{
+		Message: "Passkey added.",
+		Data: &map[string]string{
+			"redirectUrl": profilePath,
+		},
+	}
+
+	response.RenderJSON(w, http.StatusCreated, res)
+}
+
+// Sends the options to sign in with a passkey
+func (h *Handler) HandlePasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	options, ceremony, err := h.service.BeginPasskeyLogin()
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if err := h.saveCeremony(w, r, passkeyLoginKey, ceremony); err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	res := &response.APIResponse[webauthn.RequestOptions]{
+		Message: "Sign in with a passkey.",
+		Data:    options,
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Signs in the owner of the passkey that made the assertion.
+//
+// A passkey that verified the user already counts as two factors, otherwise the user is asked
+// for their second factor like after signing in with a password.
+func (h *Handler) HandlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	params, err := request.JSON[webauthn.AssertionResponse](r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	data, ceremony, err := h.takeCeremony(w, r, passkeyLoginKey)
+
+	if err != nil {
+		renderCeremonyError(w, r, err)
+		return
+	}
+
+	result, err := h.service.FinishPasskeyLogin(r.Context(), ceremony, &params)
+
+	if err != nil {
+		if errors.Is(err, ErrPasskeyInvalid) {
+			h.record(r, actionSignInFailed, "", "", map[string]any{"reason": "invalid_passkey"})
+			response.RenderError(w, r, errtypes.AuthenticationError(ErrPasskeyInvalid))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	var redirectURL string
+
+	if result.UserVerified {
+		redirectURL, err = h.startSessionWithData(w, r, data, result.UserID, false)
+
+		if err == nil {
+			h.record(r, actionSignIn, result.UserID, "", map[string]any{"method": signInPasskey, "user_verified": true})
+		}
+	} else {
+		redirectURL, err = h.completeSignIn(w, r, data, result.UserID, signInPasskey, false)
+	}
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	res := &response.APIResponse[map[string]string]{
+		Message: "Logged in.",
+		Data: &map[string]string{
+			"redirectUrl": redirectURL,
+		},
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Removes a passkey of the signed in user
+func (h *Handler) HandleDeletePasskey(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	if err := h.service.DeletePasskey(r.Context(), userID, r.PathValue("id")); err != nil {
+		if errors.Is(err, ErrPasskeyNotFound) {
+			response.RenderError(w, r, errtypes.NotFoundError(err))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if r.Header.Get("content-type") != "application/json" {
+		http.Redirect(w, r, profilePath+"?status=passkey-removed", http.StatusSeeOther)
+		return
+	}
+
+	response.RenderJSON(w, http.StatusOK, &response.APIResponse[any]{Message: "Passkey removed."})
+}
+
+// Keeps the ceremony in the session of the request until it is finished
+func (h *Handler) saveCeremony(w http.ResponseWriter, r *http.Request, key string, ceremony *webauthn.Ceremony) error {
+	data := &session.Data{}
+
+	if sessionData, err := h.sessionManager.LoadSession(r); err == nil {
+		data = sessionData
+	}
+
+	if err := session.Set(data, key, ceremony); err != nil {
+		return err
+	}
+
+	sessionID, err := h.storeSession(r, *data)
+
+	if err != nil {
+		return err
+	}
+
+	setSessionCookie(w, h.config.Session, sessionID)
+
+	return nil
+}
+
+// Removes the ceremony from the session, so that each challenge is only answered once.
+// Returns the remaining session data with the ceremony.
+func (h *Handler) takeCeremony(w http.ResponseWriter, r *http.Request, key string) (session.Data, *webauthn.Ceremony, error) {
+	data, err := h.sessionManager.LoadSession(r)
+
+	if err != nil {
+		return session.Data{}, nil, errCeremonyInvalid
+	}
+
+	ceremony, ok, err := session.Get[webauthn.Ceremony](data, key)
+
+	if err != nil || !ok {
+		return session.Data{}, nil, errCeremonyInvalid
+	}
+
+	session.Delete(data, key)
+
+	sessionID, err := h.storeSession(r, *data)
+
+	if err != nil {
+		return session.Data{}, nil, err
+	}
+
+	setSessionCookie(w, h.config.Session, sessionID)
+
+	if time.Now().After(ceremony.ExpiresAt) {
+		return session.Data{}, nil, errCeremonyInvalid
+	}
+
+	return *data, &ceremony, nil
+}
+
+func renderCeremonyError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errCeremonyInvalid) {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	response.RenderError(w, r, errtypes.ServerError(err))
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/webauthn"
+)
+
+var ErrPasskeyExists = errors.New("the passkey is already registered")
+
+type PasskeyStore interface {
+	SavePasskey(ctx context.Context, userID, name string, cred *webauthn.Credential) error
+	FindPasskey(ctx context.Context, credentialID []byte) (*Passkey, error)
+	ListPasskeys(ctx context.Context, userID string) ([]Passkey, error)
+	UpdatePasskeySignCount(ctx context.Context, id string, oldCount, newCount uint32) (bool, error)
+	DeletePasskey(ctx context.Context, userID, id string) (bool, error)
+}
+
+// Passkey is a WebAuthn credential registered by a user.
+type Passkey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Credential webauthn.Credential
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+const savePasskeyQuery = `
+INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, aaguid, transports, name)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+func (r *repo) SavePasskey(ctx context.Context, userID, name string, cred *webauthn.Credential) error {
+	_, err := r.db.ExecContext(ctx, savePasskeyQuery, userID, cred.ID, cred.PublicKey, int64(cred.SignCount), cred.AAGUID, strings.Join(cred.Transports, ","), name)
+
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return ErrPasskeyExists
+		}
+
+		return fmt.Errorf("save passkey: %w", err)
+	}
+
+	return nil
+}
+
+const passkeyColumns = "id, user_id, name, credential_id, public_key, sign_count, aaguid, transports, last_used_at, created_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPasskey(row rowScanner) (*Passkey, error) {
+	var (
+		p          Passkey
+		signCount  int64
+		transports string
+	)
+
+	if err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.Credential.ID, &p.Credential.PublicKey, &signCount,
+		&p.Credential.AAGUID, &transports, &p.LastUsedAt, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	p.Credential.SignCount = uint32(signCount)
+
+	if transports != "" {
+		p.Credential.Transports = strings.Split(transports, ",")
+	}
+
+	return &p, nil
+}
+
+// Finds the passkey with the given credential id, only when its user is not deleted
+func (r *repo) FindPasskey(ctx context.Context, credentialID []byte) (*Passkey, error) {
+	const q = "SELECT " + passkeyColumns + " FROM webauthn_credentials WHERE credential_id = $1 " +
+		"AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)"
+
+	return scanPasskey(r.db.QueryRowContext(ctx, q, credentialID))
+}
+
+func (r *repo) ListPasskeys(ctx context.Context, userID string) ([]Passkey, error) {
+	const q = "SELECT " + passkeyColumns + " FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at"
+
+	rows, err := r.db.QueryContext(ctx, q, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("list passkeys: %w", err)
+	}
+
+	defer rows.Close()
+
+	var passkeys []Passkey
+
+	for rows.Next() {
+		p, err := scanPasskey(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("scan passkey: %w", err)
+		}
+
+		passkeys = append(passkeys, *p)
+	}
+
+	return passkeys, rows.Err()
+}
+
+// Stores the sign count of an assertion. Reports false when the count changed since it was read,
+// so that two concurrent assertions cannot both succeed with the same counter.
+func (r *repo) UpdatePasskeySignCount(ctx context.Context, id string, oldCount, newCount uint32) (bool, error) {
+	const q = "UPDATE webauthn_credentials SET sign_count = $3, last_used_at = NOW() WHERE id = $1 AND sign_count = $2"
+
+	res, err := r.db.ExecContext(ctx, q, id, int64(oldCount), int64(newCount))
+
+	if err != nil {
+		return false, fmt.Errorf("update sign count: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("update sign count: %w", err)
+	}
+
+	return n == 1, nil
+}
+
+// Deletes a passkey of the user. Reports false when the user has no such passkey.
+func (r *repo) DeletePasskey(ctx context.Context, userID, id string) (bool, error) {
+	const q = "DELETE FROM webauthn_credentials WHERE id::text = $1 AND user_id = $2"
+
+	res, err := r.db.ExecContext(ctx, q, id, userID)
+
+	if err != nil {
+		return false, fmt.Errorf("delete passkey: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("delete passkey: %w", err)
+	}
+
+	return n == 1, nil
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/webauthn"
+)
+
+var (
+	ErrPasskeyNotFound = errors.New("passkey not found")
+	ErrPasskeyInvalid  = errors.New("the passkey could not be verified")
+)
+
+// Maximum length of the name of a passkey
+const passkeyNameLength = 100
+
+// PasskeyLoginResult is the outcome of signing in with a passkey.
+//
+// UserVerified is set when the authenticator verified the user with a PIN or biometrics,
+// which counts as a second factor.
+type PasskeyLoginResult struct {
+	UserID       string
+	UserVerified bool
+}
+
+// Starts registering a passkey for the user. The ceremony must be kept for FinishPasskeyRegistration.
+func (s *service) BeginPasskeyRegistration(ctx context.Context, userID string) (*webauthn.CreationOptions, *webauthn.Ceremony, error) {
+	status, err := s.repo.FindEmail(ctx, userID)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("find email: %w", err)
+	}
+
+	passkeys, err := s.repo.ListPasskeys(ctx, userID)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u := webauthn.User{
+		ID:          userID,
+		Name:        status.Email,
+		DisplayName: status.Email,
+	}
+
+	// Keeps the authenticator from registering a second passkey for the same account
+	for _, p := range passkeys {
+		u.Credentials = append(u.Credentials, p.Credential.ID)
+	}
+
+	return s.webauthn.BeginRegistration(u)
+}
+
+// Verifies the new passkey and saves it for the user who started the ceremony.
+func (s *service) FinishPasskeyRegistration(ctx context.Context, userID string, ceremony *webauthn.Ceremony, res *webauthn.RegistrationResponse, name string) error {
+	if ceremony.UserID != userID {
+		return ErrPasskeyInvalid
+	}
+
+	cred, err := s.webauthn.FinishRegistration(ceremony, res)
+
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		name = "Passkey"
+	}
+
+	if len(name) > passkeyNameLength {
+		name = name[:passkeyNameLength]
+	}
+
+	if err := s.repo.SavePasskey(ctx, userID, name, cred); err != nil {
+		return err
+	}
+
+	s.record(ctx, actionPasskeyAdded, userID, "", map[string]any{"name": name})
+
+	return nil
+}
+
+// Starts signing in with any passkey the browser has for the site.
+func (s *service) BeginPasskeyLogin() (*webauthn.RequestOptions, *webauthn.Ceremony, error) {
+	return s.webauthn.BeginLogin(nil)
+}
+
+// Verifies the assertion against the stored passkey and records its new sign count.
+func (s *service) FinishPasskeyLogin(ctx context.Context, ceremony *webauthn.Ceremony, res *webauthn.AssertionResponse) (*PasskeyLoginResult, error) {
+	passkey, err := s.repo.FindPasskey(ctx, res.RawID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPasskeyInvalid
+		}
+
+		return nil, fmt.Errorf("find passkey: %w", err)
+	}
+
+	// The user handle is the id of the user the passkey was created for
+	if len(res.Response.UserHandle) != 0 && !bytes.Equal(res.Response.UserHandle, []byte(passkey.UserID)) {
+		return nil, ErrPasskeyInvalid
+	}
+
+	result, err := s.webauthn.FinishLogin(ceremony, res, &passkey.Credential)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyInvalid, err)
+	}
+
+	updated, err := s.repo.UpdatePasskeySignCount(ctx, passkey.ID, passkey.Credential.SignCount, result.SignCount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return nil, ErrPasskeyInvalid
+	}
+
+	return &PasskeyLoginResult{
+		UserID:       passkey.UserID,
+		UserVerified: result.UserVerified,
+	}, nil
+}
+
+// Lists the passkeys of the user, oldest first.
+func (s *service) Passkeys(ctx context.Context, userID string) ([]Passkey, error) {
+	return s.repo.ListPasskeys(ctx, userID)
+}
+
+func (s *service) DeletePasskey(ctx context.Context, userID, id string) error {
+	deleted, err := s.repo.DeletePasskey(ctx, userID, id)
+
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrPasskeyNotFound
+	}
+
+	s.record(ctx, actionPasskeyRemoved, userID, "", map[string]any{"passkey_id": id})
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+// Session value holding the family of the remember me token that the session was opened with,
+// so that revoking the session revokes the family as well
+const rememberFamilyKey = "remember_family"
+
+// Creates a new remember me token for the user, replacing the family of the one the browser had.
+// Returns nil when it could not be created, which is only logged, since the user is signed in either way.
+// The caller links the session to the family of the token and then sends its value with setRememberCookie.
+func (h *Handler) rememberUser(r *http.Request, userID string) (*RememberToken, string) {
+	if cookie, err := r.Cookie(h.config.Session.RememberName); err == nil {
+		if err := h.service.RevokeRememberToken(r.Context(), cookie.Value); err != nil {
+			slog.Error("failed to revoke the previous remember me token", "user_id", userID, "error", err)
+		}
+	}
+
+	t, token, err := h.service.CreateRememberToken(r.Context(), userID)
+
+	if err != nil {
+		slog.Error("failed to create the remember me token", "user_id", userID, "error", err)
+		return nil, ""
+	}
+
+	return t, token
+}
+
+// Revokes the family of the remember me token that opened the session with the data, if any
+func (h *Handler) forgetSession(r *http.Request, data *session.Data) {
+	familyID, ok, err := session.Get[string](data, rememberFamilyKey)
+
+	if err != nil {
+		slog.Error("failed to read the remember me token family of the session", "error", err)
+		return
+	}
+
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeRememberTokenFamily(r.Context(), familyID); err != nil {
+		slog.Error("failed to revoke the remember me token family of the session", "family_id", familyID, "error", err)
+	}
+}
+
+// Revokes the remember me token of the browser, so that it is not signed back in, and expires its cookie
+func (h *Handler) forgetBrowser(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(h.config.Session.RememberName)
+
+	if err != nil {
+		return
+	}
+
+	if err := h.service.RevokeRememberToken(r.Context(), cookie.Value); err != nil {
+		slog.Error("failed to revoke the remember me token", "error", err)
+	}
+
+	clearCookie(w, h.config.Session.RememberName, true)
+}
+
+// Revokes the remember me tokens of the user, so that their revoked sessions are not opened again.
+// The token of the browser is kept when keepCurrent is set.
+func (h *Handler) forgetUser(w http.ResponseWriter, r *http.Request, userID string, keepCurrent bool) {
+	var current string
+
+	if cookie, err := r.Cookie(h.config.Session.RememberName); err == nil {
+		current = cookie.Value
+	}
+
+	var except string
+
+	if keepCurrent {
+		except = current
+	}
+
+	if err := h.service.RevokeRememberTokens(r.Context(), userID, except); err != nil {
+		slog.Error("failed to revoke remember me tokens", "user_id", userID, "error", err)
+	}
+
+	if !keepCurrent && current != "" {
+		clearCookie(w, h.config.Session.RememberName, true)
+	}
+}
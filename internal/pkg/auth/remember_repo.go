@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type RememberTokenStore interface {
+	SaveRememberToken(ctx context.Context, token *RememberToken) error
+	FindRememberToken(ctx context.Context, selector string) (*RememberToken, error)
+	RotateRememberToken(ctx context.Context, selector string, next *RememberToken) (bool, error)
+	DeleteRememberTokenFamily(ctx context.Context, familyID string) error
+	DeleteRememberTokens(ctx context.Context, userID, exceptFamilyID string) error
+	PurgeExpiredRememberTokens(ctx context.Context) (int64, error)
+}
+
+// RememberToken keeps a browser signed in after its session expired.
+// Only the hash of the validator is stored.
+type RememberToken struct {
+	Selector        string
+	ValidatorHash   string
+	FamilyID        string // Shared by the tokens that replaced each other since the user signed in
+	UserID          string
+	ExpiresAt       time.Time
+	FamilyExpiresAt time.Time  // No token of the family is valid after it, however often they are replaced
+	RotatedAt       *time.Time // Set once the token was replaced by another one
+	CreatedAt       time.Time
+}
+
+const saveRememberTokenQuery = `
+INSERT INTO remember_tokens (selector, validator_hash, user_id, expires_at, family_expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING family_id, created_at
+`
+
+// Saves the first token of a new family
+func (r *repo) SaveRememberToken(ctx context.Context, token *RememberToken) error {
+	row := r.db.QueryRowContext(ctx, saveRememberTokenQuery, token.Selector, token.ValidatorHash, token.UserID, token.ExpiresAt,
+		token.FamilyExpiresAt)
+
+	if err := row.Scan(&token.FamilyID, &token.CreatedAt); err != nil {
+		return fmt.Errorf("save remember token: %w", err)
+	}
+
+	return nil
+}
+
+// Finds the token with the given selector, only when its user is not deleted
+func (r *repo) FindRememberToken(ctx context.Context, selector string) (*RememberToken, error) {
+	const q = "SELECT selector, validator_hash, family_id, user_id, expires_at, family_expires_at, rotated_at, created_at " +
+		"FROM remember_tokens WHERE selector = $1 AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)"
+
+	var t RememberToken
+
+	if err := r.db.QueryRowContext(ctx, q, selector).Scan(&t.Selector, &t.ValidatorHash, &t.FamilyID, &t.UserID,
+		&t.ExpiresAt, &t.FamilyExpiresAt, &t.RotatedAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+const saveRotatedTokenQuery = `
+INSERT INTO remember_tokens (selector, validator_hash, family_id, user_id, expires_at, family_expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING created_at
+`
+
+// Marks the token as rotated and saves the next token of its family in its place.
+// Reports false when the token was already rotated, e.g. by a concurrent request.
+func (r *repo) RotateRememberToken(ctx context.Context, selector string, next *RememberToken) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return false, fmt.Errorf("begin rotate remember token: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const rotateQuery = "UPDATE remember_tokens SET rotated_at = NOW() WHERE selector = $1 AND rotated_at IS NULL"
+
+	res, err := tx.ExecContext(ctx, rotateQuery, selector)
+
+	if err != nil {
+		return false, fmt.Errorf("rotate remember token: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("rotate remember token: %w", err)
+	}
+
+	if n != 1 {
+		return false, nil
+	}
+
+	row := tx.QueryRowContext(ctx, saveRotatedTokenQuery, next.Selector, next.ValidatorHash, next.FamilyID, next.UserID, next.ExpiresAt,
+		next.FamilyExpiresAt)
+
+	if err := row.Scan(&next.CreatedAt); err != nil {
+		return false, fmt.Errorf("save rotated remember token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit rotate remember token: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *repo) DeleteRememberTokenFamily(ctx context.Context, familyID string) error {
+	const q = "DELETE FROM remember_tokens WHERE family_id = $1"
+
+	if _, err := r.db.ExecContext(ctx, q, familyID); err != nil {
+		return fmt.Errorf("delete remember token family: %w", err)
+	}
+
+	return nil
+}
+
+// Deletes the tokens of the user except the ones of the given family. Pass an empty family to delete all of them.
+func (r *repo) DeleteRememberTokens(ctx context.Context, userID, exceptFamilyID string) error {
+	const q = "DELETE FROM remember_tokens WHERE user_id = $1 AND family_id IS DISTINCT FROM $2"
+
+	var except any
+
+	if exceptFamilyID != "" {
+		except = exceptFamilyID
+	}
+
+	if _, err := r.db.ExecContext(ctx, q, userID, except); err != nil {
+		return fmt.Errorf("delete remember tokens: %w", err)
+	}
+
+	return nil
+}
+
+// Deletes the expired tokens, including the rotated ones that were kept to detect their reuse
+func (r *repo) PurgeExpiredRememberTokens(ctx context.Context) (int64, error) {
+	const q = "DELETE FROM remember_tokens WHERE expires_at < NOW()"
+
+	res, err := r.db.ExecContext(ctx, q)
+
+	if err != nil {
+		return 0, fmt.Errorf("purge remember tokens: %w", err)
+	}
+
+	return res.RowsAffected()
+}
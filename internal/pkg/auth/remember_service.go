@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+var (
+	ErrRememberTokenInvalid = errors.New("invalid or expired remember me token")
+	ErrRememberTokenReused  = errors.New("the remember me token was already used")
+	ErrRememberTokenRotated = errors.New("the remember me token was just replaced")
+)
+
+const (
+	// Separates the selector from the validator in the token sent to the browser
+	rememberTokenSeparator = "."
+
+	rememberSelectorLength  = 12
+	rememberValidatorLength = 32
+
+	// How long a replaced token is ignored instead of being treated as stolen, so that the
+	// requests that the browser sent with it before it got the next token do not sign the user out
+	rememberTokenGrace = 30 * time.Second
+)
+
+// Creates the first token of a new family that keeps the browser of the user signed in.
+// Returns the saved token and the value to send to the browser.
+func (s *service) CreateRememberToken(ctx context.Context, userID string) (*RememberToken, string, error) {
+	t, token, err := s.newRememberToken(userID, time.Now().Add(s.cfg.Session.RememberMaxAge))
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.repo.SaveRememberToken(ctx, t); err != nil {
+		return nil, "", err
+	}
+
+	return t, token, nil
+}
+
+// Replaces the token with the next one of its family. Returns the next token, whose user is the one
+// that it signs in, and the value to send to the browser.
+//
+// A token that was already replaced means that it was copied, since the browser only keeps the latest one.
+// Its whole family is revoked, and it is returned with ErrRememberTokenReused,
+// so that the sessions of its user can be revoked as well.
+// Within rememberTokenGrace of its replacement, ErrRememberTokenRotated is returned instead,
+// since the browser already has, or is about to get, the next token.
+func (s *service) RotateRememberToken(ctx context.Context, token string) (*RememberToken, string, error) {
+	t, err := s.findRememberToken(ctx, token)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if t.RotatedAt != nil {
+		if time.Since(*t.RotatedAt) < rememberTokenGrace {
+			return nil, "", ErrRememberTokenRotated
+		}
+
+		if err := s.repo.DeleteRememberTokenFamily(ctx, t.FamilyID); err != nil {
+			return nil, "", err
+		}
+
+		s.record(ctx, actionRememberTokenReuse, "", t.UserID, map[string]any{"family_id": t.FamilyID})
+
+		return t, "", ErrRememberTokenReused
+	}
+
+	next, nextToken, err := s.newRememberToken(t.UserID, t.FamilyExpiresAt)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	next.FamilyID = t.FamilyID
+
+	rotated, err := s.repo.RotateRememberToken(ctx, t.Selector, next)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Another request replaced it first
+	if !rotated {
+		return nil, "", ErrRememberTokenRotated
+	}
+
+	s.record(ctx, actionSignIn, t.UserID, "", map[string]any{"method": signInRememberMe})
+
+	return next, nextToken, nil
+}
+
+// Revokes the family of the token, e.g. when the user signs out. Invalid tokens are ignored.
+func (s *service) RevokeRememberToken(ctx context.Context, token string) error {
+	t, err := s.findRememberToken(ctx, token)
+
+	if err != nil {
+		if errors.Is(err, ErrRememberTokenInvalid) {
+			return nil
+		}
+
+		return err
+	}
+
+	return s.repo.DeleteRememberTokenFamily(ctx, t.FamilyID)
+}
+
+// Revokes a family of tokens, e.g. the one that opened a session that is revoked
+func (s *service) RevokeRememberTokenFamily(ctx context.Context, familyID string) error {
+	if !validation.IsUUID(familyID) {
+		return nil
+	}
+
+	return s.repo.DeleteRememberTokenFamily(ctx, familyID)
+}
+
+// Revokes the tokens of the user except the family of the given token. Pass an empty token to revoke all of them.
+func (s *service) RevokeRememberTokens(ctx context.Context, userID, exceptToken string) error {
+	var exceptFamilyID string
+
+	if exceptToken != "" {
+		t, err := s.findRememberToken(ctx, exceptToken)
+
+		if err != nil && !errors.Is(err, ErrRememberTokenInvalid) {
+			return err
+		}
+
+		if err == nil && t.UserID == userID {
+			exceptFamilyID = t.FamilyID
+		}
+	}
+
+	return s.repo.DeleteRememberTokens(ctx, userID, exceptFamilyID)
+}
+
+// Finds the unexpired token whose validator matches, rotated or not
+func (s *service) findRememberToken(ctx context.Context, token string) (*RememberToken, error) {
+	selector, validator, found := strings.Cut(token, rememberTokenSeparator)
+
+	if !found || selector == "" || validator == "" {
+		return nil, ErrRememberTokenInvalid
+	}
+
+	t, err := s.repo.FindRememberToken(ctx, selector)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRememberTokenInvalid
+		}
+
+		return nil, fmt.Errorf("find remember token: %w", err)
+	}
+
+	// A wrong validator is only a guess of the selector, which must not revoke the family of the user
+	if subtle.ConstantTimeCompare([]byte(security.HashToken(validator)), []byte(t.ValidatorHash)) != 1 {
+		return nil, ErrRememberTokenInvalid
+	}
+
+	if !time.Now().Before(t.ExpiresAt) {
+		return nil, ErrRememberTokenInvalid
+	}
+
+	return t, nil
+}
+
+// Returns a token of the user to be saved and the value sent to the browser.
+// The token lasts RememberDuration, but not past the end of its family.
+func (s *service) newRememberToken(userID string, familyExpiresAt time.Time) (*RememberToken, string, error) {
+	selector, err := security.GenerateRandomBytes(rememberSelectorLength)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("generate remember token selector: %w", err)
+	}
+
+	validator, err := security.GenerateRandomBytes(rememberValidatorLength)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("generate remember token validator: %w", err)
+	}
+
+	encodedSelector := base64.RawURLEncoding.EncodeToString(selector)
+	encodedValidator := base64.RawURLEncoding.EncodeToString(validator)
+
+	expiresAt := time.Now().Add(s.cfg.Session.RememberDuration)
+
+	if familyExpiresAt.Before(expiresAt) {
+		expiresAt = familyExpiresAt
+	}
+
+	t := &RememberToken{
+		Selector:        encodedSelector,
+		ValidatorHash:   security.HashToken(encodedValidator),
+		UserID:          userID,
+		ExpiresAt:       expiresAt,
+		FamilyExpiresAt: familyExpiresAt,
+	}
+
+	return t, encodedSelector + rememberTokenSeparator + encodedValidator, nil
+}
@@ -0,0 +1,129 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateRememberToken(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("replaces the token within its family", func(t *testing.T) {
+		repo := newFakeRepo()
+		svc, _, auditLog := newTestService(testConfig(), repo)
+
+		first, token, err := svc.CreateRememberToken(ctx, testUserID)
+		if err != nil {
+			t.Fatalf("create remember token: %v", err)
+		}
+
+		next, nextToken, err := svc.RotateRememberToken(ctx, token)
+		if err != nil {
+			t.Fatalf("rotate remember token: %v", err)
+		}
+
+		if next.UserID != testUserID || next.FamilyID != first.FamilyID || nextToken == token {
+			t.Errorf("expected the next token of the family of %s, got %+v", testUserID, next)
+		}
+
+		if n := repo.liveRememberTokens(first.FamilyID); n != 1 {
+			t.Errorf("expected only the next token to be live, got %d", n)
+		}
+
+		if actions := auditLog.actions(); len(actions) != 1 || actions[0] != actionSignIn {
+			t.Errorf("expected the sign in to be recorded, got %v", actions)
+		}
+	})
+
+	t.Run("ignores the replaced token within the grace period", func(t *testing.T) {
+		repo := newFakeRepo()
+		svc, _, _ := newTestService(testConfig(), repo)
+
+		first, token, _ := svc.CreateRememberToken(ctx, testUserID)
+
+		if _, _, err := svc.RotateRememberToken(ctx, token); err != nil {
+			t.Fatalf("rotate remember token: %v", err)
+		}
+
+		if _, _, err := svc.RotateRememberToken(ctx, token); !errors.Is(err, ErrRememberTokenRotated) {
+			t.Errorf("expected ErrRememberTokenRotated, got %v", err)
+		}
+
+		if n := repo.liveRememberTokens(first.FamilyID); n != 1 {
+			t.Errorf("expected the family to be kept, got %d live tokens", n)
+		}
+	})
+
+	t.Run("revokes the family of a reused token", func(t *testing.T) {
+		repo := newFakeRepo()
+		svc, _, auditLog := newTestService(testConfig(), repo)
+
+		first, token, _ := svc.CreateRememberToken(ctx, testUserID)
+
+		if _, _, err := svc.RotateRememberToken(ctx, token); err != nil {
+			t.Fatalf("rotate remember token: %v", err)
+		}
+
+		selector, _, _ := strings.Cut(token, rememberTokenSeparator)
+		rotatedAt := time.Now().Add(-2 * rememberTokenGrace)
+		repo.rememberTokens[selector].RotatedAt = &rotatedAt
+
+		reused, _, err := svc.RotateRememberToken(ctx, token)
+
+		if !errors.Is(err, ErrRememberTokenReused) || reused.UserID != testUserID {
+			t.Fatalf("expected ErrRememberTokenReused with the user, got %v", err)
+		}
+
+		if len(repo.rememberTokens) != 0 {
+			t.Errorf("expected the family %s to be revoked, got %d tokens", first.FamilyID, len(repo.rememberTokens))
+		}
+
+		if actions := auditLog.actions(); actions[len(actions)-1] != actionRememberTokenReuse {
+			t.Errorf("expected the reuse to be recorded, got %v", actions)
+		}
+	})
+
+	t.Run("keeps the family of a wrong validator", func(t *testing.T) {
+		repo := newFakeRepo()
+		svc, _, _ := newTestService(testConfig(), repo)
+
+		first, token, _ := svc.CreateRememberToken(ctx, testUserID)
+		selector, _, _ := strings.Cut(token, rememberTokenSeparator)
+
+		if _, _, err := svc.RotateRememberToken(ctx, selector+rememberTokenSeparator+"guess"); !errors.Is(err, ErrRememberTokenInvalid) {
+			t.Errorf("expected ErrRememberTokenInvalid, got %v", err)
+		}
+
+		if n := repo.liveRememberTokens(first.FamilyID); n != 1 {
+			t.Errorf("expected the family to be kept, got %d live tokens", n)
+		}
+	})
+
+	t.Run("ends the family at its max age", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Session.RememberDuration = time.Hour
+		cfg.Session.RememberMaxAge = time.Minute
+
+		svc, _, _ := newTestService(cfg, newFakeRepo())
+
+		first, token, _ := svc.CreateRememberToken(ctx, testUserID)
+
+		if !first.ExpiresAt.Equal(first.FamilyExpiresAt) {
+			t.Errorf("expected the token to expire with its family at %v, got %v", first.FamilyExpiresAt, first.ExpiresAt)
+		}
+
+		next, _, err := svc.RotateRememberToken(ctx, token)
+		if err != nil {
+			t.Fatalf("rotate remember token: %v", err)
+		}
+
+		if !next.ExpiresAt.Equal(first.FamilyExpiresAt) || !next.FamilyExpiresAt.Equal(first.FamilyExpiresAt) {
+			t.Errorf("expected the rotation to keep the end of the family at %v, got %v", first.FamilyExpiresAt, next.ExpiresAt)
+		}
+	})
+}
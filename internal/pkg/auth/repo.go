@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
@@ -14,7 +15,22 @@ type repo struct {
 	db  *sql.DB
 }
 
-func NewAuthRepo(cfg *config.DBConfig, conn *sql.DB) Authenticator {
+// Repo is the persistence layer of the auth package.
+type Repo interface {
+	Authenticator
+	PasswordResetter
+	EmailVerifier
+	TwoFactorStore
+	PasskeyStore
+	AccessTokenStore
+	RoleStore
+	AccountStore
+	MagicLinkStore
+	InvitationStore
+	RememberTokenStore
+}
+
+func NewAuthRepo(cfg *config.DBConfig, conn *sql.DB) Repo {
 	return &repo{
 		cfg: cfg,
 		db:  conn,
@@ -22,8 +38,8 @@ func NewAuthRepo(cfg *config.DBConfig, conn *sql.DB) Authenticator {
 }
 
 const signUpQuery = `
-INSERT INTO users (email, password_hash, auth_method)
-VALUES ($1, $2, $3)
+INSERT INTO users (email, password_hash, auth_method, verification_sent_at)
+VALUES ($1, $2, $3, NOW())
 RETURNING id, email, auth_method, created_at, updated_at
 `
 
@@ -41,14 +57,89 @@ func (r *repo) SignUp(ctx context.Context, params SignUpParams) (*user.User, err
 	return &user, nil
 }
 
-func (r *repo) SignUpOAuth(ctx context.Context, provider string, id string) *sql.Row {
-	const q = "INSERT INTO users (oauth_provider, oauth_id, auth_method) VALUES ($1, $2, $3) RETURNING id, email, auth_method, created_at, updated_at"
-	return r.db.QueryRowContext(ctx, q, provider, id, user.OAuth)
+const signUpOAuthQuery = `
+INSERT INTO users (email, oauth_provider, oauth_id, auth_method, email_verified_at)
+VALUES ($1, $2, $3, $4, CASE WHEN $5::boolean THEN NOW() END)
+RETURNING id, email, email_verified_at, oauth_provider, oauth_id, auth_method, created_at, updated_at
+`
+
+// Signs up a user authenticated by an OAuth provider
+func (r *repo) SignUpOAuth(ctx context.Context, params OAuthParams) (*user.User, error) {
+	row := r.db.QueryRowContext(ctx, signUpOAuthQuery, params.Email, params.OAuthProvider, params.OAuthID, user.OAuth, params.EmailVerified)
+
+	var u user.User
+	if err := row.Scan(&u.ID, &u.Email, &u.EmailVerifiedAt, &u.OAuthProvider, &u.OAuthID, &u.AuthMethod, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, &EmailExistsError{Email: params.Email}
+		}
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// Finds the id of the user with the given OAuth identity
+func (r *repo) FindByOAuth(ctx context.Context, provider, id string) (string, error) {
+	const q = "SELECT id FROM users WHERE oauth_provider = $1 AND oauth_id = $2 AND deleted_at IS NULL"
+
+	var userID string
+	if err := r.db.QueryRowContext(ctx, q, provider, id).Scan(&userID); err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+const linkOAuthQuery = `
+UPDATE users SET oauth_provider = $2, oauth_id = $3,
+email_verified_at = CASE WHEN $4::boolean THEN COALESCE(email_verified_at, NOW()) ELSE email_verified_at END
+WHERE id = $1 AND oauth_provider IS NULL
+`
+
+// Links an OAuth identity to a user who is not linked to a provider yet
+func (r *repo) LinkOAuth(ctx context.Context, userID string, params OAuthParams) error {
+	res, err := r.db.ExecContext(ctx, linkOAuthQuery, userID, params.OAuthProvider, params.OAuthID, params.EmailVerified)
+
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return ErrOAuthAccountConflict
+		}
+		return fmt.Errorf("link oauth identity: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return fmt.Errorf("link oauth identity: %w", err)
+	}
+
+	if n == 0 {
+		return ErrOAuthAccountConflict
+	}
+
+	return nil
+}
+
+const findUserQuery = `
+SELECT id, email, email_verified_at, oauth_provider, oauth_id, auth_method, created_at, updated_at
+FROM users
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (r *repo) FindUser(ctx context.Context, userID string) (*user.User, error) {
+	row := r.db.QueryRowContext(ctx, findUserQuery, userID)
+
+	var u user.User
+	if err := row.Scan(&u.ID, &u.Email, &u.EmailVerifiedAt, &u.OAuthProvider, &u.OAuthID, &u.AuthMethod, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return &u, nil
 }
 
 const singInQuery = `
-SELECT id, password_hash FROM users
-WHERE email = $1
+SELECT id, COALESCE(password_hash, '') FROM users
+WHERE email = $1 AND deleted_at IS NULL
 `
 
 func (r *repo) SignIn(ctx context.Context, email string) (*SignInResult, error) {
@@ -61,3 +152,14 @@ func (r *repo) SignIn(ctx context.Context, email string) (*SignInResult, error)
 
 	return &result, nil
 }
+
+// Replaces the password hash of the user, only when it was not changed since it was read.
+func (r *repo) UpdatePasswordHash(ctx context.Context, userID, oldHash, newHash string) error {
+	const q = "UPDATE users SET password_hash = $3 WHERE id = $1 AND password_hash = $2"
+
+	if _, err := r.db.ExecContext(ctx, q, userID, oldHash, newHash); err != nil {
+		return fmt.Errorf("update password hash: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+	"github.com/ferdiebergado/gopherkit/http/request"
+)
+
+type resetPasswordData struct {
+	response.PageData
+	Token string
+}
+
+func (h *Handler) HandleForgotPassword(w http.ResponseWriter, _ *http.Request) {
+	h.htmlTemplate.Render(w, "forgot-password.html", nil)
+}
+
+func (h *Handler) HandleForgotPasswordForm(w http.ResponseWriter, r *http.Request) {
+	params, err := request.JSON[ForgotPasswordParams](r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(r.Context(), params); err != nil {
+		var inputErr *validation.Error
+		if errors.As(err, &inputErr) {
+			response.RenderError(w, r, errtypes.ValidationError(*inputErr))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	res := &response.APIResponse[any]{
+		Message: "If an account exists for that email, a password reset link has been sent to it.",
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+func (h *Handler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	data := &resetPasswordData{
+		PageData: response.PageData{Title: "Reset Password"},
+		Token:    r.URL.Query().Get("token"),
+	}
+
+	h.htmlTemplate.Render(w, "reset-password.html", data)
+}
+
+func (h *Handler) HandleResetPasswordForm(w http.ResponseWriter, r *http.Request) {
+	params, err := request.JSON[ResetPasswordParams](r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.BadRequest(err))
+		return
+	}
+
+	userID, err := h.service.ResetPassword(r.Context(), params)
+
+	if err != nil {
+		var inputErr *validation.Error
+		if errors.As(err, &inputErr) {
+			response.RenderError(w, r, errtypes.ValidationError(*inputErr))
+			return
+		}
+
+		if errors.Is(err, ErrInvalidResetToken) {
+			response.RenderError(w, r, errtypes.InvalidRequestError(err))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	// The password may have been reset because the account was compromised
+	if _, err := revokeUserSessions(r.Context(), h.sessionManager, userID, ""); err != nil {
+		slog.Error("failed to revoke sessions after password reset", "user_id", userID, "error", err)
+	}
+
+	h.forgetUser(w, r, userID, false)
+
+	res := &response.APIResponse[map[string]string]{
+		Message: "Your password has been reset. You can now sign in with your new password.",
+		Data: &map[string]string{
+			"redirectUrl": redirectPath,
+		},
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,112 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+func TestHandleForgotPasswordForm(t *testing.T) {
+	cfg := testConfig()
+	svc, mailer, _ := newTestService(cfg, newFakeRepo())
+	h, _ := newTestHandler(cfg, svc, session.NewMemorySession(cfg.Session))
+
+	forgot := func(email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/forgot-password", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		h.HandleForgotPasswordForm(rec, req)
+
+		return rec
+	}
+
+	known, unknown := forgot(testEmail), forgot("nobody@example.com")
+
+	if known.Code != http.StatusOK || unknown.Code != known.Code || unknown.Body.String() != known.Body.String() {
+		t.Errorf("expected the same response for any email, got %d %q and %d %q",
+			known.Code, known.Body.String(), unknown.Code, unknown.Body.String())
+	}
+
+	if err := svc.Shutdown(context.Background()); err != nil {
+		t.Fatalf("wait for the link: %v", err)
+	}
+
+	if sent := mailer.messages(); len(sent) != 1 || sent[0].To != testEmail {
+		t.Errorf("expected a single link sent to %s, got %+v", testEmail, sent)
+	}
+}
+
+func TestHandleResetPasswordForm(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	repo := newFakeRepo()
+	svc, mailer, _ := newTestService(cfg, repo)
+	sessMgr := session.NewMemorySession(cfg.Session)
+	h, _ := newTestHandler(cfg, svc, sessMgr)
+	setTestPassword(t, svc, repo)
+
+	for _, sessionID := range []string{"laptop", "phone"} {
+		if _, err := sessMgr.StoreSession(ctx, sessionID, session.Data{UserID: testUserID}); err != nil {
+			t.Fatalf("store session: %v", err)
+		}
+	}
+
+	if _, err := sessMgr.StoreSession(ctx, "someone-else", session.Data{UserID: "someone-else"}); err != nil {
+		t.Fatalf("store session: %v", err)
+	}
+
+	remembered, rememberToken, err := svc.CreateRememberToken(ctx, testUserID)
+	if err != nil {
+		t.Fatalf("create remember token: %v", err)
+	}
+
+	token := requestTestReset(t, svc, mailer)
+
+	body := `{"token":"` + token + `","password":"` + newTestPassword + `","password_confirmation":"` + newTestPassword + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/reset-password", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: cfg.Session.RememberName, Value: rememberToken})
+
+	rec := httptest.NewRecorder()
+	h.HandleResetPasswordForm(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	for sessionID, kept := range map[string]bool{"laptop": false, "phone": false, "someone-else": true} {
+		_, err := sessMgr.LoadSession(requestWithCookie(cfg.Session.SessionName, sessionID))
+
+		if kept && err != nil {
+			t.Errorf("expected the session %s to be kept, got %v", sessionID, err)
+		}
+
+		if !kept && !errors.Is(err, session.ErrSessionNotFound) {
+			t.Errorf("expected the session %s to be revoked, got %v", sessionID, err)
+		}
+	}
+
+	if n := repo.liveRememberTokens(remembered.FamilyID); n != 0 {
+		t.Errorf("expected the remember me tokens to be revoked, got %d", n)
+	}
+
+	if c := responseCookie(rec.Result(), cfg.Session.RememberName); c == nil || c.MaxAge >= 0 {
+		t.Errorf("expected the remember me cookie to be cleared, got %v", c)
+	}
+}
+
+// Returns a request that carries the cookie
+func requestWithCookie(name, value string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: name, Value: value})
+
+	return req
+}
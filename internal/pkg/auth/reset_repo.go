@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("the password reset link is invalid or has expired")
+
+type PasswordResetter interface {
+	FindUserIDByEmail(ctx context.Context, email string) (string, error)
+	CreatePasswordResetToken(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
+	FindPasswordResetUserID(ctx context.Context, tokenHash string) (string, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error)
+	SetPasswordHash(ctx context.Context, userID, passwordHash string) error
+}
+
+func (r *repo) FindUserIDByEmail(ctx context.Context, email string) (string, error) {
+	var userID string
+
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE email = $1 AND deleted_at IS NULL", email).Scan(&userID)
+
+	if err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// Saves a new reset token for the user, discarding the tokens previously issued to them.
+func (r *repo) CreatePasswordResetToken(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("begin create reset token: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("delete previous reset tokens: %w", err)
+	}
+
+	const q = "INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)"
+
+	if _, err := tx.ExecContext(ctx, q, userID, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("save reset token: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+const consumeResetTokenQuery = `
+UPDATE password_reset_tokens SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id
+`
+
+// Returns the id of the user of the reset token while it can still be used
+func (r *repo) FindPasswordResetUserID(ctx context.Context, tokenHash string) (string, error) {
+	const q = "SELECT t.user_id FROM password_reset_tokens t JOIN users u ON u.id = t.user_id " +
+		"WHERE t.token_hash = $1 AND t.used_at IS NULL AND t.expires_at > NOW() AND u.deleted_at IS NULL"
+
+	var userID string
+
+	if err := r.db.QueryRowContext(ctx, q, tokenHash).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvalidResetToken
+		}
+
+		return "", fmt.Errorf("find reset token: %w", err)
+	}
+
+	return userID, nil
+}
+
+// Marks the reset token as used, so that it works once. Returns the id of its user.
+func (r *repo) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	var userID string
+
+	if err := r.db.QueryRowContext(ctx, consumeResetTokenQuery, tokenHash).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvalidResetToken
+		}
+
+		return "", fmt.Errorf("consume reset token: %w", err)
+	}
+
+	return userID, nil
+}
+
+// Replaces the password hash of the user, whatever it was
+func (r *repo) SetPasswordHash(ctx context.Context, userID, passwordHash string) error {
+	const q = "UPDATE users SET password_hash = $2 WHERE id = $1 AND deleted_at IS NULL"
+
+	if _, err := r.db.ExecContext(ctx, q, userID, passwordHash); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+type ForgotPasswordParams struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordParams struct {
+	Token                string `json:"token"`
+	Password             string `json:"password"`
+	PasswordConfirmation string `json:"password_confirmation"`
+}
+
+// Sends a password reset link to the email if it belongs to a user.
+//
+// The email is looked up and the link is sent in the background, so that the response is the same,
+// and takes the same time, whether the email belongs to a user or not.
+func (s *service) RequestPasswordReset(ctx context.Context, params ForgotPasswordParams) error {
+	form := validation.NewForm(params)
+	form.Required("Email")
+	form.IsEmail("Email")
+
+	if !form.IsValid() {
+		return &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	s.background(ctx, "send password reset link", func(ctx context.Context) error {
+		return s.sendPasswordReset(ctx, params.Email)
+	})
+
+	return nil
+}
+
+func (s *service) sendPasswordReset(ctx context.Context, email string) error {
+	userID, err := s.repo.FindUserIDByEmail(ctx, email)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			slog.Debug("password reset requested for unknown email")
+			return nil
+		}
+
+		return fmt.Errorf("find user: %w", err)
+	}
+
+	token, err := security.GenerateRandomBytesEncoded(32)
+
+	if err != nil {
+		return fmt.Errorf("generate reset token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.cfg.Auth.PasswordResetTTL)
+
+	if err := s.repo.CreatePasswordResetToken(ctx, userID, security.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	link := s.cfg.Server.BaseURL + "/reset-password?token=" + url.QueryEscape(token)
+
+	msg := mail.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone requested a password reset for your account.\r\n\r\n"+
+			"Open the link below to choose a new password. It expires in %s.\r\n\r\n%s\r\n\r\n"+
+			"If you did not request this, you can ignore this message.\r\n",
+			s.cfg.Auth.PasswordResetTTL, link),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send reset link: %w", err)
+	}
+
+	return nil
+}
+
+// Sets a new password using a reset token. Returns the id of the user whose password was reset.
+//
+// The token is consumed before the password is hashed, so that invalid tokens cost nothing to check.
+func (s *service) ResetPassword(ctx context.Context, params ResetPasswordParams) (string, error) {
+	form := validation.NewForm(params)
+	form.Required("Token", "Password", "PasswordConfirmation")
+	form.PasswordsMatch("Password", "PasswordConfirmation")
+
+	if !form.IsValid() {
+		return "", &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	tokenHash := security.HashToken(params.Token)
+
+	// The user is needed to check that the password does not contain their email
+	userID, err := s.repo.FindPasswordResetUserID(ctx, tokenHash)
+
+	if err != nil {
+		return "", err
+	}
+
+	u, err := s.FindUser(ctx, userID)
+
+	if err != nil {
+		// Deleted since the token was found
+		if errors.Is(err, ErrUserNotFound) {
+			return "", ErrInvalidResetToken
+		}
+
+		return "", err
+	}
+
+	form.Password("Password", s.policy, u.Email)
+
+	if !form.IsValid() {
+		return "", &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	// Consumed only now, so that a rejected password does not use up the link
+	if _, err := s.repo.ConsumePasswordResetToken(ctx, tokenHash); err != nil {
+		return "", err
+	}
+
+	hash, err := s.hasher.Hash(params.Password)
+
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+
+	if err := s.repo.SetPasswordHash(ctx, userID, hash); err != nil {
+		return "", err
+	}
+
+	s.record(ctx, actionPasswordReset, userID, "", nil)
+
+	return userID, nil
+}
@@ -0,0 +1,137 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+const newTestPassword = "x7#Kp2!vQ9zL"
+
+// Requests a reset of the password of the test user and returns the token of the link that was sent
+func requestTestReset(t *testing.T, svc *service, mailer *fakeMailer) string {
+	t.Helper()
+
+	if err := svc.RequestPasswordReset(context.Background(), ForgotPasswordParams{Email: testEmail}); err != nil {
+		t.Fatalf("request password reset: %v", err)
+	}
+
+	if err := svc.Shutdown(context.Background()); err != nil {
+		t.Fatalf("wait for the link: %v", err)
+	}
+
+	sent := mailer.messages()
+	if len(sent) != 1 || sent[0].To != testEmail {
+		t.Fatalf("expected a link sent to %s, got %+v", testEmail, sent)
+	}
+
+	return mailedToken(t, sent[0].Body)
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	repo := newFakeRepo()
+	svc, mailer, _ := newTestService(testConfig(), repo)
+
+	if err := svc.RequestPasswordReset(context.Background(), ForgotPasswordParams{Email: "nobody@example.com"}); err != nil {
+		t.Fatalf("expected an unknown email to be accepted, got %v", err)
+	}
+
+	if err := svc.Shutdown(context.Background()); err != nil {
+		t.Fatalf("wait for the link: %v", err)
+	}
+
+	if sent := mailer.messages(); len(sent) != 0 {
+		t.Fatalf("expected no link for an unknown email, got %+v", sent)
+	}
+
+	token := requestTestReset(t, svc, mailer)
+
+	if _, ok := repo.resetTokens[token]; ok {
+		t.Error("expected the token not to be stored as is")
+	}
+
+	if _, ok := repo.resetTokens[security.HashToken(token)]; !ok || len(repo.resetTokens) != 1 {
+		t.Errorf("expected only the hash of the token to be stored, got %d tokens", len(repo.resetTokens))
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+
+	// Returns the service, the repo and the token of a reset requested by the test user
+	requested := func(t *testing.T) (*service, *fakeRepo, string) {
+		t.Helper()
+
+		repo := newFakeRepo()
+		svc, mailer, _ := newTestService(testConfig(), repo)
+		setTestPassword(t, svc, repo)
+
+		return svc, repo, requestTestReset(t, svc, mailer)
+	}
+
+	params := func(token, password string) ResetPasswordParams {
+		return ResetPasswordParams{Token: token, Password: password, PasswordConfirmation: password}
+	}
+
+	t.Run("resets the password once", func(t *testing.T) {
+		svc, repo, token := requested(t)
+
+		userID, err := svc.ResetPassword(ctx, params(token, newTestPassword))
+		if err != nil || userID != testUserID {
+			t.Fatalf("expected the password of %s to be reset, got %q, %v", testUserID, userID, err)
+		}
+
+		if match, _, _ := svc.hasher.Verify(newTestPassword, repo.passwordHashes[testUserID]); !match {
+			t.Error("expected the new password to be saved")
+		}
+
+		if _, err := svc.ResetPassword(ctx, params(token, "an0ther!Passw0rd")); !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("expected the link to work once, got %v", err)
+		}
+	})
+
+	t.Run("rejects an expired link", func(t *testing.T) {
+		svc, repo, token := requested(t)
+		repo.resetTokens[security.HashToken(token)].expiresAt = time.Now().Add(-time.Minute)
+
+		if _, err := svc.ResetPassword(ctx, params(token, newTestPassword)); !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("expected ErrInvalidResetToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects an unknown link", func(t *testing.T) {
+		svc, _, _ := requested(t)
+
+		if _, err := svc.ResetPassword(ctx, params("unknown", newTestPassword)); !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("expected ErrInvalidResetToken, got %v", err)
+		}
+	})
+
+	t.Run("applies the password policy without using up the link", func(t *testing.T) {
+		svc, repo, token := requested(t)
+		previous := repo.passwordHashes[testUserID]
+
+		for _, password := range []string{"short", "jane@example.com!x7#Kp2"} {
+			_, err := svc.ResetPassword(ctx, params(token, password))
+
+			var inputErr *validation.Error
+			if !errors.As(err, &inputErr) || len(inputErr.Errors["password"]) == 0 {
+				t.Fatalf("expected a password field error for %q, got %v", password, err)
+			}
+		}
+
+		if repo.passwordHashes[testUserID] != previous {
+			t.Error("expected the password to be kept")
+		}
+
+		if _, err := svc.ResetPassword(ctx, params(token, newTestPassword)); err != nil {
+			t.Errorf("expected the link to still work, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+type RoleStore interface {
+	UserAccess(ctx context.Context, userID string) (roles, permissions []string, err error)
+	ListRoles(ctx context.Context) ([]Role, error)
+	ListUserRoles(ctx context.Context, limit, offset int) ([]UserRoles, error)
+	AssignRole(ctx context.Context, userID, role string) error
+	RemoveRole(ctx context.Context, userID, role string) (bool, error)
+}
+
+// Role is a named set of permissions that is assigned to users.
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserRoles is a user with the roles assigned to it.
+type UserRoles struct {
+	UserID    string    `json:"id"`
+	Email     string    `json:"email"`
+	Roles     []string  `json:"roles"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const userAccessQuery = `
+SELECT ur.role, COALESCE(rp.permission, '')
+FROM user_roles ur
+LEFT JOIN role_permissions rp ON rp.role = ur.role
+WHERE ur.user_id = $1
+AND ur.user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+ORDER BY ur.role, rp.permission
+`
+
+// Returns the roles of the user and the permissions that they grant
+func (r *repo) UserAccess(ctx context.Context, userID string) ([]string, []string, error) {
+	rows, err := r.db.QueryContext(ctx, userAccessQuery, userID)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("find user roles: %w", err)
+	}
+
+	defer rows.Close()
+
+	var roles, permissions []string
+
+	for rows.Next() {
+		var role, permission string
+
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, nil, fmt.Errorf("scan user role: %w", err)
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1] != role {
+			roles = append(roles, role)
+		}
+
+		if permission != "" && !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+
+	return roles, permissions, rows.Err()
+}
+
+const listRolesQuery = `
+SELECT r.name, r.description, COALESCE(string_agg(rp.permission, ',' ORDER BY rp.permission), '')
+FROM roles r
+LEFT JOIN role_permissions rp ON rp.role = r.name
+GROUP BY r.name, r.description
+ORDER BY r.name
+`
+
+func (r *repo) ListRoles(ctx context.Context) ([]Role, error) {
+	rows, err := r.db.QueryContext(ctx, listRolesQuery)
+
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+
+	defer rows.Close()
+
+	var roles []Role
+
+	for rows.Next() {
+		var (
+			role        Role
+			permissions string
+		)
+
+		if err := rows.Scan(&role.Name, &role.Description, &permissions); err != nil {
+			return nil, fmt.Errorf("scan role: %w", err)
+		}
+
+		if permissions != "" {
+			role.Permissions = strings.Split(permissions, ",")
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+const listUserRolesQuery = `
+SELECT u.id, u.email, u.created_at, COALESCE(string_agg(ur.role, ',' ORDER BY ur.role), '')
+FROM users u
+LEFT JOIN user_roles ur ON ur.user_id = u.id
+WHERE u.deleted_at IS NULL
+GROUP BY u.id
+ORDER BY u.email
+LIMIT $1 OFFSET $2
+`
+
+// Lists the users with their roles by email
+func (r *repo) ListUserRoles(ctx context.Context, limit, offset int) ([]UserRoles, error) {
+	rows, err := r.db.QueryContext(ctx, listUserRolesQuery, limit, offset)
+
+	if err != nil {
+		return nil, fmt.Errorf("list user roles: %w", err)
+	}
+
+	defer rows.Close()
+
+	var users []UserRoles
+
+	for rows.Next() {
+		var (
+			u     UserRoles
+			roles string
+		)
+
+		if err := rows.Scan(&u.UserID, &u.Email, &u.CreatedAt, &roles); err != nil {
+			return nil, fmt.Errorf("scan user roles: %w", err)
+		}
+
+		if roles != "" {
+			u.Roles = strings.Split(roles, ",")
+		}
+
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// Assigns the role to the user, doing nothing when it is already assigned.
+// Returns ErrUserNotFound when there is no such user.
+func (r *repo) AssignRole(ctx context.Context, userID, role string) error {
+	if !validation.IsUUID(userID) {
+		return ErrUserNotFound
+	}
+
+	const q = `WITH u AS (SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL),
+assigned AS (INSERT INTO user_roles (user_id, role) SELECT id, $2 FROM u ON CONFLICT DO NOTHING)
+SELECT EXISTS (SELECT 1 FROM u)`
+
+	var found bool
+
+	if err := r.db.QueryRowContext(ctx, q, userID, role).Scan(&found); err != nil {
+		if db.IsForeignKeyViolation(err) {
+			return ErrRoleNotFound
+		}
+
+		return fmt.Errorf("assign role: %w", err)
+	}
+
+	if !found {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Removes the role from the user. Reports false when the user did not have it.
+func (r *repo) RemoveRole(ctx context.Context, userID, role string) (bool, error) {
+	if !validation.IsUUID(userID) {
+		return false, nil
+	}
+
+	const q = "DELETE FROM user_roles WHERE user_id = $1 AND role = $2"
+
+	res, err := r.db.ExecContext(ctx, q, userID, role)
+
+	if err != nil {
+		return false, fmt.Errorf("remove role: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("remove role: %w", err)
+	}
+
+	return n == 1, nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Roles seeded by the migrations
+const RoleAdmin = "admin"
+
+// Permissions checked by the app, see RequirePermission
+const (
+	PermissionManageUsers  = "users:manage"
+	PermissionReadAuditLog = "audit:read"
+)
+
+var (
+	ErrRoleNotFound     = errors.New("role not found")
+	ErrPermissionDenied = errors.New("you do not have permission to do this")
+	ErrOwnRoles         = errors.New("you cannot change your own roles")
+)
+
+// Users listed on a page of the user management page
+const usersPerPage = 50
+
+// Returns the roles of the user and the permissions that they grant
+func (s *service) UserAccess(ctx context.Context, userID string) ([]string, []string, error) {
+	return s.repo.UserAccess(ctx, userID)
+}
+
+func (s *service) Roles(ctx context.Context) ([]Role, error) {
+	return s.repo.ListRoles(ctx)
+}
+
+// Lists the users with their roles, a page at a time starting at 1
+func (s *service) UserRoles(ctx context.Context, page int) ([]UserRoles, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	return s.repo.ListUserRoles(ctx, usersPerPage, (page-1)*usersPerPage)
+}
+
+// Assigns a role to a user. Users cannot change their own roles, so that an admin cannot lock themselves out.
+func (s *service) AssignRole(ctx context.Context, actorID, userID, role string) error {
+	if actorID == userID {
+		return ErrOwnRoles
+	}
+
+	if err := s.repo.AssignRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	s.record(ctx, actionRoleAssigned, actorID, userID, map[string]any{"role": role})
+
+	return nil
+}
+
+func (s *service) RemoveRole(ctx context.Context, actorID, userID, role string) error {
+	if actorID == userID {
+		return ErrOwnRoles
+	}
+
+	removed, err := s.repo.RemoveRole(ctx, userID, role)
+
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		return ErrRoleNotFound
+	}
+
+	s.record(ctx, actionRoleRemoved, actorID, userID, map[string]any{"role": role})
+
+	return nil
+}
@@ -6,10 +6,84 @@ import (
 )
 
 func RegisterAuthRoutes(router *goexpress.Router, handler *Handler, sessMgr session.Manager) {
+	requireUser := goexpress.Middleware(RequireUserMiddleware(handler.config.Session, sessMgr))
+	manageUsers := goexpress.Middleware(RequirePermission(PermissionManageUsers))
+	readAuditLog := goexpress.Middleware(RequirePermission(PermissionReadAuditLog))
+	notImpersonating := goexpress.Middleware(BlockImpersonationMiddleware())
+
 	router.Get("/signup", handler.HandleSignUp)
 	router.Get("/signin", handler.HandleSignin)
-	router.Get("/profile", handler.HandleProfile, goexpress.Middleware(RequireUserMiddleware(sessMgr)))
+	router.Get("/forgot-password", handler.HandleForgotPassword)
+	router.Get("/reset-password", handler.HandleResetPassword)
+	router.Get("/signin/2fa", handler.HandleTwoFactorForm)
+	router.Get("/signin/magic-link", handler.HandleMagicLink)
+	router.Get("/signin/magic-link/confirm", handler.HandleMagicLinkSignIn)
+	router.Get("/profile", handler.HandleProfile, requireUser)
+	router.Get("/profile/password", handler.HandleChangePassword, requireUser, notImpersonating)
+	router.Get("/profile/email", handler.HandleChangeEmail, requireUser, notImpersonating)
+	router.Get("/profile/email/confirm", handler.HandleConfirmEmailChange)
+	router.Get("/profile/delete", handler.HandleDeleteAccount, requireUser, notImpersonating)
+	router.Get("/profile/2fa/setup", handler.HandleTwoFactorSetup, requireUser, notImpersonating)
+	router.Get("/verify-email", handler.HandleVerifyEmailNotice, requireUser)
+	router.Get("/verify-email/confirm", handler.HandleVerifyEmail)
+	router.Get("/auth/oidc/link", handler.HandleOAuthLinkForm)
+	router.Get("/auth/oidc/{provider}", handler.HandleOAuthStart)
+	router.Get("/auth/oidc/{provider}/callback", handler.HandleOAuthCallback)
+	router.Get("/admin/users", handler.HandleAdminUsers, requireUser, manageUsers)
+	router.Get("/admin/invitations", handler.HandleAdminInvitations, requireUser, manageUsers)
+	router.Get("/admin/audit", handler.HandleAuditLog, requireUser, readAuditLog)
+	router.Get("/admin/audit/export", handler.HandleExportAuditLog, requireUser, readAuditLog)
+
+	router.Post("/signout", handler.HandleSignOut)
+	router.Post("/signin/2fa", handler.HandleTwoFactorSignIn)
+	router.Post("/profile/2fa/setup", handler.HandleTwoFactorSetupStart, requireUser, notImpersonating)
+	router.Post("/profile/2fa/enable", handler.HandleTwoFactorEnable, requireUser, notImpersonating)
+	router.Post("/profile/2fa/disable", handler.HandleTwoFactorDisable, requireUser, notImpersonating)
+	router.Post("/sessions/{id}/revoke", handler.HandleRevokeSession, requireUser, notImpersonating)
+	router.Post("/sessions/revoke-others", handler.HandleRevokeOtherSessions, requireUser, notImpersonating)
+	router.Post("/verify-email/resend", handler.HandleResendVerification, requireUser)
+	router.Post("/auth/oidc/link", handler.HandleOAuthLink, notImpersonating)
+	router.Post("/passkeys/{id}/delete", handler.HandleDeletePasskey, requireUser, notImpersonating)
+	router.Post("/tokens", handler.HandleCreateAccessToken, requireUser, notImpersonating)
+	router.Post("/tokens/{id}/revoke", handler.HandleRevokeAccessToken, requireUser, notImpersonating)
+	router.Post("/admin/users/{id}/roles", handler.HandleAssignRole, requireUser, manageUsers)
+	router.Post("/admin/users/{id}/roles/{role}/remove", handler.HandleRemoveRole, requireUser, manageUsers)
+	router.Post("/admin/invitations", handler.HandleCreateInvitation, requireUser, manageUsers)
+	router.Post("/admin/invitations/{id}/revoke", handler.HandleRevokeInvitation, requireUser, manageUsers)
+	router.Post("/admin/users/{id}/impersonate", handler.HandleStartImpersonation, requireUser, manageUsers)
+	router.Post("/impersonation/stop", handler.HandleStopImpersonation, requireUser)
 
 	router.Post("/api/signup", handler.HandleSignUpForm)
 	router.Post("/api/signin", handler.HandleSignInForm)
+	router.Post("/api/signin/2fa", handler.HandleTwoFactorSignIn)
+	router.Post("/api/signin/magic-link", handler.HandleMagicLinkForm)
+	router.Post("/api/signout", handler.HandleSignOut)
+	router.Post("/api/forgot-password", handler.HandleForgotPasswordForm)
+	router.Post("/api/reset-password", handler.HandleResetPasswordForm)
+	router.Post("/api/profile/password", handler.HandleChangePasswordForm, requireUser, notImpersonating)
+	router.Post("/api/profile/email", handler.HandleChangeEmailForm, requireUser, notImpersonating)
+	router.Post("/api/profile/delete", handler.HandleDeleteAccountForm, requireUser, notImpersonating)
+	router.Post("/api/verify-email/resend", handler.HandleResendVerification, requireUser)
+	router.Post("/api/webauthn/register/begin", handler.HandlePasskeyRegistrationBegin, requireUser, notImpersonating)
+	router.Post("/api/webauthn/register/finish", handler.HandlePasskeyRegistrationFinish, requireUser, notImpersonating)
+	router.Post("/api/webauthn/login/begin", handler.HandlePasskeyLoginBegin)
+	router.Post("/api/webauthn/login/finish", handler.HandlePasskeyLoginFinish)
+	router.Post("/api/tokens", handler.HandleCreateAccessToken, requireUser, notImpersonating)
+	router.Post("/api/admin/users/{id}/roles", handler.HandleAssignRole, requireUser, manageUsers)
+	router.Post("/api/admin/invitations", handler.HandleCreateInvitation, requireUser, manageUsers)
+	router.Post("/api/admin/users/{id}/impersonate", handler.HandleStartImpersonation, requireUser, manageUsers)
+	router.Post("/api/impersonation/stop", handler.HandleStopImpersonation, requireUser)
+
+	router.Get("/api/sessions", handler.HandleListSessions, requireUser)
+	router.Get("/api/tokens", handler.HandleListAccessTokens, requireUser)
+	router.Get("/api/admin/users", handler.HandleAdminUsers, requireUser, manageUsers)
+	router.Get("/api/admin/invitations", handler.HandleAdminInvitations, requireUser, manageUsers)
+	router.Get("/api/admin/audit", handler.HandleAuditLog, requireUser, readAuditLog)
+	router.Get("/api/admin/audit/export", handler.HandleExportAuditLog, requireUser, readAuditLog)
+	router.Delete("/api/sessions/{id}", handler.HandleRevokeSession, requireUser, notImpersonating)
+	router.Delete("/api/sessions", handler.HandleRevokeOtherSessions, requireUser, notImpersonating)
+	router.Delete("/api/webauthn/credentials/{id}", handler.HandleDeletePasskey, requireUser, notImpersonating)
+	router.Delete("/api/tokens/{id}", handler.HandleRevokeAccessToken, requireUser, notImpersonating)
+	router.Delete("/api/admin/users/{id}/roles/{role}", handler.HandleRemoveRole, requireUser, manageUsers)
+	router.Delete("/api/admin/invitations/{id}", handler.HandleRevokeInvitation, requireUser, manageUsers)
 }
@@ -6,39 +6,180 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"sync"
 
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/audit"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/webauthn"
 )
 
 type service struct {
-	authenticator Authenticator
-	cfg           *config.Config
+	repo     Repo
+	cfg      *config.Config
+	mailer   mail.Mailer
+	webauthn *webauthn.WebAuthn
+	hasher   *security.PasswordHasher
+	policy   validation.PasswordPolicy
+	auditor  audit.Recorder
+	tasks    sync.WaitGroup // Tasks that run after the response, see background
 }
 
 type Service interface {
 	SignUp(context.Context, SignUpParams) (*user.User, error)
+	RegistrationMode() string
+	CreateInvitation(ctx context.Context, actorID string, params CreateInvitationParams) (*NewInvitation, error)
+	Invitation(ctx context.Context, token string) (*Invitation, error)
+	Invitations(ctx context.Context) ([]Invitation, error)
+	RevokeInvitation(ctx context.Context, id string) error
+	FindUser(ctx context.Context, userID string) (*user.User, error)
 	SignIn(context.Context, SignInParams) (string, error)
+	RequestMagicLink(ctx context.Context, params MagicLinkParams, nonce string) error
+	SignInMagicLink(ctx context.Context, token, nonce string) (string, error)
+	RequestPasswordReset(context.Context, ForgotPasswordParams) error
+	ResetPassword(context.Context, ResetPasswordParams) (string, error)
+	VerifyEmail(context.Context, VerifyEmailParams) error
+	ResendVerificationEmail(ctx context.Context, userID string) error
+	EmailStatus(ctx context.Context, userID string) (*EmailStatus, error)
+	IsEmailVerified(ctx context.Context, userID string) (bool, error)
+	SignInOAuth(context.Context, OAuthParams) (*OAuthSignInResult, error)
+	LinkOAuth(ctx context.Context, params OAuthParams, password string) (string, error)
+	TwoFactorAvailable() bool
+	TwoFactorEnabled(ctx context.Context, userID string) (bool, error)
+	BeginTOTPEnrollment(ctx context.Context, userID string) error
+	TOTPEnrollment(ctx context.Context, userID string) (*TOTPEnrollment, error)
+	EnableTOTP(ctx context.Context, userID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID, code string) error
+	VerifySecondFactor(ctx context.Context, userID, code string) error
+	BeginPasskeyRegistration(ctx context.Context, userID string) (*webauthn.CreationOptions, *webauthn.Ceremony, error)
+	FinishPasskeyRegistration(ctx context.Context, userID string, ceremony *webauthn.Ceremony, res *webauthn.RegistrationResponse, name string) error
+	BeginPasskeyLogin() (*webauthn.RequestOptions, *webauthn.Ceremony, error)
+	FinishPasskeyLogin(ctx context.Context, ceremony *webauthn.Ceremony, res *webauthn.AssertionResponse) (*PasskeyLoginResult, error)
+	Passkeys(ctx context.Context, userID string) ([]Passkey, error)
+	DeletePasskey(ctx context.Context, userID, id string) error
+	CreateAccessToken(ctx context.Context, userID string, params CreateAccessTokenParams) (*NewAccessToken, error)
+	AuthenticateAccessToken(ctx context.Context, token string) (*AccessToken, error)
+	AccessTokens(ctx context.Context, userID string) ([]AccessToken, error)
+	RevokeAccessToken(ctx context.Context, userID, id string) error
+	CreateRememberToken(ctx context.Context, userID string) (*RememberToken, string, error)
+	RotateRememberToken(ctx context.Context, token string) (*RememberToken, string, error)
+	RevokeRememberToken(ctx context.Context, token string) error
+	RevokeRememberTokenFamily(ctx context.Context, familyID string) error
+	RevokeRememberTokens(ctx context.Context, userID, exceptToken string) error
+	UserAccess(ctx context.Context, userID string) (roles, permissions []string, err error)
+	Roles(ctx context.Context) ([]Role, error)
+	UserRoles(ctx context.Context, page int) ([]UserRoles, error)
+	AssignRole(ctx context.Context, actorID, userID, role string) error
+	RemoveRole(ctx context.Context, actorID, userID, role string) error
+	ImpersonationTarget(ctx context.Context, actorID, userID string) (*user.User, error)
+	HasPassword(ctx context.Context, userID string) (bool, error)
+	ChangePassword(ctx context.Context, userID string, params ChangePasswordParams) error
+	RequestEmailChange(ctx context.Context, userID string, params ChangeEmailParams) error
+	ConfirmEmailChange(context.Context, ConfirmEmailChangeParams) error
+	DeleteAccount(ctx context.Context, userID string, params DeleteAccountParams) (*user.User, error)
+	Shutdown(ctx context.Context) error
 }
 
-func NewAuthService(cfg *config.Config, authenticator Authenticator) Service {
+func NewAuthService(cfg *config.Config, repo Repo, mailer mail.Mailer, auditor audit.Recorder) Service {
 	return &service{
-		authenticator: authenticator,
-		cfg:           cfg,
+		repo:     repo,
+		cfg:      cfg,
+		mailer:   mailer,
+		webauthn: webauthn.New(cfg.WebAuthn),
+		hasher:   security.NewPasswordHasher(argon2Params(cfg.Auth.PasswordHash)),
+		policy:   passwordPolicy(cfg.Auth.PasswordPolicy),
+		auditor:  auditor,
 	}
 }
 
+// Runs fn after the request with a context that is not canceled with it, e.g. to send a mail without
+// the response waiting for it. Errors can only be logged, since the response is already on its way.
+func (s *service) background(ctx context.Context, task string, fn func(context.Context) error) {
+	s.tasks.Add(1)
+
+	go func() {
+		defer s.tasks.Done()
+
+		if err := fn(context.WithoutCancel(ctx)); err != nil {
+			slog.Error("background task failed", "task", task, "error", err)
+		}
+	}()
+}
+
+// Waits for the tasks that run after the responses, like sending mails, to finish.
+// Call it once the server stopped taking requests and before the database is closed.
+// Returns an error when ctx is done first.
+func (s *service) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		s.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for background tasks: %w", ctx.Err())
+	}
+}
+
+// Returns the rules of new passwords
+func passwordPolicy(cfg config.PasswordPolicyConfig) validation.PasswordPolicy {
+	policy := validation.PasswordPolicy{
+		MinLength:   cfg.MinLength,
+		MaxLength:   cfg.MaxLength,
+		MinStrength: cfg.MinStrength,
+	}
+
+	if cfg.BreachedFile != "" {
+		policy.Breached = validation.NewBreachedFile(cfg.BreachedFile)
+	}
+
+	return policy
+}
+
+// Returns the hashing parameters of the configuration, keeping the defaults of the unset ones
+func argon2Params(cfg config.PasswordHashConfig) security.Argon2Params {
+	params := security.DefaultArgon2Params()
+
+	if cfg.Memory > 0 {
+		params.Memory = uint32(cfg.Memory)
+	}
+
+	if cfg.Iterations > 0 {
+		params.Iterations = uint32(cfg.Iterations)
+	}
+
+	if cfg.Parallelism > 0 && cfg.Parallelism <= 255 {
+		params.Parallelism = uint8(cfg.Parallelism)
+	}
+
+	return params
+}
+
 var ErrEmailExists = errors.New("duplicate email")
 var ErrUserPassInvalid = errors.New("invalid username or password")
+var ErrUserNotFound = errors.New("user not found")
 
-// Signs up a user using email and password
+// Signs up a user using email and password.
+//
+// A user with an invitation gets the role of the invitation, and their email is verified since the invitation reached it.
 func (s *service) SignUp(ctx context.Context, params SignUpParams) (*user.User, error) {
+	if err := s.checkRegistration(params.Invitation); err != nil {
+		return nil, err
+	}
+
 	form := validation.NewForm(params)
 	form.Required("Email", "Password", "PasswordConfirmation")
 	form.PasswordsMatch("Password", "PasswordConfirmation")
 	form.IsEmail("Email")
+	form.Password("Password", s.policy, params.Email)
 
 	if !form.IsValid() {
 		return nil, &validation.Error{
@@ -46,7 +187,24 @@ func (s *service) SignUp(ctx context.Context, params SignUpParams) (*user.User,
 		}
 	}
 
-	hash, err := security.GenerateHash(params.Password)
+	// The invitation is checked before the password is hashed, so that a bad invitation costs no hashing
+	var inv *Invitation
+
+	if params.Invitation != "" {
+		var err error
+
+		if inv, err = s.Invitation(ctx, params.Invitation); err != nil {
+			return nil, err
+		}
+
+		if !strings.EqualFold(strings.TrimSpace(params.Email), inv.Email) {
+			return nil, fieldError("email", "Sign up with the email address the invitation was sent to.")
+		}
+
+		params.Email = inv.Email
+	}
+
+	hash, err := s.hasher.Hash(params.Password)
 
 	if err != nil {
 		return nil, fmt.Errorf("hash password: %w", err)
@@ -54,7 +212,51 @@ func (s *service) SignUp(ctx context.Context, params SignUpParams) (*user.User,
 
 	params.Password = hash
 
-	return s.authenticator.SignUp(ctx, params)
+	if inv != nil {
+		return s.signUpInvited(ctx, params, inv)
+	}
+
+	u, err := s.repo.SignUp(ctx, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actionSignUp, u.ID, "", map[string]any{"email": u.Email})
+
+	// The account exists at this point, the user can ask for another link if this one is lost
+	if err := s.sendVerificationEmail(ctx, u.ID, u.Email); err != nil {
+		slog.Error("failed to send verification email", "user_id", u.ID, "error", err)
+	}
+
+	return u, nil
+}
+
+func (s *service) signUpInvited(ctx context.Context, params SignUpParams, inv *Invitation) (*user.User, error) {
+	u, err := s.repo.SignUpInvited(ctx, params, security.HashToken(params.Invitation))
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actionSignUp, u.ID, "", map[string]any{"email": u.Email, "invitation_id": inv.ID, "role": inv.Role})
+
+	return u, nil
+}
+
+// Finds a user who is not deleted
+func (s *service) FindUser(ctx context.Context, userID string) (*user.User, error) {
+	u, err := s.repo.FindUser(ctx, userID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("find user: %w", err)
+	}
+
+	return u, nil
 }
 
 // Signs in a user using email and password
@@ -69,7 +271,7 @@ func (s *service) SignIn(ctx context.Context, params SignInParams) (string, erro
 		}
 	}
 
-	result, err := s.authenticator.SignIn(ctx, params.Email)
+	result, err := s.repo.SignIn(ctx, params.Email)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -81,7 +283,12 @@ func (s *service) SignIn(ctx context.Context, params SignInParams) (string, erro
 
 	slog.Debug("sign in", "hash", result.Hash)
 
-	match, err := security.VerifyPassword(params.Password, result.Hash)
+	// Users who signed up with a provider have no password
+	if result.Hash == "" {
+		return "", fmt.Errorf("verify password: %w", ErrUserPassInvalid)
+	}
+
+	match, err := s.verifyPassword(ctx, result.ID, params.Password, result.Hash)
 
 	if err != nil {
 		return "", err
@@ -93,3 +300,24 @@ func (s *service) SignIn(ctx context.Context, params SignInParams) (string, erro
 
 	return result.ID, nil
 }
+
+// Checks the password of the user against their stored hash.
+// When the hash was made with weaker parameters than the configured ones, it is replaced with a new one.
+func (s *service) verifyPassword(ctx context.Context, userID, password, hash string) (bool, error) {
+	match, rehash, err := s.hasher.Verify(password, hash)
+
+	if err != nil || !match {
+		return false, err
+	}
+
+	if rehash {
+		// The password is correct either way, so a failed upgrade is retried on the next sign in
+		if newHash, err := s.hasher.Hash(password); err != nil {
+			slog.Error("failed to rehash password", "user_id", userID, "error", err)
+		} else if err := s.repo.UpdatePasswordHash(ctx, userID, hash, newHash); err != nil {
+			slog.Error("failed to upgrade password hash", "user_id", userID, "error", err)
+		}
+	}
+
+	return true, nil
+}
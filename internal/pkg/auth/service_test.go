@@ -0,0 +1,637 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/audit"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/lockout"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+const (
+	testUserID   = "7a1c3e52-4b6d-4f0e-9a8b-2c5d7e9f1a3b"
+	testEmail    = "jane@example.com"
+	testPassword = "correct horse battery staple"
+)
+
+// Keeps what the tests need in memory.
+// The methods of Repo that are not implemented here panic, since no test should reach them.
+type fakeRepo struct {
+	Repo
+	mu             sync.Mutex
+	users          map[string]*user.User      // By id
+	passwordHashes map[string]string          // By user id
+	resetTokens    map[string]*fakeResetToken // By token hash
+	rememberTokens map[string]*RememberToken
+	families       int
+	magicLinks     map[string]fakeMagicLink // By token hash
+	invitations    map[string]*Invitation   // By token hash
+	invited        []SignUpParams
+	recoveryCodes  map[string]bool         // Hashes of the unused codes of the test user
+	accessTokens   map[string]*AccessToken // By token hash
+}
+
+type fakeResetToken struct {
+	userID    string
+	expiresAt time.Time
+	used      bool
+}
+
+type fakeMagicLink struct {
+	userID    string
+	nonceHash string
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		users: map[string]*user.User{
+			testUserID: {Model: db.Model{ID: testUserID}, Email: testEmail},
+		},
+		passwordHashes: make(map[string]string),
+		resetTokens:    make(map[string]*fakeResetToken),
+		rememberTokens: make(map[string]*RememberToken),
+		magicLinks:     make(map[string]fakeMagicLink),
+		invitations:    make(map[string]*Invitation),
+		recoveryCodes:  make(map[string]bool),
+		accessTokens:   make(map[string]*AccessToken),
+	}
+}
+
+func (r *fakeRepo) FindUser(_ context.Context, userID string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+
+	if !ok || u.DeletedAt.Valid {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *u
+	return &found, nil
+}
+
+func (r *fakeRepo) FindUserIDByEmail(_ context.Context, email string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) && !u.DeletedAt.Valid {
+			return u.ID, nil
+		}
+	}
+
+	return "", sql.ErrNoRows
+}
+
+func (r *fakeRepo) FindPasswordHash(_ context.Context, userID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return "", sql.ErrNoRows
+	}
+
+	return r.passwordHashes[userID], nil
+}
+
+func (r *fakeRepo) DeleteUser(_ context.Context, u *user.User, mode db.DeleteMode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if mode == db.HardDelete {
+		delete(r.users, u.ID)
+		return nil
+	}
+
+	u.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	stored := *u
+	r.users[u.ID] = &stored
+
+	return nil
+}
+
+func (r *fakeRepo) CreatePasswordResetToken(_ context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, t := range r.resetTokens {
+		if t.userID == userID {
+			delete(r.resetTokens, hash)
+		}
+	}
+
+	r.resetTokens[tokenHash] = &fakeResetToken{userID: userID, expiresAt: expiresAt}
+
+	return nil
+}
+
+// Returns the reset token while it can still be used, the caller must hold the lock
+func (r *fakeRepo) usableResetToken(tokenHash string) (*fakeResetToken, error) {
+	t, ok := r.resetTokens[tokenHash]
+
+	if !ok || t.used || !t.expiresAt.After(time.Now()) {
+		return nil, ErrInvalidResetToken
+	}
+
+	return t, nil
+}
+
+func (r *fakeRepo) FindPasswordResetUserID(_ context.Context, tokenHash string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, err := r.usableResetToken(tokenHash)
+
+	if err != nil {
+		return "", err
+	}
+
+	return t.userID, nil
+}
+
+func (r *fakeRepo) ConsumePasswordResetToken(_ context.Context, tokenHash string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, err := r.usableResetToken(tokenHash)
+
+	if err != nil {
+		return "", err
+	}
+
+	t.used = true
+
+	return t.userID, nil
+}
+
+func (r *fakeRepo) SetPasswordHash(_ context.Context, userID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.passwordHashes[userID] = passwordHash
+
+	return nil
+}
+
+func (r *fakeRepo) SaveRememberToken(_ context.Context, t *RememberToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.families++
+	t.FamilyID = fmt.Sprintf("00000000-0000-4000-8000-%012d", r.families)
+	t.CreatedAt = time.Now()
+
+	saved := *t
+	r.rememberTokens[t.Selector] = &saved
+
+	return nil
+}
+
+func (r *fakeRepo) FindRememberToken(_ context.Context, selector string) (*RememberToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.rememberTokens[selector]
+
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *t
+	return &found, nil
+}
+
+func (r *fakeRepo) RotateRememberToken(_ context.Context, selector string, next *RememberToken) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.rememberTokens[selector]
+
+	if !ok || t.RotatedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	t.RotatedAt = &now
+	next.CreatedAt = now
+
+	saved := *next
+	r.rememberTokens[next.Selector] = &saved
+
+	return true, nil
+}
+
+func (r *fakeRepo) DeleteRememberTokenFamily(_ context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for selector, t := range r.rememberTokens {
+		if t.FamilyID == familyID {
+			delete(r.rememberTokens, selector)
+		}
+	}
+
+	return nil
+}
+
+func (r *fakeRepo) DeleteRememberTokens(_ context.Context, userID, exceptFamilyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for selector, t := range r.rememberTokens {
+		if t.UserID == userID && t.FamilyID != exceptFamilyID {
+			delete(r.rememberTokens, selector)
+		}
+	}
+
+	return nil
+}
+
+// Returns the tokens of the family that were not replaced yet
+func (r *fakeRepo) liveRememberTokens(familyID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int
+
+	for _, t := range r.rememberTokens {
+		if t.FamilyID == familyID && t.RotatedAt == nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *fakeRepo) CreateMagicLinkToken(_ context.Context, userID, tokenHash, nonceHash string, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.magicLinks[tokenHash] = fakeMagicLink{userID: userID, nonceHash: nonceHash}
+
+	return nil
+}
+
+func (r *fakeRepo) ConsumeMagicLinkToken(_ context.Context, tokenHash, nonceHash string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.magicLinks[tokenHash]
+
+	if !ok || link.nonceHash != nonceHash {
+		return "", sql.ErrNoRows
+	}
+
+	delete(r.magicLinks, tokenHash)
+
+	return link.userID, nil
+}
+
+func (r *fakeRepo) FindInvitation(_ context.Context, tokenHash string) (*Invitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.invitations[tokenHash]
+
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *inv
+	return &found, nil
+}
+
+func (r *fakeRepo) SignUpInvited(_ context.Context, params SignUpParams, tokenHash string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.invitations[tokenHash]; !ok {
+		return nil, ErrInvalidInvitation
+	}
+
+	delete(r.invitations, tokenHash)
+	r.invited = append(r.invited, params)
+
+	return &user.User{Model: db.Model{ID: "invited"}, Email: params.Email}, nil
+}
+
+func (r *fakeRepo) UseRecoveryCode(_ context.Context, userID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if userID != testUserID || !r.recoveryCodes[codeHash] {
+		return false, nil
+	}
+
+	delete(r.recoveryCodes, codeHash)
+
+	return true, nil
+}
+
+func (r *fakeRepo) SaveAccessToken(_ context.Context, t *AccessToken, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.ID = fmt.Sprintf("00000000-0000-4000-9000-%012d", len(r.accessTokens)+1)
+	t.CreatedAt = time.Now()
+
+	saved := *t
+	r.accessTokens[hash] = &saved
+
+	return nil
+}
+
+func (r *fakeRepo) FindAccessToken(_ context.Context, hash string) (*AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.accessTokens[hash]
+
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *t
+	return &found, nil
+}
+
+func (r *fakeRepo) TouchAccessToken(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.accessTokens {
+		if t.ID == id {
+			now := time.Now()
+			t.LastUsedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func (r *fakeRepo) DeleteAccessToken(_ context.Context, userID, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Like postgres, which cannot compare the uuid column to anything else
+	if !validation.IsUUID(id) {
+		return false, fmt.Errorf("invalid input syntax for type uuid: %q", id)
+	}
+
+	for hash, t := range r.accessTokens {
+		if t.ID == id && t.UserID == userID {
+			delete(r.accessTokens, hash)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Keeps the sent messages
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = append(m.sent, msg)
+
+	return nil
+}
+
+func (m *fakeMailer) messages() []mail.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]mail.Message(nil), m.sent...)
+}
+
+// Keeps the recorded events
+type fakeAuditLog struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (l *fakeAuditLog) Record(_ context.Context, event audit.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, event)
+}
+
+func (l *fakeAuditLog) List(context.Context, audit.Filter) ([]audit.Entry, error) {
+	return nil, nil
+}
+
+func (l *fakeAuditLog) Export(context.Context, audit.Filter, func(audit.Entry) error) error {
+	return nil
+}
+
+// Returns the actions of the recorded events in order
+func (l *fakeAuditLog) actions() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	actions := make([]string, 0, len(l.events))
+
+	for _, e := range l.events {
+		actions = append(actions, e.Action)
+	}
+
+	return actions
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Server: config.HTTPServerConfig{BaseURL: "http://localhost:8888"},
+		Session: config.SessionConfig{
+			SessionName:      "sid",
+			CSRFName:         "xsrf",
+			RememberName:     "remember",
+			SameSite:         http.SameSiteStrictMode,
+			SessionDuration:  30 * time.Minute,
+			RememberDuration: 30 * 24 * time.Hour,
+			RememberMaxAge:   90 * 24 * time.Hour,
+		},
+		Auth: config.AuthConfig{
+			MagicLinkTTL:      15 * time.Minute,
+			PasswordResetTTL:  time.Hour,
+			RegistrationMode:  config.RegistrationOpen,
+			AccountPurgeAfter: 30 * 24 * time.Hour,
+			// Cheap hashing, since the tests do not need it to be slow
+			PasswordHash:   config.PasswordHashConfig{Memory: 64, Iterations: 1, Parallelism: 1},
+			PasswordPolicy: config.PasswordPolicyConfig{MinLength: 8, MaxLength: 64},
+		},
+		Lockout: config.LockoutConfig{
+			MaxAttempts:      10,
+			MaxAttemptsPerIP: 100,
+			Window:           time.Hour,
+			Duration:         15 * time.Minute,
+		},
+	}
+}
+
+func newTestService(cfg *config.Config, repo *fakeRepo) (*service, *fakeMailer, *fakeAuditLog) {
+	mailer := &fakeMailer{}
+	auditLog := &fakeAuditLog{}
+
+	return NewAuthService(cfg, repo, mailer, auditLog).(*service), mailer, auditLog
+}
+
+// Returns a handler of the service that only serves JSON, since it has no templates
+func newTestHandler(cfg *config.Config, svc Service, sessMgr session.Manager) (*Handler, *fakeAuditLog) {
+	auditLog := &fakeAuditLog{}
+
+	return NewHandler(cfg, nil, svc, nil, sessMgr, nil, lockout.NewMemoryLockout(), auditLog), auditLog
+}
+
+// Saves the password of the test user
+func setTestPassword(t *testing.T, svc *service, repo *fakeRepo) {
+	t.Helper()
+
+	hash, err := svc.hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	repo.passwordHashes[testUserID] = hash
+}
+
+func TestShutdown(t *testing.T) {
+	svc, _, _ := newTestService(testConfig(), newFakeRepo())
+
+	release := make(chan struct{})
+	var finished bool
+
+	svc.background(context.Background(), "test", func(context.Context) error {
+		<-release
+		finished = true
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := svc.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the shutdown to give up when the context is done, got %v", err)
+	}
+
+	close(release)
+
+	if err := svc.Shutdown(context.Background()); err != nil || !finished {
+		t.Errorf("expected the shutdown to wait for the task, got %v", err)
+	}
+}
+
+func TestSignUpInvitation(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	cfg.Auth.RegistrationMode = config.RegistrationInviteOnly
+
+	const token = "invitation-token"
+
+	newRepo := func() *fakeRepo {
+		repo := newFakeRepo()
+		repo.invitations[security.HashToken(token)] = &Invitation{ID: "inv-1", Email: "Invited@Example.com", Role: "editor"}
+		return repo
+	}
+
+	params := SignUpParams{
+		Email:                "invited@example.com",
+		Password:             testPassword,
+		PasswordConfirmation: testPassword,
+		Invitation:           token,
+	}
+
+	t.Run("requires an invitation", func(t *testing.T) {
+		svc, _, _ := newTestService(cfg, newRepo())
+
+		noInvitation := params
+		noInvitation.Invitation = ""
+
+		if _, err := svc.SignUp(ctx, noInvitation); !errors.Is(err, ErrInvitationRequired) {
+			t.Errorf("expected ErrInvitationRequired, got %v", err)
+		}
+	})
+
+	t.Run("rejects an unknown invitation", func(t *testing.T) {
+		svc, _, _ := newTestService(cfg, newRepo())
+
+		unknown := params
+		unknown.Invitation = "unknown"
+
+		if _, err := svc.SignUp(ctx, unknown); !errors.Is(err, ErrInvalidInvitation) {
+			t.Errorf("expected ErrInvalidInvitation, got %v", err)
+		}
+	})
+
+	t.Run("rejects another email", func(t *testing.T) {
+		repo := newRepo()
+		svc, _, _ := newTestService(cfg, repo)
+
+		other := params
+		other.Email = "someone@example.com"
+
+		_, err := svc.SignUp(ctx, other)
+
+		var inputErr *validation.Error
+		if !errors.As(err, &inputErr) || len(inputErr.Errors["email"]) == 0 {
+			t.Fatalf("expected an email field error, got %v", err)
+		}
+
+		if len(repo.invited) != 0 || len(repo.invitations) != 1 {
+			t.Errorf("expected the invitation to be left alone, got %d sign ups", len(repo.invited))
+		}
+	})
+
+	t.Run("consumes the invitation", func(t *testing.T) {
+		repo := newRepo()
+		svc, _, auditLog := newTestService(cfg, repo)
+
+		u, err := svc.SignUp(ctx, params)
+		if err != nil {
+			t.Fatalf("sign up: %v", err)
+		}
+
+		if u.Email != "Invited@Example.com" || len(repo.invited) != 1 {
+			t.Fatalf("expected the user to sign up with the email of the invitation, got %q", u.Email)
+		}
+
+		if repo.invited[0].Password == testPassword {
+			t.Error("expected the password to be hashed")
+		}
+
+		if len(repo.invitations) != 0 {
+			t.Error("expected the invitation to be consumed")
+		}
+
+		if _, err := svc.SignUp(ctx, params); !errors.Is(err, ErrInvalidInvitation) {
+			t.Errorf("expected the invitation to work once, got %v", err)
+		}
+
+		if actions := auditLog.actions(); len(actions) != 1 || actions[0] != actionSignUp {
+			t.Errorf("expected the sign up to be recorded, got %v", actions)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/client"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+const profilePath = "/profile"
+
+var ErrSessionsNotRevocable = errors.New("sessions cannot be signed out on this server, they end when they expire")
+
+// Revokes the sessions of the user except the one with the given id, pass an empty id to revoke all of them.
+// Reports false when the store cannot revoke sessions, like the cookie store, whose sessions then last until they expire.
+func revokeUserSessions(ctx context.Context, sessMgr session.Manager, userID, exceptSessionID string) (bool, error) {
+	err := sessMgr.RevokeUserSessions(ctx, userID, exceptSessionID)
+
+	if errors.Is(err, errors.ErrUnsupported) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ActiveSession is an active session of the signed in user.
+type ActiveSession struct {
+	session.Info
+	Device  string `json:"device"`
+	Current bool   `json:"current"`
+}
+
+// Lists the active sessions of the signed in user, marking the session of the request.
+func (h *Handler) activeSessions(r *http.Request, userID string) ([]ActiveSession, error) {
+	sessions, err := h.sessionManager.ListSessions(r.Context(), userID)
+
+	if err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			return []ActiveSession{}, nil
+		}
+
+		return nil, err
+	}
+
+	var currentHandle string
+
+	if sessionID, err := h.sessionManager.ExtractSessionID(r); err == nil {
+		currentHandle = session.Handle(sessionID)
+	}
+
+	active := make([]ActiveSession, 0, len(sessions))
+
+	for _, s := range sessions {
+		active = append(active, ActiveSession{
+			Info:    s,
+			Device:  client.Device(s.UserAgent),
+			Current: s.Handle == currentHandle,
+		})
+	}
+
+	return active, nil
+}
+
+func (h *Handler) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	sessions, err := h.activeSessions(r, userID)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	res := &response.APIResponse[[]ActiveSession]{
+		Data: &sessions,
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Revokes a single session of the signed in user
+func (h *Handler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	handle := r.PathValue("id")
+
+	revoked, err := h.sessionManager.RevokeSession(r.Context(), userID, handle)
+
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			response.RenderError(w, r, errtypes.NotFoundError(err))
+			return
+		}
+
+		if errors.Is(err, errors.ErrUnsupported) {
+			response.RenderError(w, r, errtypes.InvalidRequestError(ErrSessionsNotRevocable))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	// Otherwise the browser of the session would be signed back in by its remember me token
+	h.forgetSession(r, revoked)
+
+	h.record(r, actionSessionRevoked, userID, "", map[string]any{"session": handle})
+
+	if sessionID, err := h.sessionManager.ExtractSessionID(r); err == nil && session.Handle(sessionID) == handle {
+		h.forgetBrowser(w, r)
+		clearCookie(w, h.config.Session.SessionName, true)
+		clearCookie(w, h.config.Session.CSRFName, false)
+	}
+
+	h.renderSessionsRevoked(w, r, "Session revoked.")
+}
+
+// Revokes all the sessions of the signed in user except the current one
+func (h *Handler) HandleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	sessionID, err := h.sessionManager.ExtractSessionID(r)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	revoked, err := revokeUserSessions(r.Context(), h.sessionManager, userID, sessionID)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if !revoked {
+		response.RenderError(w, r, errtypes.InvalidRequestError(ErrSessionsNotRevocable))
+		return
+	}
+
+	h.forgetUser(w, r, userID, true)
+
+	h.record(r, actionSessionRevoked, userID, "", map[string]any{"session": "others"})
+
+	h.renderSessionsRevoked(w, r, "Other sessions revoked.")
+}
+
+func (h *Handler) renderSessionsRevoked(w http.ResponseWriter, r *http.Request, msg string) {
+	if r.Header.Get("content-type") != "application/json" {
+		http.Redirect(w, r, profilePath, http.StatusSeeOther)
+		return
+	}
+
+	res := &response.APIResponse[any]{
+		Message: msg,
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
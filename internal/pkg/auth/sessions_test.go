@@ -0,0 +1,130 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+func TestHandleRevokeSession(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	repo := newFakeRepo()
+	svc, _, _ := newTestService(cfg, repo)
+	sessMgr := session.NewMemorySession(cfg.Session)
+	h, auditLog := newTestHandler(cfg, svc, sessMgr)
+
+	// Opens a session of the test user with a remember me token, as a sign in with remember set does
+	remembered := func(sessionID string) *RememberToken {
+		t.Helper()
+
+		token, _, err := svc.CreateRememberToken(ctx, testUserID)
+		if err != nil {
+			t.Fatalf("create remember token: %v", err)
+		}
+
+		data := session.Data{UserID: testUserID}
+
+		if err := session.Set(&data, rememberFamilyKey, token.FamilyID); err != nil {
+			t.Fatalf("set remember family: %v", err)
+		}
+
+		if _, err := sessMgr.StoreSession(ctx, sessionID, data); err != nil {
+			t.Fatalf("store session: %v", err)
+		}
+
+		return token
+	}
+
+	revoked := remembered("lost-laptop")
+	kept := remembered("phone")
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/sessions/"+session.Handle("lost-laptop"), nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", session.Handle("lost-laptop"))
+	req.AddCookie(&http.Cookie{Name: cfg.Session.SessionName, Value: "phone"})
+	req = req.WithContext(WithPrincipal(req.Context(), &Principal{UserID: testUserID}))
+
+	rec := httptest.NewRecorder()
+	h.HandleRevokeSession(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if n := repo.liveRememberTokens(revoked.FamilyID); n != 0 {
+		t.Errorf("expected the family of the revoked session to be revoked, got %d live tokens", n)
+	}
+
+	if n := repo.liveRememberTokens(kept.FamilyID); n != 1 {
+		t.Errorf("expected the family of the other session to be kept, got %d live tokens", n)
+	}
+
+	lost := httptest.NewRequest(http.MethodGet, "/", nil)
+	lost.AddCookie(&http.Cookie{Name: cfg.Session.SessionName, Value: "lost-laptop"})
+
+	if _, err := sessMgr.LoadSession(lost); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("expected the session to be revoked, got %v", err)
+	}
+
+	if actions := auditLog.actions(); len(actions) != 1 || actions[0] != actionSessionRevoked {
+		t.Errorf("expected the revocation to be recorded, got %v", actions)
+	}
+}
+
+func TestSessionsWithCookieStore(t *testing.T) {
+	cfg := testConfig()
+	cfg.Session.Key = base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	sessMgr, err := session.NewCookieSession(cfg.Session)
+	if err != nil {
+		t.Fatalf("new cookie session: %v", err)
+	}
+
+	svc, _, _ := newTestService(cfg, newFakeRepo())
+	h, _ := newTestHandler(cfg, svc, sessMgr)
+
+	serve := func(handler http.HandlerFunc, method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		req.SetPathValue("id", session.Handle("other"))
+		req.AddCookie(&http.Cookie{Name: cfg.Session.SessionName, Value: "sealed"})
+		req = req.WithContext(WithUser(req.Context(), testUserID))
+
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		return rec
+	}
+
+	t.Run("lists no sessions", func(t *testing.T) {
+		rec := serve(h.HandleListSessions, http.MethodGet, "/api/sessions")
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("refuses to revoke a session", func(t *testing.T) {
+		rec := serve(h.HandleRevokeSession, http.MethodDelete, "/api/sessions/"+session.Handle("other"))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("refuses to revoke the other sessions", func(t *testing.T) {
+		rec := serve(h.HandleRevokeOtherSessions, http.MethodDelete, "/api/sessions")
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
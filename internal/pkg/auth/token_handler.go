@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+	"github.com/ferdiebergado/gopherkit/http/request"
+)
+
+type accessTokenData struct {
+	response.PageData
+	Token *NewAccessToken
+}
+
+// Creates a personal access token for the signed in user and shows it once
+func (h *Handler) HandleCreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	// A leaked token should not be able to mint more tokens
+	if _, ok := AccessTokenFromContext(r.Context()); ok {
+		response.RenderError(w, r, errtypes.ForbiddenError(ErrAccessTokenRequest))
+		return
+	}
+
+	isJSON := r.Header.Get("content-type") == "application/json"
+
+	var params CreateAccessTokenParams
+
+	if isJSON {
+		params, err = request.JSON[CreateAccessTokenParams](r)
+
+		if err != nil {
+			response.RenderError(w, r, errtypes.BadRequest(err))
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			response.RenderError(w, r, errtypes.BadRequest(err))
+			return
+		}
+
+		params.Name = r.PostForm.Get("name")
+		params.Scopes = r.PostForm["scopes"]
+		params.ExpiresIn, _ = strconv.Atoi(r.PostForm.Get("expires_in"))
+	}
+
+	token, err := h.service.CreateAccessToken(r.Context(), userID, params)
+
+	if err != nil {
+		var inputErr *validation.Error
+		if errors.As(err, &inputErr) {
+			if !isJSON {
+				http.Redirect(w, r, profilePath+"?status=token-invalid", http.StatusSeeOther)
+				return
+			}
+
+			response.RenderError(w, r, errtypes.ValidationError(*inputErr))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	if !isJSON {
+		data := &accessTokenData{
+			PageData: response.PageData{Title: "Access Token"},
+			Token:    token,
+		}
+
+		h.htmlTemplate.Render(w, "access-token.html", data)
+		return
+	}
+
+	res := &response.APIResponse[NewAccessToken]{
+		Message: "Access token created. Copy it now, it will not be shown again.",
+		Data:    token,
+	}
+
+	response.RenderJSON(w, http.StatusCreated, res)
+}
+
+// Lists the personal access tokens of the signed in user
+func (h *Handler) HandleListAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	tokens, err := h.service.AccessTokens(r.Context(), userID)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	res := &response.APIResponse[[]AccessToken]{
+		Data: &tokens,
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Revokes a personal access token of the signed in user
+func (h *Handler) HandleRevokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	id := r.PathValue("id")
+
+	// A mangled id would otherwise fail the query instead of matching no token
+	if !validation.IsUUID(id) {
+		response.RenderError(w, r, errtypes.NotFoundError(ErrAccessTokenNotFound))
+		return
+	}
+
+	if err := h.service.RevokeAccessToken(r.Context(), userID, id); err != nil {
+		if errors.Is(err, ErrAccessTokenNotFound) {
+			response.RenderError(w, r, errtypes.NotFoundError(err))
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	if r.Header.Get("content-type") != "application/json" {
+		http.Redirect(w, r, profilePath+"?status=token-revoked", http.StatusSeeOther)
+		return
+	}
+
+	response.RenderJSON(w, http.StatusOK, &response.APIResponse[any]{Message: "Access token revoked."})
+}
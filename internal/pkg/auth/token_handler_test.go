@@ -0,0 +1,50 @@
+//go:build !integration
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+func TestHandleRevokeAccessToken(t *testing.T) {
+	cfg := testConfig()
+	repo := newFakeRepo()
+	svc, _, _ := newTestService(cfg, repo)
+	h, _ := newTestHandler(cfg, svc, session.NewMemorySession(cfg.Session))
+
+	token := createTestAccessToken(t, svc, ScopeRead)
+
+	tests := []struct {
+		name     string
+		id       string
+		expected int
+	}{
+		{"Should reject a malformed id", "1 OR 1=1", http.StatusNotFound},
+		{"Should revoke the token", token.ID, http.StatusOK},
+		{"Should not find a revoked token", token.ID, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/tokens/x", nil)
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("id", tt.id)
+			req = req.WithContext(WithUser(req.Context(), testUserID))
+
+			rec := httptest.NewRecorder()
+			h.HandleRevokeAccessToken(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+
+	if len(repo.accessTokens) != 0 {
+		t.Errorf("expected the token to be deleted, got %v", repo.accessTokens)
+	}
+}
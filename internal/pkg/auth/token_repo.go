@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AccessTokenStore interface {
+	SaveAccessToken(ctx context.Context, token *AccessToken, hash string) error
+	FindAccessToken(ctx context.Context, hash string) (*AccessToken, error)
+	ListAccessTokens(ctx context.Context, userID string) ([]AccessToken, error)
+	TouchAccessToken(ctx context.Context, id string) error
+	DeleteAccessToken(ctx context.Context, userID, id string) (bool, error)
+}
+
+// AccessToken is a personal access token that API clients send as a bearer token.
+// Only the hash of the token is stored.
+type AccessToken struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+const saveAccessTokenQuery = `
+INSERT INTO personal_access_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, created_at
+`
+
+func (r *repo) SaveAccessToken(ctx context.Context, token *AccessToken, hash string) error {
+	row := r.db.QueryRowContext(ctx, saveAccessTokenQuery, token.UserID, token.Name, hash, token.Prefix,
+		strings.Join(token.Scopes, ","), token.ExpiresAt)
+
+	if err := row.Scan(&token.ID, &token.CreatedAt); err != nil {
+		return fmt.Errorf("save access token: %w", err)
+	}
+
+	return nil
+}
+
+const accessTokenColumns = "id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at"
+
+func scanAccessToken(row rowScanner) (*AccessToken, error) {
+	var (
+		t      AccessToken
+		scopes string
+	)
+
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+
+	return &t, nil
+}
+
+// Finds the token with the given hash, only when its user is not deleted
+func (r *repo) FindAccessToken(ctx context.Context, hash string) (*AccessToken, error) {
+	const q = "SELECT " + accessTokenColumns + " FROM personal_access_tokens WHERE token_hash = $1 " +
+		"AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)"
+
+	return scanAccessToken(r.db.QueryRowContext(ctx, q, hash))
+}
+
+func (r *repo) ListAccessTokens(ctx context.Context, userID string) ([]AccessToken, error) {
+	const q = "SELECT " + accessTokenColumns + " FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at"
+
+	rows, err := r.db.QueryContext(ctx, q, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("list access tokens: %w", err)
+	}
+
+	defer rows.Close()
+
+	var tokens []AccessToken
+
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+
+		if err != nil {
+			return nil, fmt.Errorf("scan access token: %w", err)
+		}
+
+		tokens = append(tokens, *t)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Records that the token was used. Writes at most once a minute per token, since clients may send many requests.
+func (r *repo) TouchAccessToken(ctx context.Context, id string) error {
+	const q = "UPDATE personal_access_tokens SET last_used_at = NOW() " +
+		"WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')"
+
+	if _, err := r.db.ExecContext(ctx, q, id); err != nil {
+		return fmt.Errorf("touch access token: %w", err)
+	}
+
+	return nil
+}
+
+// Deletes a token of the user. Reports false when the user has no such token.
+func (r *repo) DeleteAccessToken(ctx context.Context, userID, id string) (bool, error) {
+	const q = "DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2"
+
+	res, err := r.db.ExecContext(ctx, q, id, userID)
+
+	if err != nil {
+		return false, fmt.Errorf("delete access token: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("delete access token: %w", err)
+	}
+
+	return n == 1, nil
+}
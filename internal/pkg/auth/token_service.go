@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+// Scopes of personal access tokens
+const (
+	ScopeRead  = "read"  // Safe requests like GET
+	ScopeWrite = "write" // Requests that change something like POST and DELETE
+)
+
+var AccessTokenScopes = []string{ScopeRead, ScopeWrite}
+
+var (
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrAccessTokenInvalid  = errors.New("invalid or expired access token")
+	ErrInsufficientScope   = errors.New("the access token does not allow this request")
+	ErrAccessTokenRequest  = errors.New("access tokens cannot manage access tokens")
+)
+
+const (
+	// Marks the tokens of the app, so that leaked ones are easy to find by secret scanners
+	accessTokenPrefix = "gfb_"
+
+	// Length of the start of a token that is kept to tell tokens apart
+	accessTokenPrefixLength = 12
+
+	accessTokenNameLength = 100
+	accessTokenMaxDays    = 365
+)
+
+type CreateAccessTokenParams struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expires_in"` // In days, 0 for a token that does not expire
+}
+
+// NewAccessToken is a token that was just created. Token is shown once and never stored.
+type NewAccessToken struct {
+	AccessToken
+	Token string `json:"token"`
+}
+
+// Reports whether the token was granted the scope
+func (t *AccessToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Creates a personal access token for the user
+func (s *service) CreateAccessToken(ctx context.Context, userID string, params CreateAccessTokenParams) (*NewAccessToken, error) {
+	form := validation.NewForm(params)
+	form.Required("Name")
+
+	params.Name = strings.TrimSpace(params.Name)
+
+	if len(params.Name) > accessTokenNameLength {
+		form.Error.Add("name", fmt.Sprintf("Name must be at most %d characters.", accessTokenNameLength))
+	}
+
+	if len(params.Scopes) == 0 {
+		form.Error.Add("scopes", "Choose at least one scope.")
+	}
+
+	for _, scope := range params.Scopes {
+		if !slices.Contains(AccessTokenScopes, scope) {
+			form.Error.Add("scopes", fmt.Sprintf("Unknown scope %q.", scope))
+		}
+	}
+
+	if params.ExpiresIn < 0 || params.ExpiresIn > accessTokenMaxDays {
+		form.Error.Add("expires_in", fmt.Sprintf("Expiration must be between 0 and %d days.", accessTokenMaxDays))
+	}
+
+	if !form.IsValid() {
+		return nil, &validation.Error{
+			Errors: form.Error.Errors,
+		}
+	}
+
+	secret, err := security.GenerateRandomBytes(32)
+
+	if err != nil {
+		return nil, fmt.Errorf("generate access token: %w", err)
+	}
+
+	token := accessTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	t := AccessToken{
+		UserID: userID,
+		Name:   params.Name,
+		Prefix: token[:accessTokenPrefixLength],
+		Scopes: compactScopes(params.Scopes),
+	}
+
+	if params.ExpiresIn > 0 {
+		expiresAt := time.Now().AddDate(0, 0, params.ExpiresIn)
+		t.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.SaveAccessToken(ctx, &t, security.HashToken(token)); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actionTokenCreated, userID, "", map[string]any{"token_id": t.ID, "name": t.Name, "scopes": t.Scopes})
+
+	return &NewAccessToken{AccessToken: t, Token: token}, nil
+}
+
+// Returns the scopes in the order of AccessTokenScopes without duplicates
+func compactScopes(scopes []string) []string {
+	var compact []string
+
+	for _, scope := range AccessTokenScopes {
+		if slices.Contains(scopes, scope) {
+			compact = append(compact, scope)
+		}
+	}
+
+	return compact
+}
+
+// Finds the unexpired token and records its use
+func (s *service) AuthenticateAccessToken(ctx context.Context, token string) (*AccessToken, error) {
+	if !strings.HasPrefix(token, accessTokenPrefix) {
+		return nil, ErrAccessTokenInvalid
+	}
+
+	t, err := s.repo.FindAccessToken(ctx, security.HashToken(token))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAccessTokenInvalid
+		}
+
+		return nil, fmt.Errorf("find access token: %w", err)
+	}
+
+	if t.ExpiresAt != nil && !time.Now().Before(*t.ExpiresAt) {
+		return nil, ErrAccessTokenInvalid
+	}
+
+	// The request is authenticated either way, a missed timestamp is not worth failing it
+	if err := s.repo.TouchAccessToken(ctx, t.ID); err != nil {
+		slog.Error("failed to record access token use", "token_id", t.ID, "error", err)
+	}
+
+	return t, nil
+}
+
+// Lists the access tokens of the user, oldest first.
+func (s *service) AccessTokens(ctx context.Context, userID string) ([]AccessToken, error) {
+	return s.repo.ListAccessTokens(ctx, userID)
+}
+
+func (s *service) RevokeAccessToken(ctx context.Context, userID, id string) error {
+	deleted, err := s.repo.DeleteAccessToken(ctx, userID, id)
+
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrAccessTokenNotFound
+	}
+
+	s.record(ctx, actionTokenRevoked, userID, "", map[string]any{"token_id": id})
+
+	return nil
+}
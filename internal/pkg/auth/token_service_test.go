@@ -0,0 +1,119 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+// Creates a token of the test user with the scopes
+func createTestAccessToken(t *testing.T, svc *service, scopes ...string) *NewAccessToken {
+	t.Helper()
+
+	token, err := svc.CreateAccessToken(context.Background(), testUserID, CreateAccessTokenParams{Name: "ci", Scopes: scopes})
+	if err != nil {
+		t.Fatalf("create access token: %v", err)
+	}
+
+	return token
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	repo := newFakeRepo()
+	svc, _, _ := newTestService(testConfig(), repo)
+
+	token := createTestAccessToken(t, svc, ScopeWrite, ScopeRead, ScopeRead)
+
+	if !strings.HasPrefix(token.Token, accessTokenPrefix) {
+		t.Errorf("expected the token to start with %q, got %q", accessTokenPrefix, token.Token)
+	}
+
+	if len(token.Scopes) != 2 || token.Scopes[0] != ScopeRead || token.Scopes[1] != ScopeWrite {
+		t.Errorf("expected the scopes [read write], got %v", token.Scopes)
+	}
+
+	stored, ok := repo.accessTokens[security.HashToken(token.Token)]
+	if !ok || len(repo.accessTokens) != 1 {
+		t.Fatalf("expected only the hash of the token to be stored, got %v", repo.accessTokens)
+	}
+
+	for _, field := range []string{stored.ID, stored.Name, stored.Prefix} {
+		if strings.Contains(field, token.Token) {
+			t.Errorf("expected the token not to be stored, found it in %q", field)
+		}
+	}
+}
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	repo := newFakeRepo()
+	svc, _, _ := newTestService(testConfig(), repo)
+
+	valid := createTestAccessToken(t, svc, ScopeRead)
+	expired := createTestAccessToken(t, svc, ScopeRead)
+
+	past := time.Now().Add(-time.Minute)
+	repo.accessTokens[security.HashToken(expired.Token)].ExpiresAt = &past
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"Should authenticate a valid token", valid.Token, nil},
+		{"Should reject an expired token", expired.Token, ErrAccessTokenInvalid},
+		{"Should reject an unknown token", accessTokenPrefix + "unknown", ErrAccessTokenInvalid},
+		{"Should reject a token without the prefix", strings.TrimPrefix(valid.Token, accessTokenPrefix), ErrAccessTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := svc.AuthenticateAccessToken(context.Background(), tt.token)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err == nil && (found.ID != valid.ID || found.UserID != testUserID) {
+				t.Errorf("expected the token %s of %s, got %+v", valid.ID, testUserID, found)
+			}
+		})
+	}
+
+	if repo.accessTokens[security.HashToken(valid.Token)].LastUsedAt == nil {
+		t.Error("expected the use of the token to be recorded")
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	repo := newFakeRepo()
+	svc, _, auditLog := newTestService(testConfig(), repo)
+	ctx := context.Background()
+
+	token := createTestAccessToken(t, svc, ScopeRead)
+
+	if err := svc.RevokeAccessToken(ctx, "another-user", token.ID); !errors.Is(err, ErrAccessTokenNotFound) {
+		t.Errorf("expected the token of another user not to be found, got %v", err)
+	}
+
+	if err := svc.RevokeAccessToken(ctx, testUserID, token.ID); err != nil {
+		t.Fatalf("revoke access token: %v", err)
+	}
+
+	if _, err := svc.AuthenticateAccessToken(ctx, token.Token); !errors.Is(err, ErrAccessTokenInvalid) {
+		t.Errorf("expected the revoked token to be rejected, got %v", err)
+	}
+
+	if err := svc.RevokeAccessToken(ctx, testUserID, token.ID); !errors.Is(err, ErrAccessTokenNotFound) {
+		t.Errorf("expected %v when revoking twice, got %v", ErrAccessTokenNotFound, err)
+	}
+
+	if actions := auditLog.actions(); len(actions) != 2 || actions[1] != actionTokenRevoked {
+		t.Errorf("expected the revocation to be recorded, got %v", actions)
+	}
+}
@@ -0,0 +1,395 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/middleware"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/lockout"
+	"github.com/ferdiebergado/gopherkit/http/request"
+)
+
+const (
+	twoFactorPath       = "/signin/2fa"
+	twoFactorSetupPath  = profilePath + "/2fa/setup"
+	pendingSecondFactor = "pending_second_factor"
+	secondFactorTTL     = 5 * time.Minute
+	secondFactorTries   = 5
+)
+
+// A user who passed the first factor and still has to enter a second one
+type pendingSignIn struct {
+	UserID    string    `json:"user_id"`
+	Method    string    `json:"method"` // How the first factor was passed
+	Remember  bool      `json:"remember"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Attempts  int       `json:"attempts"`
+}
+
+type TwoFactorParams struct {
+	Code string `json:"code"`
+}
+
+// Signs in the user who passed the first factor.
+//
+// When the user has two-factor authentication enabled, the session is only marked as pending
+// the second factor and the url of the second step is returned.
+// The method is how the user passed the first factor, e.g. signInPassword.
+// When remember is set, the browser is kept signed in once the user is, see rememberUser.
+func (h *Handler) completeSignIn(w http.ResponseWriter, r *http.Request, data session.Data, userID, method string, remember bool) (string, error) {
+	enabled, err := h.service.TwoFactorEnabled(r.Context(), userID)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !enabled {
+		redirectURL, err := h.startSessionWithData(w, r, data, userID, remember)
+
+		if err != nil {
+			return "", err
+		}
+
+		h.record(r, actionSignIn, userID, "", map[string]any{"method": method, "remember": remember})
+
+		return redirectURL, nil
+	}
+
+	// Like the authenticated session, the pending one only keeps the intended url of the session of the request
+	pendingData := session.Data{}
+
+	if intendedURL := data.Flash["intendedUrl"]; intendedURL != "" {
+		pendingData.Flash = map[string]string{"intendedUrl": intendedURL}
+	}
+
+	pending := pendingSignIn{
+		UserID:    userID,
+		Method:    method,
+		Remember:  remember,
+		ExpiresAt: time.Now().Add(secondFactorTTL),
+	}
+
+	if err := session.Set(&pendingData, pendingSecondFactor, pending); err != nil {
+		return "", err
+	}
+
+	oldSessionID, err := h.sessionManager.ExtractSessionID(r)
+
+	if err != nil {
+		return "", err
+	}
+
+	// Regenerated as well, so that a planted session id never reaches the pending state
+	sid, err := h.sessionManager.Regenerate(r.Context(), oldSessionID, pendingData)
+
+	if err != nil {
+		return "", err
+	}
+
+	setSessionCookie(w, h.config.Session, sid)
+
+	return twoFactorPath, nil
+}
+
+type twoFactorData struct {
+	response.PageData
+	Error string
+}
+
+// Renders the second step of the sign in
+func (h *Handler) HandleTwoFactorForm(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := pendingFromContext(r); !ok {
+		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+		return
+	}
+
+	h.renderTwoFactorForm(w, r, "")
+}
+
+// Upgrades the pending session to a signed in session when the code is valid
+func (h *Handler) HandleTwoFactorSignIn(w http.ResponseWriter, r *http.Request) {
+	isJSON := r.Header.Get("content-type") == "application/json"
+
+	var code string
+
+	if isJSON {
+		params, err := request.JSON[TwoFactorParams](r)
+
+		if err != nil {
+			response.RenderError(w, r, errtypes.BadRequest(err))
+			return
+		}
+
+		code = params.Code
+	} else {
+		code = r.PostFormValue("code")
+	}
+
+	data, pending, ok := pendingFromContext(r)
+
+	if !ok {
+		if isJSON {
+			response.RenderError(w, r, errtypes.AuthenticationError(errors.New("sign in again to continue")))
+			return
+		}
+
+		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+		return
+	}
+
+	u, err := h.service.FindUser(r.Context(), pending.UserID)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	// The codes are guessed like passwords, so they share the lockout of the account
+	if err := h.lockout.check(r.Context(), u.Email); err != nil {
+		if isJSON || !errors.Is(err, lockout.ErrLocked) {
+			renderLockoutError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusTooManyRequests)
+		h.renderTwoFactorForm(w, r, err.Error())
+		return
+	}
+
+	if err := h.service.VerifySecondFactor(r.Context(), pending.UserID, code); err != nil {
+		if !errors.Is(err, ErrInvalidSecondFactor) {
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		h.failSignIn(r, u.Email)
+		h.failSecondFactor(w, r, data, pending, err)
+		return
+	}
+
+	session.Delete(&data, pendingSecondFactor)
+
+	redirectURL, err := h.startSessionWithData(w, r, data, pending.UserID, pending.Remember)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	h.record(r, actionSignIn, pending.UserID, "", map[string]any{"method": pending.Method, "second_factor": true, "remember": pending.Remember})
+
+	if !isJSON {
+		http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+		return
+	}
+
+	res := &response.APIResponse[map[string]string]{
+		Message: "Logged in.",
+		Data: &map[string]string{
+			"redirectUrl": redirectURL,
+		},
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
+
+// Counts the failed attempt, dropping the pending sign in after too many of them
+func (h *Handler) failSecondFactor(w http.ResponseWriter, r *http.Request, data session.Data, pending *pendingSignIn, err error) {
+	pending.Attempts++
+
+	h.record(r, actionSignInFailed, "", pending.UserID, map[string]any{"reason": "invalid_second_factor", "attempts": pending.Attempts})
+
+	if pending.Attempts >= secondFactorTries {
+		session.Delete(&data, pendingSecondFactor)
+	} else if setErr := session.Set(&data, pendingSecondFactor, pending); setErr != nil {
+		response.RenderError(w, r, errtypes.ServerError(setErr))
+		return
+	}
+
+	sid, storeErr := h.storeSession(r, data)
+
+	if storeErr != nil {
+		response.RenderError(w, r, errtypes.ServerError(storeErr))
+		return
+	}
+
+	setSessionCookie(w, h.config.Session, sid)
+
+	if r.Header.Get("content-type") == "application/json" {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	if pending.Attempts >= secondFactorTries {
+		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+		return
+	}
+
+	w.WriteHeader(http.StatusUnauthorized)
+	h.renderTwoFactorForm(w, r, err.Error())
+}
+
+func (h *Handler) renderTwoFactorForm(w http.ResponseWriter, r *http.Request, errMsg string) {
+	data := &twoFactorData{
+		PageData: response.PageData{
+			Title:     "Two-Factor Authentication",
+			CSRFToken: middleware.CSRFToken(r.Context()),
+		},
+		Error: errMsg,
+	}
+
+	h.htmlTemplate.Render(w, "signin-2fa.html", data)
+}
+
+// Returns a copy of the session data of the request and its pending sign in, if any
+func pendingFromContext(r *http.Request) (session.Data, *pendingSignIn, bool) {
+	data, ok := session.FromContext(r.Context())
+
+	if !ok {
+		return session.Data{}, nil, false
+	}
+
+	pending, ok, err := session.Get[pendingSignIn](data, pendingSecondFactor)
+
+	if err != nil || !ok || time.Now().After(pending.ExpiresAt) {
+		return session.Data{}, nil, false
+	}
+
+	return *data, &pending, true
+}
+
+type twoFactorSetupData struct {
+	response.PageData
+	Secret string
+	URI    string
+	Error  string
+}
+
+type recoveryCodesData struct {
+	response.PageData
+	Codes []string
+}
+
+// Starts the enrollment of the signed in user
+func (h *Handler) HandleTwoFactorSetupStart(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	if err := h.service.BeginTOTPEnrollment(r.Context(), userID); err != nil {
+		if errors.Is(err, ErrTwoFactorEnabled) || errors.Is(err, ErrTwoFactorUnavailable) {
+			http.Redirect(w, r, profilePath, http.StatusSeeOther)
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	http.Redirect(w, r, twoFactorSetupPath, http.StatusSeeOther)
+}
+
+// Shows the secret of the pending enrollment to enter in an authenticator app
+func (h *Handler) HandleTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	h.renderTwoFactorSetup(w, r, "")
+}
+
+// Enables two-factor authentication and shows the recovery codes once
+func (h *Handler) HandleTwoFactorEnable(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	codes, err := h.service.EnableTOTP(r.Context(), userID, r.PostFormValue("code"))
+
+	if err != nil {
+		if errors.Is(err, ErrInvalidSecondFactor) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			h.renderTwoFactorSetup(w, r, err.Error())
+			return
+		}
+
+		if errors.Is(err, ErrTwoFactorEnabled) || errors.Is(err, ErrTwoFactorNotPending) {
+			http.Redirect(w, r, profilePath, http.StatusSeeOther)
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	data := &recoveryCodesData{
+		PageData: response.PageData{Title: "Recovery Codes"},
+		Codes:    codes,
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.htmlTemplate.Render(w, "recovery-codes.html", data)
+}
+
+// Disables two-factor authentication after checking a code
+func (h *Handler) HandleTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	status := "2fa-disabled"
+
+	if err := h.service.DisableTOTP(r.Context(), userID, r.PostFormValue("code")); err != nil {
+		if !errors.Is(err, ErrInvalidSecondFactor) && !errors.Is(err, ErrTwoFactorDisabled) {
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		status = "2fa-invalid"
+	}
+
+	http.Redirect(w, r, profilePath+"?status="+status, http.StatusSeeOther)
+}
+
+func (h *Handler) renderTwoFactorSetup(w http.ResponseWriter, r *http.Request, errMsg string) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	enrollment, err := h.service.TOTPEnrollment(r.Context(), userID)
+
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorEnabled) || errors.Is(err, ErrTwoFactorNotPending) || errors.Is(err, ErrTwoFactorUnavailable) {
+			http.Redirect(w, r, profilePath, http.StatusSeeOther)
+			return
+		}
+
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	data := &twoFactorSetupData{
+		PageData: response.PageData{
+			Title:     "Set Up Two-Factor Authentication",
+			CSRFToken: middleware.CSRFToken(r.Context()),
+		},
+		Secret: enrollment.Secret,
+		URI:    enrollment.URI,
+		Error:  errMsg,
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.htmlTemplate.Render(w, "two-factor-setup.html", data)
+}
@@ -0,0 +1,159 @@
+//go:build !integration
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+const testRecoveryCode = "abcd-efgh-ijkl"
+
+// Serves the second step of the sign in of the test user, who passed the first one
+type twoFactorTest struct {
+	t         *testing.T
+	cfg       *config.Config
+	repo      *fakeRepo
+	sessMgr   session.Manager
+	auditLog  *fakeAuditLog
+	handler   http.Handler
+	sessionID string
+}
+
+func newTwoFactorTest(t *testing.T, cfg *config.Config) *twoFactorTest {
+	t.Helper()
+
+	repo := newFakeRepo()
+	repo.recoveryCodes[security.HashToken(normalizeRecoveryCode(testRecoveryCode))] = true
+
+	svc, _, _ := newTestService(cfg, repo)
+	sessMgr := session.NewMemorySession(cfg.Session)
+	h, auditLog := newTestHandler(cfg, svc, sessMgr)
+
+	data := session.Data{}
+	pending := pendingSignIn{UserID: testUserID, Method: signInPassword, ExpiresAt: time.Now().Add(secondFactorTTL)}
+
+	if err := session.Set(&data, pendingSecondFactor, pending); err != nil {
+		t.Fatalf("set pending sign in: %v", err)
+	}
+
+	sessionID, err := sessMgr.StoreSession(context.Background(), "pending-session", data)
+	if err != nil {
+		t.Fatalf("store session: %v", err)
+	}
+
+	return &twoFactorTest{
+		t:         t,
+		cfg:       cfg,
+		repo:      repo,
+		sessMgr:   sessMgr,
+		auditLog:  auditLog,
+		handler:   SessionMiddleware(cfg.Session, sessMgr, svc)(http.HandlerFunc(h.HandleTwoFactorSignIn)),
+		sessionID: sessionID,
+	}
+}
+
+// Returns a request of the browser of the pending sign in
+func (tt *twoFactorTest) request(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, twoFactorPath, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: tt.cfg.Session.SessionName, Value: tt.sessionID})
+
+	return req
+}
+
+// Submits the code and returns the status of the response
+func (tt *twoFactorTest) submit(code string) int {
+	rec := httptest.NewRecorder()
+	tt.handler.ServeHTTP(rec, tt.request(`{"code":"`+code+`"}`))
+
+	return rec.Code
+}
+
+// Reports whether the session still waits for the second factor
+func (tt *twoFactorTest) pending() bool {
+	data, err := tt.sessMgr.LoadSession(tt.request(""))
+	if err != nil {
+		tt.t.Fatalf("load session: %v", err)
+	}
+
+	_, ok, err := session.Get[pendingSignIn](data, pendingSecondFactor)
+	if err != nil {
+		tt.t.Fatalf("get pending sign in: %v", err)
+	}
+
+	return ok
+}
+
+func TestHandleTwoFactorSignIn(t *testing.T) {
+	t.Run("signs in with a valid code", func(t *testing.T) {
+		tt := newTwoFactorTest(t, testConfig())
+
+		if status := tt.submit(testRecoveryCode); status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+		}
+
+		if len(tt.repo.recoveryCodes) != 0 {
+			t.Error("expected the recovery code to be used")
+		}
+	})
+
+	t.Run("drops the pending sign in after too many codes", func(t *testing.T) {
+		tt := newTwoFactorTest(t, testConfig())
+
+		for attempt := 1; attempt <= secondFactorTries; attempt++ {
+			if status := tt.submit("wrong-code"); status != http.StatusUnauthorized {
+				t.Fatalf("expected status %d on attempt %d, got %d", http.StatusUnauthorized, attempt, status)
+			}
+
+			if pending := tt.pending(); pending != (attempt < secondFactorTries) {
+				t.Fatalf("expected the sign in to be pending after attempt %d: %v", attempt, pending)
+			}
+		}
+
+		if status := tt.submit(testRecoveryCode); status != http.StatusUnauthorized {
+			t.Errorf("expected a valid code not to help once the sign in is dropped, got %d", status)
+		}
+
+		var failures int
+
+		for _, action := range tt.auditLog.actions() {
+			if action == actionSignInFailed {
+				failures++
+			}
+		}
+
+		if failures != secondFactorTries {
+			t.Errorf("expected %d failures to be recorded, got %d", secondFactorTries, failures)
+		}
+	})
+
+	t.Run("counts the codes against the lockout of the account", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Lockout.MaxAttempts = 2
+
+		tt := newTwoFactorTest(t, cfg)
+
+		for range cfg.Lockout.MaxAttempts {
+			if status := tt.submit("wrong-code"); status != http.StatusUnauthorized {
+				t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, status)
+			}
+		}
+
+		if status := tt.submit(testRecoveryCode); status != http.StatusTooManyRequests {
+			t.Errorf("expected the account to be locked, got %d", status)
+		}
+
+		if len(tt.repo.recoveryCodes) != 1 {
+			t.Error("expected the code not to be checked while the account is locked")
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+
+type TwoFactorStore interface {
+	SaveTOTPSecret(ctx context.Context, userID string, encryptedSecret []byte) error
+	FindTOTP(ctx context.Context, userID string) (*TOTPState, error)
+	EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+	DisableTOTP(ctx context.Context, userID string) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+}
+
+// TOTPState is the two-factor enrollment of a user.
+// Secret is nil when the user never started an enrollment.
+type TOTPState struct {
+	Secret    []byte
+	EnabledAt *time.Time
+}
+
+// Saves the secret of a pending enrollment, replacing a previous pending secret.
+func (r *repo) SaveTOTPSecret(ctx context.Context, userID string, encryptedSecret []byte) error {
+	const q = "UPDATE users SET totp_secret = $2, totp_last_step = NULL WHERE id = $1 AND totp_enabled_at IS NULL"
+
+	res, err := r.db.ExecContext(ctx, q, userID, encryptedSecret)
+
+	if err != nil {
+		return fmt.Errorf("save totp secret: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return fmt.Errorf("save totp secret: %w", err)
+	}
+
+	if n == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	return nil
+}
+
+func (r *repo) FindTOTP(ctx context.Context, userID string) (*TOTPState, error) {
+	const q = "SELECT totp_secret, totp_enabled_at FROM users WHERE id = $1"
+
+	var state TOTPState
+
+	if err := r.db.QueryRowContext(ctx, q, userID).Scan(&state.Secret, &state.EnabledAt); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// Enables two-factor authentication and replaces the recovery codes of the user in one transaction.
+func (r *repo) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("begin enable totp: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const q = "UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2 WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL"
+
+	res, err := tx.ExecContext(ctx, q, userID, step)
+
+	if err != nil {
+		return fmt.Errorf("enable totp: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrTwoFactorEnabled
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash); err != nil {
+			return fmt.Errorf("save recovery code: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Disables two-factor authentication and deletes the recovery codes of the user.
+func (r *repo) DisableTOTP(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("begin disable totp: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	const q = "UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1"
+
+	if _, err := tx.ExecContext(ctx, q, userID); err != nil {
+		return fmt.Errorf("disable totp: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Records the time step of an accepted code. Reports false when the step or a later one was already used,
+// so that an intercepted code cannot be replayed.
+func (r *repo) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	const q = "UPDATE users SET totp_last_step = $2 WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)"
+
+	res, err := r.db.ExecContext(ctx, q, userID, step)
+
+	if err != nil {
+		return false, fmt.Errorf("use totp step: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("use totp step: %w", err)
+	}
+
+	return n == 1, nil
+}
+
+// Marks the recovery code as used. Reports false when the code does not exist or was already used.
+func (r *repo) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	const q = "UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL"
+
+	res, err := r.db.ExecContext(ctx, q, userID, codeHash)
+
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+
+	return n == 1, nil
+}
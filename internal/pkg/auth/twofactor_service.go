@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+var (
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication is not available")
+	ErrTwoFactorNotPending  = errors.New("no two-factor enrollment in progress")
+	ErrTwoFactorDisabled    = errors.New("two-factor authentication is not enabled")
+	ErrInvalidSecondFactor  = errors.New("the authentication code is invalid")
+)
+
+// Number of recovery codes issued on enrollment
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment is what the user enters in an authenticator app to enroll.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// Returns the key encrypting the secrets at rest
+func (s *service) totpKey() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s.cfg.Auth.EncryptionKey)
+
+	if err != nil || len(key) != security.EncryptionKeyLength {
+		return nil, ErrTwoFactorUnavailable
+	}
+
+	return key, nil
+}
+
+// Reports whether two-factor authentication can be enabled with the current configuration.
+func (s *service) TwoFactorAvailable() bool {
+	_, err := s.totpKey()
+	return err == nil
+}
+
+// Reports whether the user signs in with a second factor.
+func (s *service) TwoFactorEnabled(ctx context.Context, userID string) (bool, error) {
+	state, err := s.repo.FindTOTP(ctx, userID)
+
+	if err != nil {
+		return false, fmt.Errorf("find totp: %w", err)
+	}
+
+	return state.EnabledAt != nil, nil
+}
+
+// Starts an enrollment by generating a new secret for the user.
+func (s *service) BeginTOTPEnrollment(ctx context.Context, userID string) error {
+	key, err := s.totpKey()
+
+	if err != nil {
+		return err
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+
+	if err != nil {
+		return fmt.Errorf("generate totp secret: %w", err)
+	}
+
+	encrypted, err := security.Encrypt(key, secret)
+
+	if err != nil {
+		return fmt.Errorf("encrypt totp secret: %w", err)
+	}
+
+	return s.repo.SaveTOTPSecret(ctx, userID, encrypted)
+}
+
+// Returns the secret of the pending enrollment of the user.
+func (s *service) TOTPEnrollment(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	secret, err := s.totpSecret(ctx, userID, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.repo.FindEmail(ctx, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("find email: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret: security.EncodeTOTPSecret(secret),
+		URI:    security.TOTPURI(s.cfg.Auth.TOTPIssuer, status.Email, secret),
+	}, nil
+}
+
+// Completes the enrollment once the user proves their app generates valid codes.
+// Returns the recovery codes, which are shown once and only stored hashed.
+func (s *service) EnableTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	secret, err := s.totpSecret(ctx, userID, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := security.VerifyTOTP(secret, code, time.Now())
+
+	if !ok {
+		return nil, ErrInvalidSecondFactor
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		code, err := generateRecoveryCode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = code
+		hashes[i] = security.HashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.repo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, actionTwoFactorEnabled, userID, "", nil)
+
+	return codes, nil
+}
+
+// Disables two-factor authentication after checking a code from the app or a recovery code.
+func (s *service) DisableTOTP(ctx context.Context, userID, code string) error {
+	if err := s.VerifySecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DisableTOTP(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, actionTwoFactorDisabled, userID, "", nil)
+
+	return nil
+}
+
+// Checks a code from the authenticator app of the user, or one of their recovery codes.
+// Each code is accepted only once.
+func (s *service) VerifySecondFactor(ctx context.Context, userID, code string) error {
+	code = strings.TrimSpace(code)
+
+	if len(code) != security.TOTPDigits {
+		used, err := s.repo.UseRecoveryCode(ctx, userID, security.HashToken(normalizeRecoveryCode(code)))
+
+		if err != nil {
+			return err
+		}
+
+		if !used {
+			return ErrInvalidSecondFactor
+		}
+
+		return nil
+	}
+
+	secret, err := s.totpSecret(ctx, userID, true)
+
+	if err != nil {
+		return err
+	}
+
+	step, ok := security.VerifyTOTP(secret, code, time.Now())
+
+	if !ok {
+		return ErrInvalidSecondFactor
+	}
+
+	used, err := s.repo.UseTOTPStep(ctx, userID, step)
+
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return ErrInvalidSecondFactor
+	}
+
+	return nil
+}
+
+// Decrypts the secret of the user, either of an enabled or of a pending enrollment
+func (s *service) totpSecret(ctx context.Context, userID string, enabled bool) ([]byte, error) {
+	key, err := s.totpKey()
+
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.repo.FindTOTP(ctx, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("find totp: %w", err)
+	}
+
+	switch {
+	case enabled && state.EnabledAt == nil:
+		return nil, ErrTwoFactorDisabled
+	case !enabled && state.EnabledAt != nil:
+		return nil, ErrTwoFactorEnabled
+	case state.Secret == nil:
+		return nil, ErrTwoFactorNotPending
+	}
+
+	secret, err := security.Decrypt(key, state.Secret)
+
+	if err != nil {
+		return nil, fmt.Errorf("decrypt totp secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+// Generates a recovery code like abcd-efgh-ijkl-mnop, 80 random bits
+func generateRecoveryCode() (string, error) {
+	b, err := security.GenerateRandomBytes(10)
+
+	if err != nil {
+		return "", fmt.Errorf("generate recovery code: %w", err)
+	}
+
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// Ignores the case, the dashes and the spaces that users may type differently
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/middleware"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+)
+
+const verifyEmailPath = "/verify-email"
+
+// Messages shown on the verification page, keyed by the status query parameter
+var verifyEmailMessages = map[string]string{
+	"sent":     "A new verification link has been sent to your email address.",
+	"too-soon": ErrVerificationResendTooSoon.Error(),
+	"verified": "Your email address has been verified.",
+	"invalid":  ErrInvalidVerificationLink.Error(),
+}
+
+type verifyEmailData struct {
+	response.PageData
+	Email    string
+	Verified bool
+	Message  string
+}
+
+// Renders the page asking the user to verify their email address
+func (h *Handler) HandleVerifyEmailNotice(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	status, err := h.service.EmailStatus(r.Context(), userID)
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.ServerError(err))
+		return
+	}
+
+	data := &verifyEmailData{
+		PageData: response.PageData{
+			Title:     "Verify Email",
+			CSRFToken: middleware.CSRFToken(r.Context()),
+		},
+		Email:    status.Email,
+		Verified: status.VerifiedAt != nil,
+		Message:  verifyEmailMessages[r.URL.Query().Get("status")],
+	}
+
+	h.htmlTemplate.Render(w, "verify-email.html", data)
+}
+
+// Verifies the email address from the signed link sent by email
+func (h *Handler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	params := VerifyEmailParams{
+		UserID:    query.Get("id"),
+		Expires:   query.Get("expires"),
+		Signature: query.Get("signature"),
+	}
+
+	status := "verified"
+
+	if err := h.service.VerifyEmail(r.Context(), params); err != nil {
+		if !errors.Is(err, ErrInvalidVerificationLink) {
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		status = "invalid"
+	}
+
+	// The link may be opened in a browser without a session
+	if userID, _ := FromContext(r.Context()); userID == "" {
+		http.Redirect(w, r, redirectPath, http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, verifyEmailPath+"?status="+status, http.StatusSeeOther)
+}
+
+// Sends another verification link to the signed in user
+func (h *Handler) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := FromContext(r.Context())
+
+	if err != nil {
+		response.RenderError(w, r, errtypes.AuthenticationError(err))
+		return
+	}
+
+	isJSON := r.Header.Get("content-type") == "application/json"
+
+	if err := h.service.ResendVerificationEmail(r.Context(), userID); err != nil {
+		var status string
+
+		switch {
+		case errors.Is(err, ErrVerificationResendTooSoon):
+			if isJSON {
+				response.RenderError(w, r, errtypes.TooManyRequestsError(err, h.config.Auth.VerificationResendInterval))
+				return
+			}
+			status = "too-soon"
+		case errors.Is(err, ErrEmailAlreadyVerified):
+			if isJSON {
+				response.RenderError(w, r, errtypes.ConflictError(err))
+				return
+			}
+			status = "verified"
+		default:
+			response.RenderError(w, r, errtypes.ServerError(err))
+			return
+		}
+
+		http.Redirect(w, r, verifyEmailPath+"?status="+status, http.StatusSeeOther)
+		return
+	}
+
+	if !isJSON {
+		http.Redirect(w, r, verifyEmailPath+"?status=sent", http.StatusSeeOther)
+		return
+	}
+
+	res := &response.APIResponse[any]{
+		Message: verifyEmailMessages["sent"],
+	}
+
+	response.RenderJSON(w, http.StatusOK, res)
+}
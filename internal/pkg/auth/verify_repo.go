@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrEmailAlreadyVerified      = errors.New("the email address is already verified")
+	ErrVerificationResendTooSoon = errors.New("a verification email was sent recently, please wait before requesting another one")
+)
+
+type EmailVerifier interface {
+	FindEmail(ctx context.Context, userID string) (*EmailStatus, error)
+	MarkEmailVerified(ctx context.Context, userID, email string) error
+	ClaimVerificationResend(ctx context.Context, userID string, interval time.Duration) (string, error)
+}
+
+type EmailStatus struct {
+	Email      string
+	VerifiedAt *time.Time
+}
+
+func (r *repo) FindEmail(ctx context.Context, userID string) (*EmailStatus, error) {
+	const q = "SELECT email, email_verified_at FROM users WHERE id = $1 AND deleted_at IS NULL"
+
+	var status EmailStatus
+
+	if err := r.db.QueryRowContext(ctx, q, userID).Scan(&status.Email, &status.VerifiedAt); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// Marks the email as verified, provided it is still the email of the user.
+func (r *repo) MarkEmailVerified(ctx context.Context, userID, email string) error {
+	const q = "UPDATE users SET email_verified_at = NOW() WHERE id = $1 AND email = $2 AND email_verified_at IS NULL"
+
+	if _, err := r.db.ExecContext(ctx, q, userID, email); err != nil {
+		return fmt.Errorf("mark email verified: %w", err)
+	}
+
+	return nil
+}
+
+const claimVerificationResendQuery = `
+UPDATE users SET verification_sent_at = NOW()
+WHERE id = $1
+AND email_verified_at IS NULL
+AND (verification_sent_at IS NULL OR verification_sent_at <= NOW() - make_interval(secs => $2))
+RETURNING email
+`
+
+// Records that a verification email is about to be sent to the user and returns their email.
+//
+// The update only succeeds once per interval, which limits the emails sent to an address
+// even when requests race each other.
+func (r *repo) ClaimVerificationResend(ctx context.Context, userID string, interval time.Duration) (string, error) {
+	var email string
+
+	err := r.db.QueryRowContext(ctx, claimVerificationResendQuery, userID, interval.Seconds()).Scan(&email)
+
+	if err == nil {
+		return email, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("claim verification resend: %w", err)
+	}
+
+	status, err := r.FindEmail(ctx, userID)
+
+	if err != nil {
+		return "", err
+	}
+
+	if status.VerifiedAt != nil {
+		return "", ErrEmailAlreadyVerified
+	}
+
+	return "", ErrVerificationResendTooSoon
+}
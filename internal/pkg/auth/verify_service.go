@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/mail"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
+)
+
+var ErrInvalidVerificationLink = errors.New("the verification link is invalid or has expired")
+
+// Purpose of the signature, so that it cannot be replayed for another kind of link
+const verifyEmailPurpose = "verify-email"
+
+type VerifyEmailParams struct {
+	UserID    string
+	Expires   string
+	Signature string
+}
+
+// Builds the signed link that verifies the email of the user.
+func (s *service) verificationLink(userID, email string) string {
+	expires := strconv.FormatInt(time.Now().Add(s.cfg.Auth.VerificationTTL).Unix(), 10)
+	signature := security.Sign(s.cfg.Auth.SigningKey, verifyEmailPurpose, userID, email, expires)
+
+	query := url.Values{}
+	query.Set("id", userID)
+	query.Set("expires", expires)
+	query.Set("signature", signature)
+
+	return s.cfg.Server.BaseURL + "/verify-email/confirm?" + query.Encode()
+}
+
+func (s *service) sendVerificationEmail(ctx context.Context, userID, email string) error {
+	msg := mail.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Thank you for signing up.\r\n\r\n"+
+			"Open the link below to verify your email address. It expires in %s.\r\n\r\n%s\r\n\r\n"+
+			"If you did not create an account, you can ignore this message.\r\n",
+			s.cfg.Auth.VerificationTTL, s.verificationLink(userID, email)),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send verification link: %w", err)
+	}
+
+	return nil
+}
+
+// Verifies the email of a user from the parameters of a signed link.
+func (s *service) VerifyEmail(ctx context.Context, params VerifyEmailParams) error {
+	// A mangled id would otherwise fail the query instead of the link
+	if !validation.IsUUID(params.UserID) {
+		return ErrInvalidVerificationLink
+	}
+
+	expires, err := strconv.ParseInt(params.Expires, 10, 64)
+
+	if err != nil || time.Now().Unix() > expires {
+		return ErrInvalidVerificationLink
+	}
+
+	status, err := s.repo.FindEmail(ctx, params.UserID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidVerificationLink
+		}
+
+		return fmt.Errorf("find email: %w", err)
+	}
+
+	// The email is part of the signature, so links sent to a previous address stop working
+	if !security.VerifySignature(s.cfg.Auth.SigningKey, params.Signature, verifyEmailPurpose, params.UserID, status.Email, params.Expires) {
+		return ErrInvalidVerificationLink
+	}
+
+	if status.VerifiedAt != nil {
+		return nil
+	}
+
+	return s.repo.MarkEmailVerified(ctx, params.UserID, status.Email)
+}
+
+// Sends another verification email to the user, at most once per resend interval.
+func (s *service) ResendVerificationEmail(ctx context.Context, userID string) error {
+	email, err := s.repo.ClaimVerificationResend(ctx, userID, s.cfg.Auth.VerificationResendInterval)
+
+	if err != nil {
+		return err
+	}
+
+	return s.sendVerificationEmail(ctx, userID, email)
+}
+
+// Returns the email of the user and when it was verified.
+func (s *service) EmailStatus(ctx context.Context, userID string) (*EmailStatus, error) {
+	status, err := s.repo.FindEmail(ctx, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("find email: %w", err)
+	}
+
+	return status, nil
+}
+
+// Reports whether the user has verified their email address.
+func (s *service) IsEmailVerified(ctx context.Context, userID string) (bool, error) {
+	status, err := s.EmailStatus(ctx, userID)
+
+	if err != nil {
+		return false, err
+	}
+
+	return status.VerifiedAt != nil, nil
+}
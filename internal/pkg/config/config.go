@@ -1,23 +1,35 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/ferdiebergado/gopherkit/env"
 )
 
 type Config struct {
-	Server  HTTPServerConfig
-	DB      DBConfig
-	HTML    HTMLTemplateConfig
-	Session SessionConfig
+	Server   HTTPServerConfig
+	DB       DBConfig
+	HTML     HTMLTemplateConfig
+	Session  SessionConfig
+	Auth     AuthConfig
+	Mail     MailConfig
+	WebAuthn WebAuthnConfig
+	Lockout  LockoutConfig
+	Audit    AuditConfig
 }
 
 type HTTPServerConfig struct {
+	BaseURL         string
 	Addr            string
 	Port            int
 	ShutdownTimeout time.Duration
+	TrustedProxies  []*net.IPNet
 	SessionName     string
 	SameSite        http.SameSite
 	SessionDuration time.Duration
@@ -46,19 +58,136 @@ type HTMLTemplateConfig struct {
 }
 
 type SessionConfig struct {
-	SessionName     string
-	SameSite        http.SameSite
-	SessionDuration time.Duration
-	CleanUpInterval time.Duration
-	CSRFName        string
+	Store            string
+	Key              string
+	SessionName      string
+	SameSite         http.SameSite
+	SessionDuration  time.Duration
+	ActivityInterval time.Duration // How often loading a session records its last activity at most
+	CleanUpInterval  time.Duration
+	CleanUpBatchSize int
+	CSRFName         string
+	RememberName     string        // Cookie of the remember me token
+	RememberDuration time.Duration // How long a remember me token lasts after it was last used
+	RememberMaxAge   time.Duration // How long the tokens of a sign in last at most, however often they are used
+}
+
+type AuthConfig struct {
+	SigningKey                 []byte
+	EncryptionKey              string
+	TOTPIssuer                 string
+	PasswordResetTTL           time.Duration
+	MagicLinkTTL               time.Duration
+	VerificationTTL            time.Duration
+	VerificationResendInterval time.Duration
+	OIDCProviders              []OIDCProviderConfig
+	PasswordHash               PasswordHashConfig
+	PasswordPolicy             PasswordPolicyConfig
+	AccountPurgeAfter          time.Duration // Deleted accounts can be restored by support until they are purged
+	AccountPurgeInterval       time.Duration
+	RegistrationMode           string // One of RegistrationOpen, RegistrationInviteOnly or RegistrationClosed
+	InvitationTTL              time.Duration
+}
+
+// Who can sign up
+const (
+	RegistrationOpen       = "open"        // Anyone
+	RegistrationInviteOnly = "invite-only" // Only the holders of an invitation
+	RegistrationClosed     = "closed"      // No one
+)
+
+// Argon2id parameters of new password hashes.
+// Stored hashes made with weaker parameters are replaced when their users sign in.
+type PasswordHashConfig struct {
+	Memory      int // In KiB
+	Iterations  int
+	Parallelism int
+}
+
+// Rules of new passwords. Lengths are in characters.
+type PasswordPolicyConfig struct {
+	MinLength    int
+	MaxLength    int
+	MinStrength  int    // From 0 to 4, 0 disables the strength check
+	BreachedFile string // Sorted SHA-1 hashes of breached passwords, empty disables the check
+}
+
+type OIDCProviderConfig struct {
+	Name         string
+	Label        string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type WebAuthnConfig struct {
+	RPID    string
+	RPName  string
+	Origin  string
+	Timeout time.Duration
+}
+
+type LockoutConfig struct {
+	Store            string
+	MaxAttempts      int           // Failed sign ins of an account before it is locked
+	MaxAttemptsPerIP int           // Failed sign ins from an address before it is locked
+	Window           time.Duration // Failures are forgotten after this long without another one
+	Duration         time.Duration // First lockout, doubled by every failure after it
+	Delay            time.Duration // First delay before an account can try again, doubled by every failure
+	CleanUpInterval  time.Duration
+}
+
+type AuditConfig struct {
+	BufferSize   int           // Events waiting to be written, more are written to the application log instead
+	BatchSize    int           // Most events written at once
+	WriteTimeout time.Duration // Of each batch
 }
 
-func Load() *Config {
-	return &Config{
+type MailConfig struct {
+	Driver string
+	From   string
+	Dir    string
+	AppEnv string // The log driver only works in development
+}
+
+// Loads the config from the environment. Returns an error when a setting is invalid.
+func Load() (*Config, error) {
+	baseURL := strings.TrimSuffix(env.Get("APP_URL", "http://localhost:8080"), "/")
+
+	trustedProxies, err := parseCIDRs(env.Get("TRUSTED_PROXIES", "127.0.0.0/8,::1/128,172.16.0.0/12"))
+
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+
+	rpID := env.Get("WEBAUTHN_RP_ID", "")
+
+	if rpID == "" {
+		if rpID, err = hostname(baseURL); err != nil {
+			return nil, fmt.Errorf("APP_URL: %w", err)
+		}
+	}
+
+	mode, err := registrationMode(env.Get("REGISTRATION_MODE", RegistrationOpen))
+
+	if err != nil {
+		return nil, fmt.Errorf("REGISTRATION_MODE: %w", err)
+	}
+
+	providers, err := loadOIDCProviders(env.Get("OIDC_PROVIDERS", ""))
+
+	if err != nil {
+		return nil, fmt.Errorf("OIDC_PROVIDERS: %w", err)
+	}
+
+	cfg := &Config{
 		Server: HTTPServerConfig{
+			BaseURL:         baseURL,
 			Addr:            env.Get("SERVER_HOST", "0.0.0.0"),
 			Port:            env.GetInt("SERVER_PORT", 8888),
 			ShutdownTimeout: time.Duration(env.GetInt("SERVER_SHUTDOWN_TIMEOUT", 10)) * time.Second,
+			TrustedProxies:  trustedProxies,
 		},
 		DB: DBConfig{
 			Driver:             "pgx",
@@ -80,11 +209,170 @@ func Load() *Config {
 			PartialsDir: "partials",
 		},
 		Session: SessionConfig{
-			SessionName:     "sid",
-			SameSite:        http.SameSiteStrictMode,
-			SessionDuration: 30 * time.Minute,
-			CleanUpInterval: 10 * time.Minute,
-			CSRFName:        "xsrf",
+			Store:            env.Get("SESSION_STORE", "database"),
+			Key:              os.Getenv("SESSION_KEY"), // Read directly to keep the key out of the logs
+			SessionName:      "sid",
+			SameSite:         http.SameSiteStrictMode,
+			SessionDuration:  30 * time.Minute,
+			ActivityInterval: time.Minute,
+			CleanUpInterval:  10 * time.Minute,
+			CleanUpBatchSize: 1000,
+			CSRFName:         "xsrf",
+			RememberName:     "remember",
+			RememberDuration: time.Duration(env.GetInt("REMEMBER_ME_DURATION", 30)) * 24 * time.Hour,
+			RememberMaxAge:   time.Duration(env.GetInt("REMEMBER_ME_MAX_AGE", 90)) * 24 * time.Hour,
+		},
+		Auth: AuthConfig{
+			SigningKey:                 []byte(os.Getenv("APP_KEY")), // Read directly to keep the key out of the logs
+			EncryptionKey:              os.Getenv("ENCRYPTION_KEY"),  // Read directly to keep the key out of the logs
+			TOTPIssuer:                 env.Get("TOTP_ISSUER", "go-fullstack-boilerplate"),
+			PasswordResetTTL:           time.Duration(env.GetInt("PASSWORD_RESET_TTL", 60)) * time.Minute,
+			MagicLinkTTL:               time.Duration(env.GetInt("MAGIC_LINK_TTL", 15)) * time.Minute,
+			VerificationTTL:            time.Duration(env.GetInt("EMAIL_VERIFICATION_TTL", 1440)) * time.Minute,
+			VerificationResendInterval: time.Duration(env.GetInt("EMAIL_VERIFICATION_RESEND_INTERVAL", 60)) * time.Second,
+			OIDCProviders:              providers,
+			PasswordHash: PasswordHashConfig{
+				Memory:      env.GetInt("ARGON2_MEMORY", 64*1024),
+				Iterations:  env.GetInt("ARGON2_ITERATIONS", 3),
+				Parallelism: env.GetInt("ARGON2_PARALLELISM", 2),
+			},
+			AccountPurgeAfter:    time.Duration(env.GetInt("ACCOUNT_PURGE_AFTER", 30)) * 24 * time.Hour,
+			AccountPurgeInterval: time.Hour,
+			RegistrationMode:     mode,
+			InvitationTTL:        time.Duration(env.GetInt("INVITATION_TTL", 7)) * 24 * time.Hour,
+			PasswordPolicy: PasswordPolicyConfig{
+				MinLength:    env.GetInt("PASSWORD_MIN_LENGTH", 8),
+				MaxLength:    env.GetInt("PASSWORD_MAX_LENGTH", 128),
+				MinStrength:  env.GetInt("PASSWORD_MIN_STRENGTH", 3),
+				BreachedFile: env.Get("PASSWORD_BREACHED_FILE", ""),
+			},
+		},
+		WebAuthn: WebAuthnConfig{
+			RPID:    rpID,
+			RPName:  env.Get("WEBAUTHN_RP_NAME", "go-fullstack-boilerplate"),
+			Origin:  baseURL,
+			Timeout: 5 * time.Minute,
+		},
+		Lockout: LockoutConfig{
+			Store:            env.Get("LOCKOUT_STORE", "database"),
+			MaxAttempts:      env.GetInt("LOGIN_MAX_ATTEMPTS", 5),
+			MaxAttemptsPerIP: env.GetInt("LOGIN_MAX_ATTEMPTS_PER_IP", 50),
+			Window:           time.Duration(env.GetInt("LOGIN_ATTEMPT_WINDOW", 60)) * time.Minute,
+			Duration:         time.Duration(env.GetInt("LOGIN_LOCKOUT_DURATION", 15)) * time.Minute,
+			Delay:            time.Duration(env.GetInt("LOGIN_DELAY", 1)) * time.Second,
+			CleanUpInterval:  10 * time.Minute,
+		},
+		Audit: AuditConfig{
+			BufferSize:   env.GetInt("AUDIT_BUFFER_SIZE", 1000),
+			BatchSize:    100,
+			WriteTimeout: 5 * time.Second,
 		},
+		Mail: MailConfig{
+			Driver: env.Get("MAIL_DRIVER", "log"),
+			From:   env.Get("MAIL_FROM", "noreply@localhost"),
+			Dir:    env.Get("MAIL_DIR", "tmp/mail"),
+			AppEnv: env.Get("APP_ENV", "development"),
+		},
+	}
+
+	return cfg, nil
+}
+
+// Returns the host name of the url without the port
+func hostname(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid url %q: no host", rawURL)
+	}
+
+	return u.Hostname(), nil
+}
+
+// Parses a comma-separated list of CIDR ranges
+func parseCIDRs(list string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+
+	for _, cidr := range strings.Split(list, ",") {
+		cidr = strings.TrimSpace(cidr)
+
+		if cidr == "" {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q: %w", cidr, err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func registrationMode(mode string) (string, error) {
+	switch mode {
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationClosed:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid registration mode %q", mode)
 	}
 }
+
+// Loads the settings of each provider in a comma-separated list of provider names.
+//
+// The settings of a provider named google are read from OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID,
+// OIDC_GOOGLE_CLIENT_SECRET, OIDC_GOOGLE_SCOPES and OIDC_GOOGLE_LABEL.
+func loadOIDCProviders(list string) ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		issuer, err := requireEnv(prefix + "ISSUER")
+
+		if err != nil {
+			return nil, err
+		}
+
+		clientID, err := requireEnv(prefix + "CLIENT_ID")
+
+		if err != nil {
+			return nil, err
+		}
+
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			Label:        env.Get(prefix+"LABEL", name),
+			Issuer:       issuer,
+			ClientID:     clientID,
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"), // Read directly to keep the secret out of the logs
+			Scopes:       strings.Fields(env.Get(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+
+	return providers, nil
+}
+
+// Returns the value of the environment variable, or an error when it is not set
+func requireEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+
+	if !ok {
+		return "", fmt.Errorf("%s is not set", name)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,66 @@
+//go:build !integration
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	networks, err := parseCIDRs("127.0.0.0/8, ::1/128,,")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(networks) != 2 {
+		t.Errorf("expected 2 networks, got %d", len(networks))
+	}
+
+	if _, err := parseCIDRs("127.0.0.0/8,10.0.0.1"); err == nil {
+		t.Error("expected an error for a range without a prefix length")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	for _, name := range []string{"DB_HOST", "DB_PORT", "DB_NAME", "DB_USER", "DB_PASSWORD", "DB_SSLMODE"} {
+		t.Setenv(name, "test")
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "Defaults"},
+		{name: "Invalid trusted proxy", env: map[string]string{"TRUSTED_PROXIES": "10.0.0.0/33"}, wantErr: "TRUSTED_PROXIES"},
+		{name: "Invalid app url", env: map[string]string{"APP_URL": "localhost"}, wantErr: "APP_URL"},
+		{name: "Relying party id without an app url", env: map[string]string{"APP_URL": "localhost", "WEBAUTHN_RP_ID": "localhost"}},
+		{name: "Invalid registration mode", env: map[string]string{"REGISTRATION_MODE": "invite"}, wantErr: "REGISTRATION_MODE"},
+		{name: "OIDC provider without an issuer", env: map[string]string{"OIDC_PROVIDERS": "acme", "OIDC_ACME_CLIENT_ID": "id"}, wantErr: "OIDC_ACME_ISSUER"},
+		{name: "OIDC provider", env: map[string]string{"OIDC_PROVIDERS": "acme", "OIDC_ACME_ISSUER": "https://acme.example", "OIDC_ACME_CLIENT_ID": "id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load()
+
+			if tt.wantErr == "" {
+				if err != nil || cfg == nil {
+					t.Fatalf("expected the config, got %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error about %s, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
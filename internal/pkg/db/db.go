@@ -5,7 +5,6 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
-	"sync"
 
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
 )
@@ -39,10 +38,8 @@ func Connect(ctx context.Context, cfg config.DBConfig) (*sql.DB, error) {
 	return db, nil
 }
 
-func WaitDisconnect(ctx context.Context, wg *sync.WaitGroup, conn *sql.DB) {
-	defer wg.Done() // Signal completion of database shutdown
-	<-ctx.Done()
-
+// Closes the database connection once nothing uses it anymore
+func Disconnect(conn *sql.DB) {
 	slog.Info("Closing database connection...")
 
 	if err := conn.Close(); err != nil {
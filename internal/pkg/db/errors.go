@@ -15,3 +15,8 @@ func IsUniqueViolation(err error) bool {
 	// PostgreSQL drivers usually embed the SQLSTATE in the error string
 	return strings.Contains(err.Error(), "23505")
 }
+
+// IsForeignKeyViolation checks if an error is a foreign key constraint violation based on SQLSTATE code 23503.
+func IsForeignKeyViolation(err error) bool {
+	return strings.Contains(err.Error(), "23503")
+}
@@ -2,6 +2,7 @@ package errtypes
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
 )
@@ -10,6 +11,9 @@ type HTTPError struct {
 	Msg  string `json:"msg"`
 	Err  error  `json:"err"`
 	Code int    `json:"code"`
+
+	// How long the client should wait before trying again, sent in the Retry-After header when set.
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
 }
 
 func (e HTTPError) Error() string {
@@ -40,6 +44,15 @@ func BadRequest(err error) *HTTPError {
 	}
 }
 
+// For requests that were read but cannot be carried out, e.g. with an expired link. The client is told why.
+func InvalidRequestError(err error) *HTTPError {
+	return &HTTPError{
+		Msg:  err.Error(),
+		Err:  err,
+		Code: http.StatusBadRequest,
+	}
+}
+
 func ValidationError(inputErr validation.Error) *HTTPError {
 	return &HTTPError{
 		Msg:  inputErr.Error(),
@@ -56,6 +69,48 @@ func AuthenticationError(err error) *HTTPError {
 	}
 }
 
+func ForbiddenError(err error) *HTTPError {
+	return &HTTPError{
+		Msg:  "You are not allowed to perform this action.",
+		Err:  err,
+		Code: http.StatusForbidden,
+	}
+}
+
+// Like ForbiddenError, for when the user should be told why, e.g. that sign ups are closed.
+func DeniedError(err error) *HTTPError {
+	return &HTTPError{
+		Msg:  err.Error(),
+		Err:  err,
+		Code: http.StatusForbidden,
+	}
+}
+
+func NotFoundError(err error) *HTTPError {
+	return &HTTPError{
+		Msg:  "The requested resource was not found.",
+		Err:  err,
+		Code: http.StatusNotFound,
+	}
+}
+
+func ConflictError(err error) *HTTPError {
+	return &HTTPError{
+		Msg:  err.Error(),
+		Err:  err,
+		Code: http.StatusConflict,
+	}
+}
+
+func TooManyRequestsError(err error, retryAfter time.Duration) *HTTPError {
+	return &HTTPError{
+		Msg:        err.Error(),
+		Err:        err,
+		Code:       http.StatusTooManyRequests,
+		RetryAfter: retryAfter,
+	}
+}
+
 func JSONEncodeError(err error) *HTTPError {
 	return &HTTPError{
 		Msg:  "Failed to encode json.",
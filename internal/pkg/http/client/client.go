@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Info describes the client that sent a request.
+type Info struct {
+	IP        string
+	UserAgent string
+}
+
+type ctxKey struct{}
+
+// Stores the client info in the context.
+func WithInfo(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, ctxKey{}, info)
+}
+
+// Retrieves the client info from the context.
+func FromContext(ctx context.Context) (Info, bool) {
+	info, ok := ctx.Value(ctxKey{}).(Info)
+	return info, ok
+}
+
+// Resolves the client info of each request and stores it in the request context.
+//
+// Returns a plain handler wrapper to avoid an import cycle with the middleware package.
+func Middleware(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := Info{
+				IP:        IP(r, trustedProxies),
+				UserAgent: r.UserAgent(),
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithInfo(r.Context(), info)))
+		})
+	}
+}
+
+// Determines the ip address of the client.
+//
+// X-Forwarded-For is only honored when the request comes from a trusted proxy.
+// The header is then read from right to left and the first address that is not
+// a trusted proxy is the client, since everything left of it can be forged.
+func IP(r *http.Request, trustedProxies []*net.IPNet) string {
+	remoteIP := parseIP(r.RemoteAddr)
+
+	if remoteIP == nil {
+		return ""
+	}
+
+	if !isTrusted(remoteIP, trustedProxies) {
+		return remoteIP.String()
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseIP(strings.TrimSpace(hops[i]))
+
+		if hop == nil {
+			break
+		}
+
+		if !isTrusted(hop, trustedProxies) {
+			return hop.String()
+		}
+
+		remoteIP = hop
+	}
+
+	return remoteIP.String()
+}
+
+// Parses an ip address that may include a port
+func parseIP(addr string) net.IP {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	return net.ParseIP(addr)
+}
+
+func isTrusted(ip net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
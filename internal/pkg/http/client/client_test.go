@@ -0,0 +1,48 @@
+//go:build !integration
+
+package client
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIP(t *testing.T) {
+	_, proxyNet, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trusted := []*net.IPNet{proxyNet}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{"Should use the remote address without a proxy", "203.0.113.7:51000", "", "203.0.113.7"},
+		{"Should ignore forwarded headers from untrusted peers", "203.0.113.7:51000", "198.51.100.1", "203.0.113.7"},
+		{"Should use the forwarded address from a trusted proxy", "10.0.0.2:51000", "198.51.100.1", "198.51.100.1"},
+		{"Should skip trusted hops", "10.0.0.2:51000", "198.51.100.1, 10.0.0.5", "198.51.100.1"},
+		{"Should not trust spoofed addresses left of the client", "10.0.0.2:51000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"Should fall back to the proxy when the header is invalid", "10.0.0.2:51000", "garbage", "10.0.0.2"},
+		{"Should handle ipv6 addresses", "[2001:db8::1]:443", "", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			if actual := IP(req, trusted); actual != tt.expected {
+				t.Errorf("IP() = %q; want %q", actual, tt.expected)
+			}
+		})
+	}
+}
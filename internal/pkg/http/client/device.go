@@ -0,0 +1,51 @@
+package client
+
+import "strings"
+
+// Browsers in the order they must be matched, since most user agents also claim to be the ones after them
+var browsers = [...][2]string{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+var platforms = [...][2]string{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// Summarizes a user agent string into a human readable device description like "Firefox on Linux".
+func Device(userAgent string) string {
+	browser := match(userAgent, browsers[:])
+	platform := match(userAgent, platforms[:])
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	case userAgent != "":
+		return userAgent
+	default:
+		return "Unknown device"
+	}
+}
+
+func match(userAgent string, patterns [][2]string) string {
+	for _, pattern := range patterns {
+		if strings.Contains(userAgent, pattern[0]) {
+			return pattern[1]
+		}
+	}
+
+	return ""
+}
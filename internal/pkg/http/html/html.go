@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
@@ -38,6 +39,12 @@ func funcMap() template.FuncMap {
 		"css": func(s string) template.CSS {
 			return template.CSS(s) // #nosec G203 -- No user input
 		},
+		"datetime": func(t time.Time) string {
+			return t.Local().Format("Jan 2, 2006 3:04 PM")
+		},
+		"csrfField": func(token string) template.HTML {
+			return template.HTML(`<input type="hidden" name="_csrf" value="` + template.HTMLEscapeString(token) + `" />`) // #nosec G203 -- Token is escaped
+		},
 	}
 }
 
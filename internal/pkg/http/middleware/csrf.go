@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/response"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+const (
+	CSRFHeader    = "X-XSRF-Token"
+	CSRFFormField = "_csrf"
+)
+
+type (
+	csrfCtxKey       struct{}
+	csrfExemptCtxKey struct{}
+)
+
+var ErrCSRFTokenMismatch = errors.New("invalid or missing csrf token")
+
+// Checks the csrf token sent with state-changing requests.
+//
+// The token is read from the X-XSRF-Token header, falling back to the _csrf form field.
+// Signed in sessions have their own token. Other requests, like the sign in, are checked against the
+// csrf cookie instead, which is issued with the first safe request, so that a forged form cannot sign
+// the browser in to the account of the attacker.
+// Requests marked with WithoutCSRF are let through.
+// Must be registered after the middleware that loads the session into the context.
+func CSRF(cfg config.SessionConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt, _ := r.Context().Value(csrfExemptCtxKey{}).(bool); exempt {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var expected string
+
+			if sessionData, ok := session.FromContext(r.Context()); ok && sessionData.UserID != "" {
+				expected = sessionData.CSRFToken
+			} else if cookie, err := r.Cookie(cfg.CSRFName); err == nil {
+				expected = cookie.Value
+			}
+
+			if IsSafeMethod(r.Method) {
+				if expected == "" {
+					token, err := issueCSRFCookie(w, cfg)
+
+					if err != nil {
+						response.RenderError(w, r, errtypes.ServerError(err))
+						return
+					}
+
+					expected = token
+				}
+
+				ctx := context.WithValue(r.Context(), csrfCtxKey{}, expected)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			token := r.Header.Get(CSRFHeader)
+
+			if token == "" {
+				token = r.PostFormValue(CSRFFormField)
+			}
+
+			if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+				response.RenderError(w, r, errtypes.ForbiddenError(ErrCSRFTokenMismatch))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), csrfCtxKey{}, expected)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Marks the request as authenticated by a credential that the browser does not send on its own, like
+// a bearer token, so that CSRF lets it through. Only call it once the credential has been verified,
+// since the session cookie may still come along with the request.
+func WithoutCSRF(ctx context.Context) context.Context {
+	return context.WithValue(ctx, csrfExemptCtxKey{}, true)
+}
+
+// Sends a new csrf token for the requests made before signing in
+func issueCSRFCookie(w http.ResponseWriter, cfg config.SessionConfig) (string, error) {
+	token, err := security.GenerateRandomBytesEncoded(32)
+
+	if err != nil {
+		return "", fmt.Errorf("generate csrf token: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cfg.CSRFName,
+		Value:    token,
+		HttpOnly: false, // Read by the scripts that send it back in the header
+		SameSite: cfg.SameSite,
+		Path:     "/",
+	})
+
+	return token, nil
+}
+
+// Retrieves the csrf token of the current session from the context.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfCtxKey{}).(string)
+	return token
+}
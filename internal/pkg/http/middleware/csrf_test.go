@@ -0,0 +1,179 @@
+//go:build !integration
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/session"
+)
+
+const testToken = "csrf-token"
+
+var testConfig = config.SessionConfig{CSRFName: "xsrf", SameSite: http.SameSiteStrictMode}
+
+func csrfHandler(t *testing.T, data *session.Data) http.Handler {
+	t.Helper()
+
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := CSRFToken(r.Context()); data != nil && data.UserID != "" && got != data.CSRFToken {
+			t.Errorf("expected token %q in context, got %q", data.CSRFToken, got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	withSession := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if data != nil {
+				r = r.WithContext(session.WithData(r.Context(), data))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return withSession(CSRF(testConfig)(okHandler))
+}
+
+func TestCSRF(t *testing.T) {
+	authenticated := &session.Data{UserID: "user-1", CSRFToken: testToken}
+
+	tests := []struct {
+		name     string
+		data     *session.Data
+		method   string
+		cookie   string
+		header   string
+		form     string
+		expected int
+	}{
+		{"Should allow safe methods without a token", authenticated, http.MethodGet, "", "", "", http.StatusNoContent},
+		{"Should reject a missing token", authenticated, http.MethodPost, "", "", "", http.StatusForbidden},
+		{"Should reject a wrong token", authenticated, http.MethodDelete, "", "wrong", "", http.StatusForbidden},
+		{"Should accept the token from the header", authenticated, http.MethodPut, "", testToken, "", http.StatusNoContent},
+		{"Should accept the token from the form", authenticated, http.MethodPost, "", "", testToken, http.StatusNoContent},
+		{"Should reject when the session has no token", &session.Data{UserID: "user-1"}, http.MethodPatch, "", "", "", http.StatusForbidden},
+		{"Should ignore the cookie of signed in sessions", authenticated, http.MethodPost, "other", "other", "", http.StatusForbidden},
+		{"Should reject requests without a session or a cookie", nil, http.MethodPost, "", "", "", http.StatusForbidden},
+		{"Should reject anonymous sessions without a token", &session.Data{}, http.MethodPost, testToken, "", "", http.StatusForbidden},
+		{"Should reject anonymous requests with a wrong token", nil, http.MethodPost, testToken, "wrong", "", http.StatusForbidden},
+		{"Should accept the token of the cookie before signing in", &session.Data{}, http.MethodPost, testToken, testToken, "", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body *strings.Reader
+			if tt.form != "" {
+				body = strings.NewReader(url.Values{CSRFFormField: {tt.form}}.Encode())
+			} else {
+				body = strings.NewReader("")
+			}
+
+			req := httptest.NewRequest(tt.method, "/", body)
+			if tt.form != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: testConfig.CSRFName, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+
+			csrfHandler(t, tt.data).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCSRFIssuesTokenBeforeSignIn(t *testing.T) {
+	var token string
+
+	handler := CSRF(testConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = CSRFToken(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/signin", nil))
+
+	cookies := rec.Result().Cookies()
+
+	if len(cookies) != 1 || cookies[0].Name != testConfig.CSRFName || cookies[0].Value == "" {
+		t.Fatalf("expected a csrf cookie, got %v", cookies)
+	}
+
+	if token != cookies[0].Value {
+		t.Errorf("expected token %q in context, got %q", cookies[0].Value, token)
+	}
+
+	// The cookie is kept once issued
+	req := httptest.NewRequest(http.MethodGet, "/signin", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if len(rec.Result().Cookies()) != 0 || token != cookies[0].Value {
+		t.Errorf("expected the issued token to be reused, got %q", token)
+	}
+}
+
+func TestCSRFSkipsVerifiedCredentials(t *testing.T) {
+	// The session cookie comes along with the request, whatever else authenticates it
+	authenticated := &session.Data{UserID: "user-1", CSRFToken: testToken}
+
+	handler := CSRF(testConfig)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		verified bool
+		expected int
+	}{
+		{"Should check requests with an unverified Authorization header", "Basic dXNlcjpwYXNz", false, http.StatusForbidden},
+		{"Should check requests with a bearer token that was not verified", "Bearer token", false, http.StatusForbidden},
+		{"Should let requests with a verified credential through", "Bearer token", true, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/tokens", nil)
+			req.Header.Set("Authorization", tt.header)
+			req = req.WithContext(session.WithData(req.Context(), authenticated))
+
+			if tt.verified {
+				req = req.WithContext(WithoutCSRF(req.Context()))
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestIsSafeMethod(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace} {
+		if !IsSafeMethod(method) {
+			t.Errorf("expected %s to be safe", method)
+		}
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if IsSafeMethod(method) {
+			t.Errorf("expected %s to be unsafe", method)
+		}
+	}
+}
@@ -3,3 +3,13 @@ package middleware
 import "net/http"
 
 type Middleware func(http.Handler) http.Handler
+
+// IsSafeMethod reports whether requests with the method only read, so they need neither a csrf token nor write access.
+func IsSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
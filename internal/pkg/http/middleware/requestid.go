@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDCtxKey struct{}
+
+// Gives every request a random id that is sent back in the X-Request-ID header,
+// so that what a request left in the logs can be matched with the response that the client got.
+//
+// Ids sent by the client are ignored, since they could be forged to point at other requests.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := newRequestID()
+
+			if err != nil {
+				slog.Error("failed to generate a request id", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDCtxKey{}, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Retrieves the id of the current request from the context, empty when it has none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
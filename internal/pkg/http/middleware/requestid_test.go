@@ -0,0 +1,49 @@
+//go:build !integration
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var got string
+
+	handler := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFromContext(r.Context())
+	}))
+
+	seen := make(map[string]bool)
+
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, "forged")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if len(got) != 32 {
+			t.Fatalf("expected a 32 character id, got %q", got)
+		}
+
+		if header := rec.Header().Get(RequestIDHeader); header != got {
+			t.Errorf("expected the header %q to match the id %q", header, got)
+		}
+
+		if seen[got] {
+			t.Errorf("id %q was given to more than one request", got)
+		}
+
+		seen[got] = true
+	}
+}
+
+func TestRequestIDFromContextWithoutID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if id := RequestIDFromContext(req.Context()); id != "" {
+		t.Errorf("expected no id, got %q", id)
+	}
+}
@@ -4,15 +4,30 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
 
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/app/user"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/errtypes"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/validation"
 	gkitResponse "github.com/ferdiebergado/gopherkit/http/response"
 )
 
 type PageData struct {
-	Title    string
-	Subtitle string
+	Title       string
+	Subtitle    string
+	CSRFToken   string
+	Permissions []string   // Of the signed in user
+	User        *user.User // The signed in user, nil on pages that do not need it
+
+	// Set while an admin is impersonating the signed in user
+	Impersonating bool
+}
+
+// Reports whether the signed in user has the permission, so that templates can hide what it cannot use
+func (p PageData) Can(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
 }
 
 type APIResponse[T any] struct {
@@ -24,6 +39,12 @@ type APIResponse[T any] struct {
 func RenderError(w http.ResponseWriter, r *http.Request, err *errtypes.HTTPError) {
 	slog.Error(err.Msg, "error", err.Err)
 
+	if err.RetryAfter > 0 {
+		// Rounded up, so that the client never retries too early
+		seconds := (err.RetryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+	}
+
 	if r.Header.Get("content-type") != "application/json" {
 		http.Error(w, err.Error(), err.Code)
 		return
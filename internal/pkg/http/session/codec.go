@@ -0,0 +1,37 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Codec serializes the session data for storage.
+type Codec interface {
+	Encode(Data) ([]byte, error)
+	Decode([]byte) (*Data, error)
+}
+
+// JSONCodec stores the session data as json so that it can be inspected and queried with sql.
+type JSONCodec struct{}
+
+var _ Codec = JSONCodec{}
+
+func (JSONCodec) Encode(data Data) ([]byte, error) {
+	encoded, err := json.Marshal(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("encode session data as json: %w", err)
+	}
+
+	return encoded, nil
+}
+
+func (JSONCodec) Decode(encoded []byte) (*Data, error) {
+	var data Data
+
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, fmt.Errorf("decode session data from json: %w", err)
+	}
+
+	return &data, nil
+}
@@ -0,0 +1,79 @@
+//go:build !integration
+
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testValue struct {
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+func TestCodecs(t *testing.T) {
+	data := Data{
+		UserID:    "user-1",
+		CSRFToken: "token",
+		Flash:     map[string]string{"intendedUrl": "/dashboard"},
+	}
+
+	if err := Set(&data, "value", testValue{Count: 2, Tags: []string{"a"}}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	codecs := map[string]Codec{
+		"json": JSONCodec{},
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := codec.Encode(data)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+
+			decoded, err := codec.Decode(encoded)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+
+			if !reflect.DeepEqual(*decoded, data) {
+				t.Errorf("expected %+v, got %+v", data, *decoded)
+			}
+		})
+	}
+}
+
+func TestValues(t *testing.T) {
+	var data Data
+
+	if _, ok, err := Get[string](&data, "missing"); ok || err != nil {
+		t.Errorf("expected missing value, got ok: %v, err: %v", ok, err)
+	}
+
+	expected := testValue{Count: 3, Tags: []string{"x", "y"}}
+	if err := Set(&data, "value", expected); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	actual, ok, err := Get[testValue](&data, "value")
+	if err != nil || !ok {
+		t.Fatalf("expected value, got ok: %v, err: %v", ok, err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	if _, _, err := Get[int](&data, "value"); err == nil {
+		t.Error("expected an error when reading a value as the wrong type")
+	}
+
+	Delete(&data, "value")
+
+	if _, ok, _ := Get[testValue](&data, "value"); ok {
+		t.Error("expected value to be deleted")
+	}
+}
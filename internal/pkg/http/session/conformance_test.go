@@ -0,0 +1,284 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/client"
+)
+
+type managerFactory func(t *testing.T, cfg config.SessionConfig) Manager
+
+func testConfig() config.SessionConfig {
+	return config.SessionConfig{
+		SessionName:      "sid",
+		SessionDuration:  time.Minute,
+		CleanUpBatchSize: 10,
+		CSRFName:         "xsrf",
+	}
+}
+
+// Builds a request that carries the given session id in its cookie
+func requestWithSession(cfg config.SessionConfig, sessionID string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if sessionID != "" {
+		req.AddCookie(&http.Cookie{Name: cfg.SessionName, Value: sessionID})
+	}
+
+	return req
+}
+
+// Runs the behavior every session manager must implement against the managers created by newManager.
+//
+// Stores that cannot enumerate their sessions report errors.ErrUnsupported from ListSessions,
+// and are then not expected to invalidate session ids on the server side.
+func runConformance(t *testing.T, userID string, newManager managerFactory) {
+	t.Helper()
+
+	cfg := testConfig()
+	ctx := client.WithInfo(context.Background(), client.Info{IP: "203.0.113.7", UserAgent: "conformance-test"})
+
+	data := Data{
+		UserID:    userID,
+		CSRFToken: "csrf",
+		Flash:     map[string]string{"intendedUrl": "/profile"},
+	}
+
+	if err := Set(&data, "counter", 1); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	store := func(t *testing.T, mgr Manager, data Data) string {
+		t.Helper()
+
+		sessionID, err := mgr.ExtractSessionID(requestWithSession(cfg, ""))
+		if err != nil {
+			t.Fatalf("extract session id: %v", err)
+		}
+
+		sessionID, err = mgr.StoreSession(ctx, sessionID, data)
+		if err != nil {
+			t.Fatalf("store session: %v", err)
+		}
+
+		return sessionID
+	}
+
+	t.Run("Should load the stored session", func(t *testing.T) {
+		mgr := newManager(t, cfg)
+		sessionID := store(t, mgr, data)
+
+		loaded, err := mgr.LoadSession(requestWithSession(cfg, sessionID))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if !reflect.DeepEqual(*loaded, data) {
+			t.Errorf("expected %+v, got %+v", data, *loaded)
+		}
+	})
+
+	t.Run("Should report missing sessions", func(t *testing.T) {
+		mgr := newManager(t, cfg)
+
+		for _, sessionID := range []string{"", "unknown-session-id"} {
+			if _, err := mgr.LoadSession(requestWithSession(cfg, sessionID)); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("expected ErrSessionNotFound for %q, got: %v", sessionID, err)
+			}
+		}
+	})
+
+	t.Run("Should reject expired sessions", func(t *testing.T) {
+		expiredCfg := cfg
+		expiredCfg.SessionDuration = -time.Minute
+		mgr := newManager(t, expiredCfg)
+		sessionID := store(t, mgr, data)
+
+		if _, err := mgr.LoadSession(requestWithSession(cfg, sessionID)); !errors.Is(err, ErrSessionNotFound) {
+			t.Fatalf("expected ErrSessionNotFound, got: %v", err)
+		}
+
+		if sweeper, ok := mgr.(Sweeper); ok {
+			deleted, err := sweeper.DeleteExpired(context.Background())
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+
+			if deleted < 1 {
+				t.Errorf("expected the expired session to be deleted, got %d", deleted)
+			}
+		}
+	})
+
+	t.Run("Should carry the data over when regenerating", func(t *testing.T) {
+		mgr := newManager(t, cfg)
+		oldSessionID := store(t, mgr, Data{Flash: data.Flash})
+
+		newSessionID, err := mgr.Regenerate(ctx, oldSessionID, data)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if newSessionID == oldSessionID {
+			t.Fatal("expected a new session id")
+		}
+
+		loaded, err := mgr.LoadSession(requestWithSession(cfg, newSessionID))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if !reflect.DeepEqual(*loaded, data) {
+			t.Errorf("expected %+v, got %+v", data, *loaded)
+		}
+
+		if isStateful(t, mgr, userID) {
+			if _, err := mgr.LoadSession(requestWithSession(cfg, oldSessionID)); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("expected the old session to be deleted, got: %v", err)
+			}
+		}
+	})
+
+	t.Run("Should destroy the session", func(t *testing.T) {
+		mgr := newManager(t, cfg)
+		sessionID := store(t, mgr, data)
+		req := requestWithSession(cfg, sessionID)
+
+		if err := mgr.DestroySession(req); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if isStateful(t, mgr, userID) {
+			if _, err := mgr.LoadSession(req); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("expected ErrSessionNotFound, got: %v", err)
+			}
+		}
+	})
+
+	t.Run("Should record the activity of loaded sessions at most once per interval", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			interval time.Duration
+			recorded bool
+		}{
+			{"Should keep the activity within the interval", time.Hour, false},
+			{"Should record the activity after the interval", time.Millisecond, true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				activityCfg := cfg
+				activityCfg.ActivityInterval = tt.interval
+				mgr := newManager(t, activityCfg)
+
+				if !isStateful(t, mgr, userID) {
+					t.Skip("store cannot enumerate its sessions")
+				}
+
+				sessionID := store(t, mgr, data)
+
+				lastActivity := func() time.Time {
+					t.Helper()
+
+					sessions, err := mgr.ListSessions(ctx, userID)
+					if err != nil {
+						t.Fatalf("expected no error, got: %v", err)
+					}
+
+					for _, s := range sessions {
+						if s.Handle == Handle(sessionID) {
+							return s.LastActivity
+						}
+					}
+
+					t.Fatal("expected the session to be listed")
+					return time.Time{}
+				}
+
+				stored := lastActivity()
+				time.Sleep(10 * time.Millisecond)
+
+				if _, err := mgr.LoadSession(requestWithSession(cfg, sessionID)); err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+
+				if recorded := lastActivity().After(stored); recorded != tt.recorded {
+					t.Errorf("expected the activity to be recorded: %v, got %v", tt.recorded, recorded)
+				}
+			})
+		}
+	})
+
+	t.Run("Should list and revoke the sessions of a user", func(t *testing.T) {
+		mgr := newManager(t, cfg)
+
+		if !isStateful(t, mgr, userID) {
+			t.Skip("store cannot enumerate its sessions")
+		}
+
+		first := store(t, mgr, data)
+		second := store(t, mgr, data)
+		third := store(t, mgr, data)
+
+		sessions, err := mgr.ListSessions(ctx, userID)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if len(sessions) != 3 {
+			t.Fatalf("expected 3 sessions, got %d", len(sessions))
+		}
+
+		if sessions[0].IPAddress != "203.0.113.7" || sessions[0].UserAgent != "conformance-test" {
+			t.Errorf("expected the client info to be recorded, got %+v", sessions[0])
+		}
+
+		revoked, err := mgr.RevokeSession(ctx, userID, Handle(first))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if revoked.UserID != userID {
+			t.Errorf("expected the data of the revoked session, got %+v", revoked)
+		}
+
+		if _, err := mgr.RevokeSession(ctx, userID, Handle(first)); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("expected ErrSessionNotFound when revoking twice, got: %v", err)
+		}
+
+		if err := mgr.RevokeUserSessions(ctx, userID, second); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		for sessionID, alive := range map[string]bool{first: false, second: true, third: false} {
+			_, err := mgr.LoadSession(requestWithSession(cfg, sessionID))
+
+			if alive && err != nil {
+				t.Errorf("expected session to be kept, got: %v", err)
+			}
+
+			if !alive && !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("expected session to be revoked, got: %v", err)
+			}
+		}
+	})
+}
+
+func isStateful(t *testing.T, mgr Manager, userID string) bool {
+	t.Helper()
+
+	_, err := mgr.ListSessions(context.Background(), userID)
+
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("list sessions: %v", err)
+	}
+
+	return err == nil
+}
@@ -0,0 +1,16 @@
+package session
+
+import "context"
+
+type ctxKey struct{}
+
+// Stores the loaded session data in the context.
+func WithData(ctx context.Context, data *Data) context.Context {
+	return context.WithValue(ctx, ctxKey{}, data)
+}
+
+// Retrieves the session data loaded for the current request.
+func FromContext(ctx context.Context) (*Data, bool) {
+	data, ok := ctx.Value(ctxKey{}).(*Data)
+	return data, ok && data != nil
+}
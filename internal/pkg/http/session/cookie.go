@@ -0,0 +1,123 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+var ErrInvalidSessionKey = errors.New("the session key must be a base64 encoded 32-byte key")
+
+// CookieSession stores the session data in the session cookie itself, encrypted and authenticated with AES-GCM.
+//
+// It needs no storage at all, but the server cannot enumerate or revoke the sessions it issued,
+// so signing out only clears the cookie on the client and a copied cookie stays valid until it expires.
+type CookieSession struct {
+	cfg config.SessionConfig
+	key []byte
+}
+
+var _ Manager = (*CookieSession)(nil)
+
+type cookiePayload struct {
+	Data       Data      `json:"data"`
+	ExpiryTime time.Time `json:"exp"`
+}
+
+func NewCookieSession(cfg config.SessionConfig) (Manager, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.Key)
+
+	if err != nil || len(key) != security.EncryptionKeyLength {
+		return nil, ErrInvalidSessionKey
+	}
+
+	return &CookieSession{
+		cfg: cfg,
+		key: key,
+	}, nil
+}
+
+// Seals the session data. The returned value is the new session cookie, the given session id is ignored.
+func (c *CookieSession) StoreSession(_ context.Context, _ string, sessionData Data) (string, error) {
+	payload, err := json.Marshal(cookiePayload{
+		Data:       sessionData,
+		ExpiryTime: time.Now().Add(c.cfg.SessionDuration),
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("encode session cookie: %w", err)
+	}
+
+	sealed, err := security.Encrypt(c.key, payload)
+
+	if err != nil {
+		return "", fmt.Errorf("seal session cookie: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c *CookieSession) LoadSession(r *http.Request) (*Data, error) {
+	cookie, err := r.Cookie(c.cfg.SessionName)
+
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+
+	payload, err := security.Decrypt(c.key, sealed)
+
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+
+	var decoded cookiePayload
+
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, fmt.Errorf("decode session cookie: %w", err)
+	}
+
+	if !decoded.ExpiryTime.After(time.Now()) {
+		return nil, ErrSessionNotFound
+	}
+
+	return &decoded.Data, nil
+}
+
+func (c *CookieSession) ExtractSessionID(r *http.Request) (string, error) {
+	return extractSessionID(r, c.cfg.SessionName)
+}
+
+// There is nothing to delete on the server, the caller is responsible for clearing the cookie.
+func (c *CookieSession) DestroySession(_ *http.Request) error {
+	return nil
+}
+
+// Seals the data into a new cookie value with a fresh nonce.
+func (c *CookieSession) Regenerate(ctx context.Context, _ string, sessionData Data) (string, error) {
+	return c.StoreSession(ctx, "", sessionData)
+}
+
+func (c *CookieSession) ListSessions(context.Context, string) ([]Info, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (c *CookieSession) RevokeSession(context.Context, string, string) (*Data, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (c *CookieSession) RevokeUserSessions(context.Context, string, string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,75 @@
+//go:build !integration
+
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+func newTestCookieSession(t *testing.T, cfg config.SessionConfig) Manager {
+	t.Helper()
+
+	key, err := security.GenerateRandomBytes(security.EncryptionKeyLength)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	cfg.Key = base64.StdEncoding.EncodeToString(key)
+
+	mgr, err := NewCookieSession(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	return mgr
+}
+
+func TestCookieSession(t *testing.T) {
+	runConformance(t, "user-1", newTestCookieSession)
+}
+
+func TestCookieSessionRejectsTampering(t *testing.T) {
+	cfg := testConfig()
+	mgr := newTestCookieSession(t, cfg)
+
+	sessionID, err := mgr.StoreSession(context.Background(), "", Data{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(sessionID)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	sealed[len(sealed)/2] ^= 0x01
+	tampered := base64.RawURLEncoding.EncodeToString(sealed)
+
+	if _, err := mgr.LoadSession(requestWithSession(cfg, tampered)); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound, got: %v", err)
+	}
+
+	other := newTestCookieSession(t, cfg)
+
+	if _, err := other.LoadSession(requestWithSession(cfg, sessionID)); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected a cookie sealed with another key to be rejected, got: %v", err)
+	}
+}
+
+func TestNewCookieSessionRequiresKey(t *testing.T) {
+	for _, key := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		cfg := testConfig()
+		cfg.Key = key
+
+		if _, err := NewCookieSession(cfg); !errors.Is(err, ErrInvalidSessionKey) {
+			t.Errorf("expected ErrInvalidSessionKey for %q, got: %v", key, err)
+		}
+	}
+}
+
@@ -0,0 +1,237 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/client"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+type memoryEntry struct {
+	data         []byte
+	userID       string
+	ipAddress    string
+	userAgent    string
+	loginTime    time.Time
+	lastActivity time.Time
+	expiryTime   time.Time
+}
+
+// MemorySession keeps the sessions in process memory.
+//
+// Sessions are lost on restart and are not shared between instances,
+// so it is meant for single-instance deployments and tests.
+type MemorySession struct {
+	cfg      config.SessionConfig
+	codec    Codec
+	mu       sync.RWMutex
+	sessions map[string]*memoryEntry
+}
+
+var _ Manager = (*MemorySession)(nil)
+var _ Sweeper = (*MemorySession)(nil)
+
+func NewMemorySession(cfg config.SessionConfig) Manager {
+	return &MemorySession{
+		cfg:      cfg,
+		codec:    JSONCodec{},
+		sessions: make(map[string]*memoryEntry),
+	}
+}
+
+func (m *MemorySession) StoreSession(ctx context.Context, sessionID string, sessionData Data) (string, error) {
+	encoded, err := m.codec.Encode(sessionData)
+
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.store(ctx, sessionID, encoded, sessionData.UserID)
+
+	return sessionID, nil
+}
+
+// Saves the encoded session data, the caller must hold the write lock
+func (m *MemorySession) store(ctx context.Context, sessionID string, encoded []byte, userID string) {
+	now := time.Now()
+	info, _ := client.FromContext(ctx)
+
+	entry, ok := m.sessions[sessionID]
+
+	if !ok {
+		entry = &memoryEntry{loginTime: now}
+		m.sessions[sessionID] = entry
+	}
+
+	entry.data = encoded
+	entry.userID = userID
+	entry.ipAddress = info.IP
+	entry.userAgent = info.UserAgent
+	entry.lastActivity = now
+	entry.expiryTime = now.Add(m.cfg.SessionDuration)
+}
+
+func (m *MemorySession) LoadSession(r *http.Request) (*Data, error) {
+	sessionID, err := m.ExtractSessionID(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	m.mu.RLock()
+	entry, ok := m.sessions[sessionID]
+
+	var encoded []byte
+	var idle bool
+
+	if ok && entry.expiryTime.After(now) {
+		encoded = entry.data
+		idle = now.Sub(entry.lastActivity) >= m.cfg.ActivityInterval
+	}
+
+	m.mu.RUnlock()
+
+	if encoded == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	if idle {
+		m.touch(sessionID, now)
+	}
+
+	return m.codec.Decode(encoded)
+}
+
+// Records the activity of the session, unless it was recorded less than the activity interval ago
+func (m *MemorySession) touch(sessionID string, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.sessions[sessionID]; ok && now.Sub(entry.lastActivity) >= m.cfg.ActivityInterval {
+		entry.lastActivity = now
+	}
+}
+
+func (m *MemorySession) ExtractSessionID(r *http.Request) (string, error) {
+	return extractSessionID(r, m.cfg.SessionName)
+}
+
+func (m *MemorySession) DestroySession(r *http.Request) error {
+	sessionID, err := m.ExtractSessionID(r)
+
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	delete(m.sessions, sessionID)
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *MemorySession) Regenerate(ctx context.Context, oldSessionID string, sessionData Data) (string, error) {
+	encoded, err := m.codec.Encode(sessionData)
+
+	if err != nil {
+		return "", err
+	}
+
+	newSessionID, err := security.GenerateRandomBytesEncoded(64)
+
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, oldSessionID)
+	m.store(ctx, newSessionID, encoded, sessionData.UserID)
+
+	return newSessionID, nil
+}
+
+func (m *MemorySession) ListSessions(_ context.Context, userID string) ([]Info, error) {
+	now := time.Now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []Info
+
+	for sessionID, entry := range m.sessions {
+		if entry.userID != userID || !entry.expiryTime.After(now) {
+			continue
+		}
+
+		sessions = append(sessions, Info{
+			Handle:       Handle(sessionID),
+			IPAddress:    entry.ipAddress,
+			UserAgent:    entry.userAgent,
+			LoginTime:    entry.loginTime,
+			LastActivity: entry.lastActivity,
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActivity.After(sessions[j].LastActivity)
+	})
+
+	return sessions, nil
+}
+
+func (m *MemorySession) RevokeSession(_ context.Context, userID, handle string) (*Data, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for sessionID, entry := range m.sessions {
+		if entry.userID == userID && Handle(sessionID) == handle {
+			delete(m.sessions, sessionID)
+			return m.codec.Decode(entry.data)
+		}
+	}
+
+	return nil, ErrSessionNotFound
+}
+
+func (m *MemorySession) RevokeUserSessions(_ context.Context, userID, exceptSessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for sessionID, entry := range m.sessions {
+		if entry.userID == userID && sessionID != exceptSessionID {
+			delete(m.sessions, sessionID)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemorySession) DeleteExpired(_ context.Context) (int64, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+
+	for sessionID, entry := range m.sessions {
+		if !entry.expiryTime.After(now) {
+			delete(m.sessions, sessionID)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
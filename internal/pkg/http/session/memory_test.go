@@ -0,0 +1,15 @@
+//go:build !integration
+
+package session
+
+import (
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+)
+
+func TestMemorySession(t *testing.T) {
+	runConformance(t, "user-1", func(_ *testing.T, cfg config.SessionConfig) Manager {
+		return NewMemorySession(cfg)
+	})
+}
@@ -1,56 +1,116 @@
 package session
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/gob"
 	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/http/client"
 	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
 )
 
 type DatabaseSession struct {
 	cfg   config.SessionConfig
 	store *sql.DB
+	codec Codec
 }
 
 var _ Manager = (*DatabaseSession)(nil)
+var _ Sweeper = (*DatabaseSession)(nil)
 
-func NewDatabaseSession(cfg config.SessionConfig, db *sql.DB) Manager {
-	gob.Register(Data{})
-
+func NewDatabaseSession(cfg config.SessionConfig, db *sql.DB, codec Codec) Manager {
 	return &DatabaseSession{
 		cfg:   cfg,
 		store: db,
+		codec: codec,
 	}
 }
 
-func (d *DatabaseSession) StoreSession(ctx context.Context, sessionID string, sessionData Data) error {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(sessionData); err != nil {
-		return fmt.Errorf("encode session data: %w", err)
+const storeSessionQuery = `
+INSERT INTO user_sessions (session_id, session_data, last_activity, expiry_time, user_id, ip_address, user_agent, login_time)
+VALUES ($1, $2, NOW(), $3, NULLIF($4, '')::uuid, NULLIF($5, '')::inet, NULLIF($6, ''), NOW())
+ON CONFLICT (session_id) DO UPDATE SET session_data = $2, last_activity = NOW(), expiry_time = $3,
+user_id = NULLIF($4, '')::uuid, ip_address = NULLIF($5, '')::inet, user_agent = NULLIF($6, '')`
+
+// Builds the arguments of the store session query
+func storeSessionArgs(ctx context.Context, sessionID string, encoded []byte, expiryTime time.Time, userID string) []any {
+	info, _ := client.FromContext(ctx)
+	return []any{sessionID, string(encoded), expiryTime, userID, info.IP, info.UserAgent}
+}
+
+func (d *DatabaseSession) StoreSession(ctx context.Context, sessionID string, sessionData Data) (string, error) {
+	encoded, err := d.codec.Encode(sessionData)
+
+	if err != nil {
+		return "", err
 	}
 
 	expiryTime := time.Now().Add(d.cfg.SessionDuration)
 
-	_, err := d.store.ExecContext(ctx,
-		`INSERT INTO user_sessions (session_id, session_data, last_activity, expiry_time)
-                VALUES ($1, $2, NOW(), $3)
-                ON CONFLICT (session_id) DO UPDATE SET session_data = $2, last_activity = NOW(), expiry_time = $3`,
-		sessionID, buf.Bytes(), expiryTime)
+	args := storeSessionArgs(ctx, sessionID, encoded, expiryTime, sessionData.UserID)
+	_, err = d.store.ExecContext(ctx, storeSessionQuery, args...)
 
 	if err != nil {
-		return fmt.Errorf("save session data: %w", err)
+		return "", fmt.Errorf("save session data: %w", err)
 	}
 
-	return nil
+	return sessionID, nil
+}
+
+func (d *DatabaseSession) Regenerate(ctx context.Context, oldSessionID string, sessionData Data) (string, error) {
+	encoded, err := d.codec.Encode(sessionData)
+
+	if err != nil {
+		return "", err
+	}
+
+	newSessionID, err := security.GenerateRandomBytesEncoded(64)
+
+	if err != nil {
+		return "", fmt.Errorf("generate session id: %w", err)
+	}
+
+	tx, err := d.store.BeginTx(ctx, nil)
+
+	if err != nil {
+		return "", fmt.Errorf("begin regenerate session: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	expiryTime := time.Now().Add(d.cfg.SessionDuration)
+
+	args := storeSessionArgs(ctx, newSessionID, encoded, expiryTime, sessionData.UserID)
+	if _, err := tx.ExecContext(ctx, storeSessionQuery, args...); err != nil {
+		return "", fmt.Errorf("save regenerated session: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_sessions WHERE session_id = $1", oldSessionID); err != nil {
+		return "", fmt.Errorf("delete old session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit regenerate session: %w", err)
+	}
+
+	return newSessionID, nil
 }
 
+// Loads the session data and records the activity of the session, unless it was recorded less than the
+// interval in $2 seconds ago, so that the sessions page is accurate without a write on every request
+const loadSessionQuery = `
+WITH touched AS (
+	UPDATE user_sessions SET last_activity = NOW()
+	WHERE session_id = $1 AND expiry_time > NOW() AND last_activity <= NOW() - make_interval(secs => $2)
+)
+SELECT session_data FROM user_sessions WHERE session_id = $1 AND expiry_time > NOW()`
+
 func (d *DatabaseSession) LoadSession(r *http.Request) (*Data, error) {
 	sessionID, err := d.ExtractSessionID(r)
 
@@ -59,22 +119,16 @@ func (d *DatabaseSession) LoadSession(r *http.Request) (*Data, error) {
 	}
 
 	var sessionData []byte
-	err = d.store.QueryRowContext(r.Context(), "SELECT session_data FROM user_sessions WHERE session_id = $1", sessionID).Scan(&sessionData)
+	err = d.store.QueryRowContext(r.Context(), loadSessionQuery, sessionID, d.cfg.ActivityInterval.Seconds()).Scan(&sessionData)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+			return nil, ErrSessionNotFound
 		}
 		return nil, fmt.Errorf("get session data: %w", err)
 	}
 
-	var data Data
-	err = gob.NewDecoder(bytes.NewReader(sessionData)).Decode(&data)
-	if err != nil {
-		return nil, fmt.Errorf("decode session data: %w", err)
-	}
-
-	return &data, nil
+	return d.codec.Decode(sessionData)
 }
 
 func (d *DatabaseSession) DestroySession(r *http.Request) error {
@@ -91,18 +145,105 @@ func (d *DatabaseSession) DestroySession(r *http.Request) error {
 	return nil
 }
 
-func (d *DatabaseSession) ExtractSessionID(r *http.Request) (string, error) {
-	session, err := r.Cookie(d.cfg.SessionName)
-	var sessionID string
+const listSessionsQuery = `
+SELECT session_id, COALESCE(host(ip_address), ''), COALESCE(user_agent, ''), login_time, last_activity
+FROM user_sessions
+WHERE user_id = $1 AND expiry_time > NOW()
+ORDER BY last_activity DESC`
+
+func (d *DatabaseSession) ListSessions(ctx context.Context, userID string) ([]Info, error) {
+	rows, err := d.store.QueryContext(ctx, listSessionsQuery, userID)
+
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+
+	defer rows.Close()
+
+	var sessions []Info
+
+	for rows.Next() {
+		var sessionID []byte
+		var info Info
+
+		if err := rows.Scan(&sessionID, &info.IPAddress, &info.UserAgent, &info.LoginTime, &info.LastActivity); err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+
+		info.Handle = Handle(string(sessionID))
+		sessions = append(sessions, info)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (d *DatabaseSession) RevokeSession(ctx context.Context, userID, handle string) (*Data, error) {
+	var sessionData []byte
+
+	err := d.store.QueryRowContext(ctx,
+		"DELETE FROM user_sessions WHERE user_id = $1 AND encode(sha256(session_id), 'hex') = $2 RETURNING session_data",
+		userID, handle).Scan(&sessionData)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+
+		return nil, fmt.Errorf("revoke session: %w", err)
+	}
+
+	return d.codec.Decode(sessionData)
+}
+
+func (d *DatabaseSession) RevokeUserSessions(ctx context.Context, userID, exceptSessionID string) error {
+	_, err := d.store.ExecContext(ctx,
+		"DELETE FROM user_sessions WHERE user_id = $1 AND session_id <> $2",
+		userID, exceptSessionID)
+
 	if err != nil {
-		sessionID, err = security.GenerateRandomBytesEncoded(64)
+		return fmt.Errorf("revoke user sessions: %w", err)
+	}
+
+	return nil
+}
+
+const deleteExpiredQuery = `
+DELETE FROM user_sessions
+WHERE session_id IN (
+	SELECT session_id FROM user_sessions
+	WHERE expiry_time <= NOW()
+	LIMIT $1
+)`
+
+// Deletes the expired sessions in batches until none is left.
+func (d *DatabaseSession) DeleteExpired(ctx context.Context) (int64, error) {
+	var total int64
+
+	for {
+		res, err := d.store.ExecContext(ctx, deleteExpiredQuery, d.cfg.CleanUpBatchSize)
 
 		if err != nil {
-			return "", fmt.Errorf("generate session id: %w", err)
+			return total, fmt.Errorf("delete expired sessions: %w", err)
+		}
+
+		deleted, err := res.RowsAffected()
+
+		if err != nil {
+			return total, fmt.Errorf("count deleted sessions: %w", err)
+		}
+
+		total += deleted
+
+		if deleted < int64(d.cfg.CleanUpBatchSize) {
+			return total, nil
 		}
-	} else {
-		sessionID = session.Value
 	}
+}
 
-	return sessionID, nil
+func (d *DatabaseSession) ExtractSessionID(r *http.Request) (string, error) {
+	return extractSessionID(r, d.cfg.SessionName)
 }
@@ -0,0 +1,53 @@
+//go:build integration
+
+package session
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+)
+
+func TestDatabaseSession(t *testing.T) {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("can't load the config: %v", err)
+	}
+
+	conn, err := db.Connect(ctx, cfg.DB)
+	if err != nil {
+		t.Fatalf("can't connect to the database: %v", err)
+	}
+
+	defer conn.Close()
+
+	var userID string
+	err = conn.QueryRowContext(ctx,
+		"INSERT INTO users (email, auth_method) VALUES ('session.conformance@example.com', 'email/password') RETURNING id").
+		Scan(&userID)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID); err != nil {
+			t.Errorf("delete user: %v", err)
+		}
+	}()
+
+	runConformance(t, userID, func(t *testing.T, cfg config.SessionConfig) Manager {
+		t.Cleanup(func() {
+			if _, err := conn.ExecContext(ctx, "DELETE FROM user_sessions WHERE user_id = $1", userID); err != nil {
+				t.Errorf("delete sessions: %v", err)
+			}
+		})
+
+		return NewDatabaseSession(cfg, conn, JSONCodec{})
+	})
+}
@@ -2,19 +2,56 @@ package session
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
 )
 
+var ErrSessionNotFound = errors.New("session not found or expired")
+
 type Data struct {
-	UserID string
-	Flash  map[string]string
+	UserID    string            `json:"user_id,omitempty"`
+	CSRFToken string            `json:"csrf_token,omitempty"`
+	Flash     map[string]string `json:"flash,omitempty"`
+
+	// Arbitrary values stored by features, use Get and Set to access them.
+	Values map[string]json.RawMessage `json:"values,omitempty"`
+}
+
+// Info describes an active session of a user.
+type Info struct {
+	// Opaque identifier of the session that is safe to expose to the client.
+	Handle       string    `json:"id"`
+	IPAddress    string    `json:"ip_address"`
+	UserAgent    string    `json:"user_agent"`
+	LoginTime    time.Time `json:"login_time"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// Derives the handle of a session from its id.
+//
+// Session ids are credentials, so they are never sent back to the client.
+func Handle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
 }
 
 type Manager interface {
 	// Saves a session.
-	StoreSession(context.Context, string, Data) error
+	// Returns the session id that must be sent to the client, which is only different
+	// from the given id for stores that keep the data in the cookie.
+	StoreSession(context.Context, string, Data) (string, error)
 
 	// Loads the session data from the request.
+	// Stores that list their sessions record the activity, at most once per SessionConfig.ActivityInterval.
 	LoadSession(*http.Request) (*Data, error)
 
 	// Extracts the session id from the request.
@@ -22,4 +59,59 @@ type Manager interface {
 
 	// Deletes the session from the request.
 	DestroySession(*http.Request) error
+
+	// Moves the session data to a new session id and deletes the old session.
+	// Returns the new session id.
+	Regenerate(ctx context.Context, oldSessionID string, data Data) (string, error)
+
+	// Lists the active sessions of a user, most recently active first.
+	ListSessions(ctx context.Context, userID string) ([]Info, error)
+
+	// Deletes the session of a user with the given handle.
+	// Returns the data that the session held.
+	RevokeSession(ctx context.Context, userID, handle string) (*Data, error)
+
+	// Deletes all the sessions of a user except the session with the given id.
+	// Pass an empty id to delete all of them.
+	RevokeUserSessions(ctx context.Context, userID, exceptSessionID string) error
+}
+
+// Names of the session stores that can be selected in the configuration
+const (
+	DatabaseStore = "database"
+	MemoryStore   = "memory"
+	CookieStore   = "cookie"
+)
+
+var ErrUnknownStore = errors.New("unknown session store")
+
+// Creates the session manager for the store selected in the configuration.
+func New(cfg config.SessionConfig, db *sql.DB) (Manager, error) {
+	switch cfg.Store {
+	case DatabaseStore, "":
+		return NewDatabaseSession(cfg, db, JSONCodec{}), nil
+	case MemoryStore:
+		return NewMemorySession(cfg), nil
+	case CookieStore:
+		return NewCookieSession(cfg)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStore, cfg.Store)
+	}
+}
+
+// Reads the session id from the session cookie, generating a new one when there is none.
+func extractSessionID(r *http.Request, sessionName string) (string, error) {
+	cookie, err := r.Cookie(sessionName)
+
+	if err == nil {
+		return cookie.Value, nil
+	}
+
+	sessionID, err := security.GenerateRandomBytesEncoded(64)
+
+	if err != nil {
+		return "", fmt.Errorf("generate session id: %w", err)
+	}
+
+	return sessionID, nil
 }
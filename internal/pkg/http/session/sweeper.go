@@ -0,0 +1,11 @@
+package session
+
+import (
+	"context"
+)
+
+// Sweeper is implemented by session stores that need expired sessions purged periodically.
+type Sweeper interface {
+	// Deletes the expired sessions and returns how many were removed.
+	DeleteExpired(context.Context) (int64, error)
+}
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Stores a value in the session data under the given key.
+//
+// The value must be serializable to json.
+func Set[T any](data *Data, key string, value T) error {
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Errorf("encode session value %q: %w", key, err)
+	}
+
+	if data.Values == nil {
+		data.Values = make(map[string]json.RawMessage)
+	}
+
+	data.Values[key] = encoded
+
+	return nil
+}
+
+// Retrieves the value stored in the session data under the given key.
+//
+// Reports false when there is no value for the key.
+func Get[T any](data *Data, key string) (T, bool, error) {
+	var value T
+
+	encoded, ok := data.Values[key]
+
+	if !ok {
+		return value, false, nil
+	}
+
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return value, false, fmt.Errorf("decode session value %q: %w", key, err)
+	}
+
+	return value, true, nil
+}
+
+// Removes the value stored in the session data under the given key.
+func Delete(data *Data, key string) {
+	delete(data.Values, key)
+}
@@ -0,0 +1,120 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Runs the behavior every store must implement against the store created by newStore.
+// Keys are prefixed with the name of the test, so that stores shared between tests stay isolated.
+func runConformance(t *testing.T, newStore func(t *testing.T) Store) {
+	ctx := context.Background()
+
+	t.Run("counts failures within the window", func(t *testing.T) {
+		store := newStore(t)
+		key := t.Name()
+
+		for want := 1; want <= 3; want++ {
+			got, err := store.AddFailure(ctx, key, time.Hour)
+			if err != nil {
+				t.Fatalf("add failure: %v", err)
+			}
+
+			if got != want {
+				t.Errorf("expected %d failures, got %d", want, got)
+			}
+		}
+	})
+
+	t.Run("starts over after the window", func(t *testing.T) {
+		store := newStore(t)
+		key := t.Name()
+
+		if _, err := store.AddFailure(ctx, key, time.Hour); err != nil {
+			t.Fatalf("add failure: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		got, err := store.AddFailure(ctx, key, time.Millisecond)
+		if err != nil {
+			t.Fatalf("add failure: %v", err)
+		}
+
+		if got != 1 {
+			t.Errorf("expected the count to start over, got %d failures", got)
+		}
+	})
+
+	t.Run("locks and resets", func(t *testing.T) {
+		store := newStore(t)
+		key := t.Name()
+
+		until, err := store.LockedUntil(ctx, key)
+		if err != nil {
+			t.Fatalf("locked until: %v", err)
+		}
+
+		if !until.IsZero() {
+			t.Errorf("expected an unknown key to be unlocked, got %v", until)
+		}
+
+		want := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+
+		if err := store.Lock(ctx, key, want); err != nil {
+			t.Fatalf("lock: %v", err)
+		}
+
+		until, err = store.LockedUntil(ctx, key)
+		if err != nil {
+			t.Fatalf("locked until: %v", err)
+		}
+
+		if !until.Equal(want) {
+			t.Errorf("expected lock until %v, got %v", want, until)
+		}
+
+		if err := store.Reset(ctx, key); err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+
+		until, err = store.LockedUntil(ctx, key)
+		if err != nil {
+			t.Fatalf("locked until: %v", err)
+		}
+
+		if !until.IsZero() {
+			t.Errorf("expected the lock to be removed, got %v", until)
+		}
+	})
+
+	t.Run("deletes expired keys", func(t *testing.T) {
+		store := newStore(t)
+		expired, locked := t.Name()+"/expired", t.Name()+"/locked"
+
+		for _, key := range []string{expired, locked} {
+			if _, err := store.AddFailure(ctx, key, time.Hour); err != nil {
+				t.Fatalf("add failure: %v", err)
+			}
+		}
+
+		if err := store.Lock(ctx, locked, time.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("lock: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		if _, err := store.DeleteExpired(ctx, time.Millisecond); err != nil {
+			t.Fatalf("delete expired: %v", err)
+		}
+
+		if got, _ := store.AddFailure(ctx, expired, time.Hour); got != 1 {
+			t.Errorf("expected the expired key to be deleted, got %d failures", got)
+		}
+
+		if until, _ := store.LockedUntil(ctx, locked); until.IsZero() {
+			t.Error("expected the locked key to be kept")
+		}
+	})
+}
@@ -0,0 +1,174 @@
+// Package lockout counts failed attempts and locks out whoever makes too many of them.
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+)
+
+var ErrLocked = errors.New("too many failed attempts, try again later")
+
+// LockedError is returned for a locked key with the time left until it can try again.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ErrLocked.Error()
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// Store keeps the failures and locks of the keys.
+type Store interface {
+	// Records a failure of the key and returns its failures, counting this one.
+	// The count starts over when the previous failure is older than the window.
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+
+	// Locks the key until the given time.
+	Lock(ctx context.Context, key string, until time.Time) error
+
+	// Returns when the lock of the key ends, the zero time when it is not locked.
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+
+	// Forgets the failures and the lock of the key.
+	Reset(ctx context.Context, key string) error
+
+	// Deletes the keys that are not locked and had no failure within the window.
+	// Returns how many were removed.
+	DeleteExpired(ctx context.Context, window time.Duration) (int64, error)
+}
+
+// Names of the stores that can be selected in the configuration
+const (
+	DatabaseStore = "database"
+	MemoryStore   = "memory"
+)
+
+var ErrUnknownStore = errors.New("unknown lockout store")
+
+// Creates the store selected in the configuration.
+func New(cfg config.LockoutConfig, db *sql.DB) (Store, error) {
+	switch cfg.Store {
+	case DatabaseStore, "":
+		return NewDatabaseLockout(db), nil
+	case MemoryStore:
+		return NewMemoryLockout(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStore, cfg.Store)
+	}
+}
+
+// Longest lockout, however many failures there are
+const maxLockout = 24 * time.Hour
+
+// Policy decides how long a key is locked after its failures.
+type Policy struct {
+	// Failures before the key is locked out.
+	Threshold int
+
+	// Failures are forgotten after this long without another one.
+	Window time.Duration
+
+	// Length of the first lockout, doubled by every failure after it.
+	Duration time.Duration
+
+	// Wait after the first failure, doubled by every failure until the threshold.
+	// No wait when zero.
+	Delay time.Duration
+}
+
+// Returns how long the key is locked after the given number of failures
+func (p Policy) lockFor(failures int) time.Duration {
+	if failures < p.Threshold {
+		if p.Delay <= 0 || failures < 1 {
+			return 0
+		}
+
+		return min(double(p.Delay, failures-1), p.Duration)
+	}
+
+	return min(double(p.Duration, failures-p.Threshold), maxLockout)
+}
+
+// Doubles d n times, saturating instead of overflowing
+func double(d time.Duration, n int) time.Duration {
+	for ; n > 0 && d < maxLockout; n-- {
+		d *= 2
+	}
+
+	return d
+}
+
+// Limiter applies a policy to the keys of one kind, e.g. accounts or client addresses.
+type Limiter struct {
+	name   string
+	store  Store
+	policy Policy
+}
+
+// Creates a limiter whose keys are stored prefixed with the name.
+func NewLimiter(name string, store Store, policy Policy) *Limiter {
+	return &Limiter{
+		name:   name,
+		store:  store,
+		policy: policy,
+	}
+}
+
+func (l *Limiter) key(key string) string {
+	return l.name + ":" + key
+}
+
+// Check returns a *LockedError when the key is locked.
+func (l *Limiter) Check(ctx context.Context, key string) error {
+	until, err := l.store.LockedUntil(ctx, l.key(key))
+
+	if err != nil {
+		return fmt.Errorf("check lockout: %w", err)
+	}
+
+	if wait := time.Until(until); wait > 0 {
+		return &LockedError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// Fail records a failure of the key and locks it as the policy says.
+// Returns a *LockedError when the key is now locked.
+func (l *Limiter) Fail(ctx context.Context, key string) error {
+	failures, err := l.store.AddFailure(ctx, l.key(key), l.policy.Window)
+
+	if err != nil {
+		return fmt.Errorf("record failure: %w", err)
+	}
+
+	wait := l.policy.lockFor(failures)
+
+	if wait <= 0 {
+		return nil
+	}
+
+	if err := l.store.Lock(ctx, l.key(key), time.Now().Add(wait)); err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+
+	return &LockedError{RetryAfter: wait}
+}
+
+// Reset forgets the failures of the key, e.g. after it succeeded.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	if err := l.store.Reset(ctx, l.key(key)); err != nil {
+		return fmt.Errorf("reset lockout: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build !integration
+
+package lockout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPolicyLockFor(t *testing.T) {
+	policy := Policy{
+		Threshold: 4,
+		Window:    time.Hour,
+		Duration:  15 * time.Minute,
+		Delay:     time.Second,
+	}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 15 * time.Minute},
+		{5, 30 * time.Minute},
+		{6, time.Hour},
+		{100, maxLockout},
+	}
+
+	for _, tt := range tests {
+		if got := policy.lockFor(tt.failures); got != tt.want {
+			t.Errorf("lockFor(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	policy.Delay = 0
+
+	if got := policy.lockFor(3); got != 0 {
+		t.Errorf("expected no delay without a delay configured, got %v", got)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryLockout()
+	accounts := NewLimiter("account", store, Policy{Threshold: 2, Window: time.Hour, Duration: time.Minute})
+	addresses := NewLimiter("ip", store, Policy{Threshold: 2, Window: time.Hour, Duration: time.Minute})
+
+	if err := accounts.Fail(ctx, "jane@example.com"); err != nil {
+		t.Fatalf("expected the first failure to be tolerated, got %v", err)
+	}
+
+	err := accounts.Fail(ctx, "jane@example.com")
+
+	var locked *LockedError
+	if !errors.As(err, &locked) || locked.RetryAfter != time.Minute {
+		t.Fatalf("expected a lockout of a minute, got %v", err)
+	}
+
+	err = accounts.Check(ctx, "jane@example.com")
+
+	if !errors.Is(err, ErrLocked) || !errors.As(err, &locked) || locked.RetryAfter <= 0 || locked.RetryAfter > time.Minute {
+		t.Errorf("expected the account to be locked, got %v", err)
+	}
+
+	// Keys of other limiters sharing the store are counted apart
+	if err := addresses.Check(ctx, "jane@example.com"); err != nil {
+		t.Errorf("expected the address to be unlocked, got %v", err)
+	}
+
+	if err := accounts.Reset(ctx, "jane@example.com"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	if err := accounts.Check(ctx, "jane@example.com"); err != nil {
+		t.Errorf("expected the account to be unlocked after a reset, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// MemoryLockout keeps the counters in process memory.
+//
+// Counters are lost on restart and are not shared between instances,
+// so it is meant for single-instance deployments and tests.
+type MemoryLockout struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+var _ Store = (*MemoryLockout)(nil)
+
+func NewMemoryLockout() Store {
+	return &MemoryLockout{
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+func (m *MemoryLockout) AddFailure(_ context.Context, key string, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := m.entries[key]
+
+	if !ok {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+
+	if now.Sub(entry.lastFailure) > window {
+		entry.failures = 0
+	}
+
+	entry.failures++
+	entry.lastFailure = now
+
+	return entry.failures, nil
+}
+
+func (m *MemoryLockout) Lock(_ context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+
+	if !ok {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+
+	entry.lockedUntil = until
+
+	return nil
+}
+
+func (m *MemoryLockout) LockedUntil(_ context.Context, key string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	return entry.lockedUntil, nil
+}
+
+func (m *MemoryLockout) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+
+	return nil
+}
+
+func (m *MemoryLockout) DeleteExpired(_ context.Context, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	var deleted int64
+
+	for key, entry := range m.entries {
+		if now.Sub(entry.lastFailure) > window && now.After(entry.lockedUntil) {
+			delete(m.entries, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
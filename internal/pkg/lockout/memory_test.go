@@ -0,0 +1,11 @@
+//go:build !integration
+
+package lockout
+
+import "testing"
+
+func TestMemoryLockout(t *testing.T) {
+	runConformance(t, func(*testing.T) Store {
+		return NewMemoryLockout()
+	})
+}
@@ -0,0 +1,87 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DatabaseLockout keeps the counters in the login_attempts table, shared by all the instances.
+type DatabaseLockout struct {
+	db *sql.DB
+}
+
+var _ Store = (*DatabaseLockout)(nil)
+
+func NewDatabaseLockout(db *sql.DB) Store {
+	return &DatabaseLockout{db: db}
+}
+
+// Incremented in one statement, so that concurrent failures are all counted
+const addFailureQuery = `
+INSERT INTO login_attempts (key, failures, last_failure_at)
+VALUES ($1, 1, NOW())
+ON CONFLICT (key) DO UPDATE SET
+failures = CASE WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => $2) THEN 1 ELSE login_attempts.failures + 1 END,
+last_failure_at = NOW()
+RETURNING failures`
+
+func (d *DatabaseLockout) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures int
+
+	if err := d.db.QueryRowContext(ctx, addFailureQuery, key, window.Seconds()).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("add failure: %w", err)
+	}
+
+	return failures, nil
+}
+
+const lockQuery = `
+INSERT INTO login_attempts (key, failures, last_failure_at, locked_until)
+VALUES ($1, 0, NOW(), $2)
+ON CONFLICT (key) DO UPDATE SET locked_until = $2`
+
+func (d *DatabaseLockout) Lock(ctx context.Context, key string, until time.Time) error {
+	if _, err := d.db.ExecContext(ctx, lockQuery, key, until); err != nil {
+		return fmt.Errorf("lock: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DatabaseLockout) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	const q = "SELECT locked_until FROM login_attempts WHERE key = $1"
+
+	var until sql.NullTime
+
+	if err := d.db.QueryRowContext(ctx, q, key).Scan(&until); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("find lock: %w", err)
+	}
+
+	return until.Time, nil
+}
+
+func (d *DatabaseLockout) Reset(ctx context.Context, key string) error {
+	if _, err := d.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key); err != nil {
+		return fmt.Errorf("reset: %w", err)
+	}
+
+	return nil
+}
+
+func (d *DatabaseLockout) DeleteExpired(ctx context.Context, window time.Duration) (int64, error) {
+	const q = `
+DELETE FROM login_attempts
+WHERE last_failure_at < NOW() - make_interval(secs => $1)
+AND (locked_until IS NULL OR locked_until < NOW())`
+
+	res, err := d.db.ExecContext(ctx, q, window.Seconds())
+
+	if err != nil {
+		return 0, fmt.Errorf("delete expired login attempts: %w", err)
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,39 @@
+//go:build integration
+
+package lockout
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/db"
+)
+
+func TestDatabaseLockout(t *testing.T) {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("can't load the config: %v", err)
+	}
+
+	conn, err := db.Connect(ctx, cfg.DB)
+	if err != nil {
+		t.Fatalf("can't connect to the database: %v", err)
+	}
+
+	defer conn.Close()
+
+	runConformance(t, func(t *testing.T) Store {
+		t.Cleanup(func() {
+			if _, err := conn.ExecContext(ctx, "DELETE FROM login_attempts WHERE key LIKE $1", t.Name()+"%"); err != nil {
+				t.Errorf("delete login attempts: %v", err)
+			}
+		})
+
+		return NewDatabaseLockout(conn)
+	})
+}
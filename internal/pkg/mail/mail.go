@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+)
+
+// Names of the mailers that can be selected in the configuration
+const (
+	LogDriver  = "log"
+	FileDriver = "file"
+)
+
+var (
+	ErrUnknownDriver = errors.New("unknown mail driver")
+	ErrLogDriver     = errors.New("the log mail driver only works in development")
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(context.Context, Message) error
+}
+
+// Creates the mailer selected in the configuration.
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case LogDriver, "":
+		// The messages never reach anyone, and the links in them would end up in the logs
+		if cfg.AppEnv != "development" {
+			return nil, fmt.Errorf("%w, APP_ENV is %q", ErrLogDriver, cfg.AppEnv)
+		}
+
+		return &LogMailer{from: cfg.From}, nil
+	case FileDriver:
+		return NewFileMailer(cfg.From, cfg.Dir), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, cfg.Driver)
+	}
+}
+
+// LogMailer writes the messages to the application log instead of delivering them.
+// The bodies are only logged at the debug level, since they carry the links that sign users in.
+type LogMailer struct {
+	from string
+}
+
+var _ Mailer = (*LogMailer)(nil)
+
+func (l *LogMailer) Send(_ context.Context, msg Message) error {
+	slog.Info("Mail sent", "from", l.from, "to", msg.To, "subject", msg.Subject)
+	slog.Debug("Mail body", "to", msg.To, "body", msg.Body)
+	return nil
+}
+
+// FileMailer writes each message to a file in a directory, where it can be inspected during development and tests.
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+var _ Mailer = (*FileMailer)(nil)
+
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{
+		from: from,
+		dir:  dir,
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+func (f *FileMailer) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(f.dir, 0o750); err != nil {
+		return fmt.Errorf("create mail directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	path := filepath.Join(f.dir, name)
+
+	if err := os.WriteFile(path, []byte(f.format(msg)), 0o600); err != nil {
+		return fmt.Errorf("write mail: %w", err)
+	}
+
+	slog.Debug("Mail written", "path", path)
+
+	return nil
+}
+
+// Formats the message as a plain text email
+func (f *FileMailer) format(msg Message) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", f.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	return b.String()
+}
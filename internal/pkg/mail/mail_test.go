@@ -0,0 +1,73 @@
+//go:build !integration
+
+package mail
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer("noreply@example.com", dir)
+
+	msg := Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "Hello there!",
+	}
+
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*-user@example.com.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected 1 mail file, got %v (err: %v)", files, err)
+	}
+
+	contents, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	for _, expected := range []string{"From: noreply@example.com", "To: user@example.com", "Subject: Hello", "Hello there!"} {
+		if !strings.Contains(string(contents), expected) {
+			t.Errorf("expected mail to contain %q, got:\n%s", expected, contents)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.MailConfig
+		wantErr error
+	}{
+		{name: "Log driver in development", cfg: config.MailConfig{Driver: LogDriver, AppEnv: "development"}},
+		{name: "Log driver in production", cfg: config.MailConfig{Driver: LogDriver, AppEnv: "production"}, wantErr: ErrLogDriver},
+		{name: "Default driver in production", cfg: config.MailConfig{AppEnv: "production"}, wantErr: ErrLogDriver},
+		{name: "File driver in production", cfg: config.MailConfig{Driver: FileDriver, AppEnv: "production"}},
+		{name: "Unknown driver", cfg: config.MailConfig{Driver: "smtp", AppEnv: "development"}, wantErr: ErrUnknownDriver},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mailer, err := New(tt.cfg)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			if err == nil && mailer == nil {
+				t.Error("expected a mailer")
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Package oidc implements the OpenID Connect authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+)
+
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// Metadata is the subset of the provider metadata used by the authorization code flow.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect provider. Its metadata and signing keys are discovered on first use.
+type Provider struct {
+	cfg         config.OIDCProviderConfig
+	redirectURL string
+	client      *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     map[string]*rsa.PublicKey
+}
+
+// NewProvider creates a provider that redirects back to redirectURL.
+// A default client with a timeout is used when client is nil.
+func NewProvider(cfg config.OIDCProviderConfig, redirectURL string, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Provider{
+		cfg:         cfg,
+		redirectURL: redirectURL,
+		client:      client,
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) Label() string {
+	return p.cfg.Label
+}
+
+// Retrieves the provider metadata from the discovery document of the issuer
+func (p *Provider) discover(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+
+	var metadata Metadata
+
+	if err := p.getJSON(ctx, wellKnown, &metadata); err != nil {
+		return nil, fmt.Errorf("discover %s: %w", p.cfg.Name, err)
+	}
+
+	if metadata.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discover %s: issuer %q does not match the configured issuer %q", p.cfg.Name, metadata.Issuer, p.cfg.Issuer)
+	}
+
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("discover %s: incomplete provider metadata", p.cfg.Name)
+	}
+
+	p.metadata = &metadata
+
+	return p.metadata, nil
+}
+
+// AuthCodeURL returns the url of the provider where the user is sent to authenticate.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", Challenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return metadata.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// Tokens is the response of the token endpoint.
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+}
+
+type tokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange trades the authorization code and the PKCE verifier for tokens.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*Tokens, error) {
+	metadata, err := p.discover(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return nil, fmt.Errorf("create token request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var tokenErr tokenError
+		_ = json.NewDecoder(res.Body).Decode(&tokenErr)
+		return nil, fmt.Errorf("token request: %s: %s %s", res.Status, tokenErr.Error, tokenErr.ErrorDescription)
+	}
+
+	var tokens Tokens
+
+	if err := json.NewDecoder(res.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("decode token response: %w", err)
+	}
+
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response: %w: missing id_token", ErrInvalidIDToken)
+	}
+
+	return &tokens, nil
+}
+
+// Authenticate exchanges the authorization code and returns the verified claims of the id token.
+func (p *Provider) Authenticate(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	tokens, err := p.Exchange(ctx, code, verifier)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
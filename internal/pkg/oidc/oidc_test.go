@@ -0,0 +1,190 @@
+//go:build !integration
+
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/oidc/oidctest"
+)
+
+const redirectURL = "http://app.test/auth/oidc/stub/callback"
+
+// Follows the authorization url and returns the code and state sent back to the redirect url
+func authorize(t *testing.T, issuer *oidctest.Issuer, authURL string) (string, string) {
+	t.Helper()
+
+	client := issuer.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	res, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: expected status %d, got %d", http.StatusFound, res.StatusCode)
+	}
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parse redirect: %v", err)
+	}
+
+	if !strings.HasPrefix(location.String(), redirectURL) {
+		t.Fatalf("expected redirect to %s, got %s", redirectURL, location)
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer := oidctest.NewIssuer("client-1", "secret-1")
+	defer issuer.Close()
+
+	provider := NewProvider(issuer.Config("stub"), redirectURL, issuer.Client())
+	ctx := context.Background()
+
+	verifier, err := RandomString()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	code, state := authorize(t, issuer, authURL)
+
+	if state != "state-1" {
+		t.Errorf("expected state %q, got %q", "state-1", state)
+	}
+
+	claims, err := provider.Authenticate(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if claims.Subject != issuer.Subject || claims.Email != issuer.Email || !bool(claims.EmailVerified) {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	if _, err := provider.Authenticate(ctx, code, verifier, "nonce-1"); err == nil {
+		t.Error("expected the code to be usable only once")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	issuer := oidctest.NewIssuer("client-1", "secret-1")
+	defer issuer.Close()
+
+	provider := NewProvider(issuer.Config("stub"), redirectURL, issuer.Client())
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	code, _ := authorize(t, issuer, authURL)
+
+	if _, err := provider.Exchange(ctx, code, "verifier-2"); err == nil {
+		t.Fatal("expected an error for a verifier that does not match the challenge")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := oidctest.NewIssuer("client-1", "secret-1")
+	defer issuer.Close()
+
+	other := oidctest.NewIssuer("client-1", "secret-1")
+	defer other.Close()
+
+	provider := NewProvider(issuer.Config("stub"), redirectURL, issuer.Client())
+
+	tests := []struct {
+		name    string
+		token   func() string
+		nonce   string
+		wantErr bool
+	}{
+		{"valid", func() string { return issuer.IDToken(issuer.Claims("nonce-1")) }, "nonce-1", false},
+		{"wrong nonce", func() string { return issuer.IDToken(issuer.Claims("nonce-1")) }, "nonce-2", true},
+		{"expired", func() string {
+			claims := issuer.Claims("nonce-1")
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			return issuer.IDToken(claims)
+		}, "nonce-1", true},
+		{"other audience", func() string {
+			claims := issuer.Claims("nonce-1")
+			claims["aud"] = "client-2"
+			return issuer.IDToken(claims)
+		}, "nonce-1", true},
+		{"other issuer", func() string {
+			claims := issuer.Claims("nonce-1")
+			claims["iss"] = other.URL
+			return issuer.IDToken(claims)
+		}, "nonce-1", true},
+		{"signed by another key", func() string {
+			claims := issuer.Claims("nonce-1")
+			return other.IDToken(claims)
+		}, "nonce-1", true},
+		{"tampered", func() string {
+			parts := strings.Split(issuer.IDToken(issuer.Claims("nonce-1")), ".")
+			claims := issuer.Claims("nonce-1")
+			claims["email"] = "attacker@example.com"
+			forged := strings.Split(issuer.IDToken(claims), ".")
+			return parts[0] + "." + forged[1] + "." + parts[2]
+		}, "nonce-1", true},
+		{"malformed", func() string { return "not-a-token" }, "nonce-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(context.Background(), tt.token(), tt.nonce)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("expected ErrInvalidIDToken, got: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	// Example from RFC 7636, Appendix B
+	got := Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if got != want {
+		t.Errorf("Challenge() = %q, want %q", got, want)
+	}
+}
+
+func TestRandomString(t *testing.T) {
+	s, err := RandomString()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// RFC 7636, section 4.1: 43 to 128 unreserved characters
+	if len(s) < 43 || len(s) > 128 || strings.ContainsAny(s, "=+/") {
+		t.Errorf("expected a valid PKCE verifier, got %q", s)
+	}
+}
@@ -0,0 +1,22 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+// Bytes of a random string. A multiple of 3, so that its encoding has no padding, which a PKCE verifier cannot contain.
+const randomStringLength = 33
+
+// RandomString returns a url-safe random string suitable for a state, a nonce or a PKCE verifier.
+func RandomString() (string, error) {
+	return security.GenerateRandomBytesEncoded(randomStringLength)
+}
+
+// Challenge derives the S256 PKCE code challenge of the verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
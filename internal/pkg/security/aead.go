@@ -0,0 +1,68 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+)
+
+// Length of the keys used by Encrypt and Decrypt
+const EncryptionKeyLength = 32 // 32 bytes for AES-256
+
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Encrypt encrypts and authenticates the plaintext with AES-256-GCM.
+// The random nonce is prepended to the returned ciphertext.
+func Encrypt(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := GenerateRandomBytes(uint32(aead.NonceSize())) // #nosec G115 -- Nonce size is a small constant
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts a ciphertext produced by Encrypt, failing if it was tampered with.
+func Decrypt(key, ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCiphertext, err)
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptionKeyLength {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeyLength, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	return aead, nil
+}
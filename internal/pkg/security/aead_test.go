@@ -0,0 +1,43 @@
+//go:build !integration
+
+package security
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateRandomBytes(EncryptionKeyLength)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	plaintext := []byte("secret message")
+
+	ciphertext, err := Encrypt(key, plaintext)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	decrypted, err := Decrypt(key, ciphertext)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("expected %q, got %q", plaintext, decrypted)
+	}
+
+	// Tampering with the ciphertext must be detected
+	ciphertext[len(ciphertext)-1] ^= 0xff
+
+	if _, err := Decrypt(key, ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("expected ErrInvalidCiphertext, got: %v", err)
+	}
+
+	if _, err := Encrypt([]byte("short"), plaintext); err == nil {
+		t.Fatal("expected an error for an invalid key length")
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"golang.org/x/crypto/argon2"
 )
 
-// Parameters for the Argon2ID algorithm
+// Default parameters for the Argon2ID algorithm
 const (
 	Memory      = 64 * 1024 // 64 MB
 	Iterations  = 3
@@ -19,66 +19,142 @@ const (
 	KeyLength   = 32 // 32 bytes
 )
 
-// GenerateHash generates a hashed password using Argon2ID.
-func GenerateHash(password string) (string, error) {
+var ErrInvalidHash = errors.New("invalid hash format")
+
+// Argon2Params are the cost parameters of new password hashes.
+type Argon2Params struct {
+	Memory      uint32 // In KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params returns the parameters used by GenerateHash.
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      Memory,
+		Iterations:  Iterations,
+		Parallelism: Parallelism,
+		SaltLength:  SaltLength,
+		KeyLength:   KeyLength,
+	}
+}
+
+// PasswordHasher hashes passwords with Argon2ID using the given parameters.
+type PasswordHasher struct {
+	params Argon2Params
+}
+
+func NewPasswordHasher(params Argon2Params) *PasswordHasher {
+	return &PasswordHasher{params: params}
+}
+
+// Hash hashes the password in the PHC string format.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	p := h.params
+
 	// Generate a random salt
-	salt, err := GenerateRandomBytes(SaltLength)
+	salt, err := GenerateRandomBytes(p.SaltLength)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate salt: %w", err)
 	}
 
 	// Hash the password
-	hash := argon2.IDKey([]byte(password), salt, Iterations, Memory, Parallelism, KeyLength)
+	hash := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
 
 	// Encode the salt and hash for storage
 	saltBase64 := base64.RawStdEncoding.EncodeToString(salt)
 	hashBase64 := base64.RawStdEncoding.EncodeToString(hash)
 
 	// Return the formatted password hash
-	return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s",
-		Memory, Iterations, Parallelism, saltBase64, hashBase64), nil
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism, saltBase64, hashBase64), nil
 }
 
-// VerifyPassword compares a plain password with a hashed password.
-func VerifyPassword(password, hashedPassword string) (bool, error) {
+// Verify compares a plain password with a hashed password, using the parameters stored in the hash.
+//
+// Rehash reports whether the hash is weaker than the parameters of the hasher, or was made by Argon2i
+// instead of Argon2id, so that it should be replaced with a new hash of the password.
+// Hashes of another version of Argon2 cannot be computed, so no password matches them.
+func (h *PasswordHasher) Verify(password, hashedPassword string) (match, rehash bool, err error) {
 	parts := strings.Split(hashedPassword, "$")
 	if len(parts) != 6 {
-		return false, errors.New("invalid hash format")
+		return false, false, ErrInvalidHash
+	}
+
+	var keyFunc func(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte
+
+	switch parts[1] {
+	case "argon2id":
+		keyFunc = argon2.IDKey
+	case "argon2i":
+		keyFunc = argon2.Key
+	default:
+		return false, false, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidHash, parts[1])
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, fmt.Errorf("%w: invalid version %q", ErrInvalidHash, parts[2])
+	}
+
+	// Hashes of versions before 1.3 cannot be computed anymore, their users have to reset their password
+	if version != argon2.Version {
+		return false, false, nil
 	}
 
 	// Extract parameters and the salt/hash values
 	var memory uint32
 	var iterations uint32
 	var parallelism uint8
-	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism)
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism)
 	if err != nil {
-		return false, fmt.Errorf("failed to parse hash parameters: %w", err)
+		return false, false, fmt.Errorf("failed to parse hash parameters: %w", err)
 	}
 
 	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, fmt.Errorf("failed to decode salt: %w", err)
+		return false, false, fmt.Errorf("failed to decode salt: %w", err)
 	}
 
 	expectedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
 	if err != nil {
-		return false, fmt.Errorf("failed to decode hash: %w", err)
+		return false, false, fmt.Errorf("failed to decode hash: %w", err)
 	}
 
 	// Check if len(expectedHash) can safely fit in a uint32
 	hashLen := len(expectedHash)
 
 	if hashLen > int(^uint32(0)) { // ^uint32(0) gives the max value of uint32
-		return false, errors.New("expected hash length exceeds uint32 limits")
+		return false, false, errors.New("expected hash length exceeds uint32 limits")
 	}
 
 	// Compute the hash with the same parameters
-	computedHash := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(hashLen))
+	computedHash := keyFunc([]byte(password), salt, iterations, memory, parallelism, uint32(hashLen))
 
 	// Constant time comparison to prevent timing attacks
-	if subtle.ConstantTimeCompare(computedHash, expectedHash) == 1 {
-		return true, nil
+	if subtle.ConstantTimeCompare(computedHash, expectedHash) != 1 {
+		return false, false, nil
 	}
 
-	return false, nil
+	p := h.params
+	rehash = parts[1] != "argon2id" ||
+		memory < p.Memory ||
+		iterations < p.Iterations ||
+		uint32(len(salt)) < p.SaltLength ||
+		uint32(hashLen) < p.KeyLength
+
+	return true, rehash, nil
+}
+
+// GenerateHash generates a hashed password using Argon2ID with the default parameters.
+func GenerateHash(password string) (string, error) {
+	return NewPasswordHasher(DefaultArgon2Params()).Hash(password)
+}
+
+// VerifyPassword compares a plain password with a hashed password.
+func VerifyPassword(password, hashedPassword string) (bool, error) {
+	match, _, err := NewPasswordHasher(DefaultArgon2Params()).Verify(password, hashedPassword)
+	return match, err
 }
@@ -3,8 +3,12 @@
 package security
 
 import (
+	"encoding/base64"
+	"errors"
 	"strings"
 	"testing"
+
+	"golang.org/x/crypto/argon2"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -90,3 +94,88 @@ func isValidHashFormat(hash string) bool {
 	parts := strings.Split(hash, "$")
 	return len(parts) == 6 && parts[1] == "argon2id"
 }
+
+func TestPasswordHasherRehash(t *testing.T) {
+	password := "secure_password"
+
+	weak := Argon2Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	strong := Argon2Params{Memory: 16 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	weakHash, err := NewPasswordHasher(weak).Hash(password)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	strongHash, err := NewPasswordHasher(strong).Hash(password)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	stronger := strong
+	stronger.Memory *= 2
+
+	strongerHash, err := NewPasswordHasher(stronger).Hash(password)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Argon2i hash of "secure_password" with the weak parameters
+	salt := []byte("0123456789abcdef")
+	argon2iHash := "$argon2i$v=19$m=8192,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(argon2.Key([]byte(password), salt, 1, 8*1024, 1, 32))
+
+	tests := []struct {
+		name   string
+		hash   string
+		match  bool
+		rehash bool
+	}{
+		{"weaker parameters", weakHash, true, true},
+		{"current parameters", strongHash, true, false},
+		{"stronger parameters", strongerHash, true, false},
+		{"older algorithm", argon2iHash, true, true},
+	}
+
+	hasher := NewPasswordHasher(strong)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, rehash, err := hasher.Verify(password, tt.hash)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+
+			if match != tt.match || rehash != tt.rehash {
+				t.Errorf("expected match %v and rehash %v, got %v and %v", tt.match, tt.rehash, match, rehash)
+			}
+		})
+	}
+
+	if match, rehash, _ := hasher.Verify("wrong_password", weakHash); match || rehash {
+		t.Errorf("expected a wrong password not to match nor need a rehash, got %v and %v", match, rehash)
+	}
+}
+
+func TestPasswordHasherRejectsUnsupportedHashes(t *testing.T) {
+	hasher := NewPasswordHasher(DefaultArgon2Params())
+
+	for _, hash := range []string{
+		"",
+		"$argon2id$version$m=65536,t=3,p=2$c2FsdA$aGFzaA",
+		"$scrypt$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA",
+	} {
+		if _, _, err := hasher.Verify("password", hash); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("expected %v for %q, got %v", ErrInvalidHash, hash, err)
+		}
+	}
+}
+
+func TestPasswordHasherDoesNotMatchOtherVersions(t *testing.T) {
+	hasher := NewPasswordHasher(DefaultArgon2Params())
+
+	match, rehash, err := hasher.Verify("password", "$argon2id$v=16$m=65536,t=3,p=2$c2FsdA$aGFzaA")
+
+	if err != nil || match || rehash {
+		t.Errorf("expected no match without an error, got %v, %v and %v", match, rehash, err)
+	}
+}
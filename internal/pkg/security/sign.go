@@ -0,0 +1,29 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Sign computes an HMAC-SHA256 signature of the parts using the key.
+//
+// Each part is length-prefixed so that ("ab", "c") and ("a", "bc") sign differently.
+func Sign(key []byte, parts ...string) string {
+	mac := hmac.New(sha256.New, key)
+
+	for _, part := range parts {
+		mac.Write([]byte(strconv.Itoa(len(part))))
+		mac.Write([]byte{':'})
+		mac.Write([]byte(part))
+	}
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is the signature of the parts, in constant time.
+func VerifySignature(key []byte, signature string, parts ...string) bool {
+	expected := Sign(key, parts...)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
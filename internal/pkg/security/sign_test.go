@@ -0,0 +1,32 @@
+//go:build !integration
+
+package security
+
+import "testing"
+
+func TestVerifySignature(t *testing.T) {
+	key := []byte("signing-key")
+	sig := Sign(key, "user-1", "user@example.com", "1700000000")
+
+	tests := []struct {
+		name  string
+		key   []byte
+		sig   string
+		parts []string
+		want  bool
+	}{
+		{"valid", key, sig, []string{"user-1", "user@example.com", "1700000000"}, true},
+		{"tampered part", key, sig, []string{"user-2", "user@example.com", "1700000000"}, false},
+		{"shifted boundary", key, sig, []string{"user-1user@example.com", "", "1700000000"}, false},
+		{"wrong key", []byte("other-key"), sig, []string{"user-1", "user@example.com", "1700000000"}, false},
+		{"empty signature", key, "", []string{"user-1", "user@example.com", "1700000000"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.key, tt.sig, tt.parts...); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
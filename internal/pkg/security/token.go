@@ -0,0 +1,15 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken hashes a high-entropy random token for storage.
+//
+// Tokens are random enough that a fast unsalted hash cannot be brute forced,
+// and a deterministic hash lets the token be looked up by its hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 -- SHA-1 is the algorithm of RFC 6238 supported by authenticator apps
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parameters of the time-based one-time passwords, the defaults of authenticator apps
+const (
+	TOTPSecretLength = 20 // 160 bits, as recommended by RFC 4226
+	TOTPDigits       = 6
+	TOTPPeriod       = 30 * time.Second
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret generates a random secret for time-based one-time passwords.
+func GenerateTOTPSecret() ([]byte, error) {
+	return GenerateRandomBytes(TOTPSecretLength)
+}
+
+// EncodeTOTPSecret encodes the secret in base32, the format entered in authenticator apps.
+func EncodeTOTPSecret(secret []byte) string {
+	return totpEncoding.EncodeToString(secret)
+}
+
+// TOTPStep returns the time step of t.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// TOTPCode computes the one-time password of the secret for a time step (RFC 6238).
+func TOTPCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step)) // #nosec G115 -- Steps are positive
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", TOTPDigits, value%1_000_000)
+}
+
+// VerifyTOTP checks the code against the steps around t, allowing for the drift of the clock of the device.
+// Returns the matching step, so that callers can reject codes that were already used.
+func VerifyTOTP(secret []byte, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(t)
+
+	for _, step := range []int64{current, current - 1, current + 1} {
+		if subtle.ConstantTimeCompare([]byte(TOTPCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// TOTPURI returns the otpauth:// uri that authenticator apps read from a QR code.
+func TOTPURI(issuer, account string, secret []byte) string {
+	query := url.Values{}
+	query.Set("secret", EncodeTOTPSecret(secret))
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
@@ -0,0 +1,71 @@
+//go:build !integration
+
+package security
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors of RFC 6238 appendix B for SHA-1, truncated to six digits
+func TestTOTPCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got := TOTPCode(secret, TOTPStep(time.Unix(tt.unix, 0)))
+
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+
+	tests := []struct {
+		name   string
+		code   string
+		wantOK bool
+	}{
+		{"current step", TOTPCode(secret, step), true},
+		{"previous step", TOTPCode(secret, step-1), true},
+		{"next step", TOTPCode(secret, step+1), true},
+		{"too old", TOTPCode(secret, step-2), false},
+		{"wrong length", "12345", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := VerifyTOTP(secret, tt.code, now); ok != tt.wantOK {
+				t.Errorf("VerifyTOTP() = %v, want %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("My App", "user@example.com", []byte("12345678901234567890"))
+
+	for _, want := range []string{"otpauth://totp/My%20App:user@example.com?", "secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "issuer=My+App"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("expected %q to contain %q", uri, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package validation
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A list of passwords known from data breaches
+type BreachedPasswords interface {
+	Contains(password string) (bool, error)
+}
+
+// Length of a hex encoded SHA-1 hash
+const sha1HexLength = sha1.Size * 2
+
+// Longest line read from a breached passwords file, a hash and a count
+const maxBreachedLine = 256
+
+// A breached passwords file, like the ones of Have I Been Pwned, looked up offline.
+// Every line has the uppercase hex SHA-1 of a password, optionally followed by :COUNT,
+// and the lines are sorted by hash.
+type BreachedFile struct {
+	path string
+}
+
+func NewBreachedFile(path string) *BreachedFile {
+	return &BreachedFile{path: path}
+}
+
+// Reports whether the password is in the file by binary searching its hash
+func (b *BreachedFile) Contains(password string) (bool, error) {
+	f, err := os.Open(b.path)
+
+	if err != nil {
+		return false, fmt.Errorf("open breached passwords: %w", err)
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil {
+		return false, fmt.Errorf("stat breached passwords: %w", err)
+	}
+
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	found, err := searchSortedHashes(f, info.Size(), target)
+
+	if err != nil {
+		return false, fmt.Errorf("search breached passwords: %w", err)
+	}
+
+	return found, nil
+}
+
+// Binary searches the lines of r for the hash.
+// lo is always the start of a line, the lines before it are lower than the hash and the ones from hi are higher.
+func searchSortedHashes(r io.ReaderAt, size int64, target string) (bool, error) {
+	var lo, hi int64 = 0, size
+
+	buf := make([]byte, maxBreachedLine)
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		// Find the first line starting after mid, unless mid is already at lo
+		start := lo
+
+		if mid > lo {
+			next, err := nextLineStart(r, mid-1, buf)
+
+			if err != nil {
+				return false, err
+			}
+
+			start = next
+		}
+
+		if start >= hi {
+			// No line starts in the upper half, search the lower one
+			hi = mid
+			continue
+		}
+
+		line, end, err := readLine(r, start, buf)
+
+		if err != nil {
+			return false, err
+		}
+
+		switch hash := lineHash(line); {
+		case hash == target:
+			return true, nil
+		case hash > target:
+			hi = start
+		default:
+			lo = end
+		}
+	}
+
+	return false, nil
+}
+
+// Returns the offset just after the next newline at or after off, or the size when there is none
+func nextLineStart(r io.ReaderAt, off int64, buf []byte) (int64, error) {
+	for {
+		n, err := r.ReadAt(buf, off)
+
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return off + int64(i) + 1, nil
+		}
+
+		off += int64(n)
+
+		if errors.Is(err, io.EOF) {
+			return off, nil
+		}
+
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Returns the line starting at off and the offset of the next line
+func readLine(r io.ReaderAt, off int64, buf []byte) ([]byte, int64, error) {
+	n, err := r.ReadAt(buf, off)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+
+	line := buf[:n]
+
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		return line[:i], off + int64(i) + 1, nil
+	}
+
+	if n == len(buf) {
+		return nil, 0, errors.New("line too long")
+	}
+
+	return line, off + int64(n), nil
+}
+
+// Returns the uppercase hash of a line
+func lineHash(line []byte) string {
+	line = bytes.TrimRight(line, "\r")
+
+	if i := bytes.IndexByte(line, ':'); i >= 0 {
+		line = line[:i]
+	}
+
+	if len(line) > sha1HexLength {
+		line = line[:sha1HexLength]
+	}
+
+	return strings.ToUpper(string(line))
+}
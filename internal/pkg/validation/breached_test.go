@@ -0,0 +1,87 @@
+//go:build !integration
+
+package validation
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeBreachedFile(t *testing.T, passwords []string, lineEnd string) string {
+	t.Helper()
+
+	lines := make([]string, len(passwords))
+
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines[i] = fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1)
+	}
+
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, lineEnd)+lineEnd), 0o600); err != nil {
+		t.Fatalf("write breached file: %v", err)
+	}
+
+	return path
+}
+
+func TestBreachedFileContains(t *testing.T) {
+	breached := make([]string, 500)
+
+	for i := range breached {
+		breached[i] = fmt.Sprintf("password%d", i)
+	}
+
+	for _, lineEnd := range []string{"\n", "\r\n"} {
+		list := NewBreachedFile(writeBreachedFile(t, breached, lineEnd))
+
+		for _, password := range breached {
+			found, err := list.Contains(password)
+
+			if err != nil {
+				t.Fatalf("Contains(%q): %v", password, err)
+			}
+
+			if !found {
+				t.Errorf("Contains(%q) = false, want true", password)
+			}
+		}
+
+		for _, password := range []string{"", "password", "password500", "x7#Kp2!vQ9zL"} {
+			found, err := list.Contains(password)
+
+			if err != nil {
+				t.Fatalf("Contains(%q): %v", password, err)
+			}
+
+			if found {
+				t.Errorf("Contains(%q) = true, want false", password)
+			}
+		}
+	}
+}
+
+func TestBreachedFileContainsEmpty(t *testing.T) {
+	list := NewBreachedFile(writeBreachedFile(t, nil, ""))
+
+	if found, err := list.Contains("password"); err != nil || found {
+		t.Errorf("Contains() = %v, %v, want false, nil", found, err)
+	}
+}
+
+func TestBreachedFileMissing(t *testing.T) {
+	list := NewBreachedFile(filepath.Join(t.TempDir(), "missing.txt"))
+
+	if _, err := list.Contains("password"); err == nil {
+		t.Error("Contains() error = nil, want an error")
+	}
+}
@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rules that a new password has to follow
+type PasswordPolicy struct {
+	MinLength   int               // In characters
+	MaxLength   int               // In characters, keeps the input of the password hash bounded
+	MinStrength int               // From 0 to 4, see PasswordStrength
+	Breached    BreachedPasswords // Optional
+}
+
+// Shortest part of the email that a password may not contain
+const minEmailReuseLength = 3
+
+// Checks a new password against the policy.
+// userInputs are values of the user that a password should not be guessable from, like the email.
+func (f *Form[T]) Password(field string, policy PasswordPolicy, userInputs ...string) {
+	password := f.val.FieldByName(field).String()
+
+	if password == "" {
+		return
+	}
+
+	jsonTag := f.getJSONTag(field)
+	length := utf8.RuneCountInString(password)
+
+	// The other rules are only worth checking once the length is right
+	if policy.MinLength > 0 && length < policy.MinLength {
+		f.Error.Add(jsonTag, fmt.Sprintf("Password must be at least %d characters.", policy.MinLength))
+		return
+	}
+
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		f.Error.Add(jsonTag, fmt.Sprintf("Password must be at most %d characters.", policy.MaxLength))
+		return
+	}
+
+	if containsUserInput(password, userInputs) {
+		f.Error.Add(jsonTag, "Password must not contain your email.")
+	}
+
+	if policy.MinStrength > 0 && PasswordStrength(password, emailParts(userInputs)...) < policy.MinStrength {
+		f.Error.Add(jsonTag, "Password is too easy to guess.")
+	}
+
+	if policy.Breached != nil {
+		breached, err := policy.Breached.Contains(password)
+
+		// A broken list should not keep users from signing up
+		if err != nil {
+			slog.Error("cannot check breached passwords", "error", err)
+		} else if breached {
+			f.Error.Add(jsonTag, "Password has appeared in a data breach, choose another one.")
+		}
+	}
+}
+
+// Reports whether the password contains any of the inputs or the local part of an email in them
+func containsUserInput(password string, userInputs []string) bool {
+	password = strings.ToLower(password)
+
+	for _, part := range emailParts(userInputs) {
+		if len(part) >= minEmailReuseLength && strings.Contains(password, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the inputs in lowercase with the local parts of the emails among them
+func emailParts(userInputs []string) []string {
+	parts := make([]string, 0, len(userInputs)*2)
+
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+
+		if input == "" {
+			continue
+		}
+
+		parts = append(parts, input)
+
+		if local, _, ok := strings.Cut(input, "@"); ok && local != "" {
+			parts = append(parts, local)
+		}
+	}
+
+	return parts
+}
@@ -0,0 +1,65 @@
+//go:build !integration
+
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type passwordParams struct {
+	Password string `json:"password"`
+}
+
+type breachedList map[string]bool
+
+func (b breachedList) Contains(password string) (bool, error) {
+	return b[password], nil
+}
+
+type brokenList struct{}
+
+func (brokenList) Contains(string) (bool, error) {
+	return false, errors.New("list unavailable")
+}
+
+func TestFormPassword(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:   8,
+		MaxLength:   64,
+		MinStrength: 3,
+		Breached:    breachedList{"x7#Kp2!vQ9zLbreached": true},
+	}
+
+	tests := []struct {
+		name       string
+		password   string
+		policy     PasswordPolicy
+		userInputs []string
+		want       []string
+	}{
+		{"valid", "x7#Kp2!vQ9zL", policy, nil, nil},
+		{"empty", "", policy, nil, nil},
+		{"too short", "x7#Kp2", policy, nil, []string{"Password must be at least 8 characters."}},
+		{"too long", strings.Repeat("x7#Kp2!vQ9zL", 6), policy, nil, []string{"Password must be at most 64 characters."}},
+		{"too weak", "password123", policy, nil, []string{"Password is too easy to guess."}},
+		{"contains email", "x7#Kp2!vQ9zLjuan", policy, []string{"Juan@example.com"}, []string{"Password must not contain your email."}},
+		{"breached", "x7#Kp2!vQ9zLbreached", policy, nil, []string{"Password has appeared in a data breach, choose another one."}},
+		{"broken breached list", "x7#Kp2!vQ9zL", PasswordPolicy{Breached: brokenList{}}, nil, nil},
+		{"length in characters", "ñññññññ", PasswordPolicy{MinLength: 8}, nil, []string{"Password must be at least 8 characters."}},
+		{"no policy", "a", PasswordPolicy{}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := NewForm(passwordParams{Password: tt.password})
+			form.Password("Password", tt.policy, tt.userInputs...)
+
+			if got := form.Error.Get("password"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
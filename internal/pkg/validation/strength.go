@@ -0,0 +1,340 @@
+package validation
+
+import (
+	"bufio"
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Passwords that attackers try first, most common first
+//
+//go:embed common-passwords.txt
+var commonPasswordsList string
+
+var commonPasswords = rankedWords(commonPasswordsList)
+
+// Longest prefix of a password that is searched for patterns, the rest is counted as random
+const maxAnalyzedLength = 64
+
+// Guesses of a character that is not part of a pattern
+const bruteforceCardinality = 10
+
+// Keyboard rows used to find runs of adjacent keys like "asdf"
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+// Characters commonly substituted for letters
+var leetReplacer = strings.NewReplacer("4", "a", "@", "a", "8", "b", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t", "+", "t")
+
+// A part of the password matched by a pattern, with the guesses needed to find it
+type strengthMatch struct {
+	start, end int // Rune offsets, end exclusive
+	guesses    float64
+}
+
+// PasswordStrength estimates how hard the password is to guess, like the score of zxcvbn:
+//
+//	0: too guessable, 1: very guessable, 2: somewhat guessable, 3: safely unguessable, 4: very unguessable
+//
+// The password is split into the parts that are cheapest to guess, like common passwords, keyboard runs,
+// sequences, repeats and years. userInputs are words an attacker would try first, like the email of the user.
+func PasswordStrength(password string, userInputs ...string) int {
+	logGuesses := estimateGuesses(password, userInputs)
+
+	switch {
+	case logGuesses < 3:
+		return 0
+	case logGuesses < 6:
+		return 1
+	case logGuesses < 8:
+		return 2
+	case logGuesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Returns the log10 of the guesses needed to find the password
+func estimateGuesses(password string, userInputs []string) float64 {
+	runes := []rune(password)
+
+	var extra float64
+
+	if len(runes) > maxAnalyzedLength {
+		extra = float64(len(runes) - maxAnalyzedLength)
+		runes = runes[:maxAnalyzedLength]
+	}
+
+	n := len(runes)
+
+	if n == 0 {
+		return 0
+	}
+
+	dictionary := make(map[string]int, len(commonPasswords)+len(userInputs))
+
+	for word, rank := range commonPasswords {
+		dictionary[word] = rank
+	}
+
+	for _, input := range userInputs {
+		if input = strings.ToLower(input); len(input) >= 3 {
+			dictionary[input] = 1
+		}
+	}
+
+	matches := findMatches(runes, dictionary)
+
+	// best[k][l] is the lowest log10 of the product of the guesses of l matches covering the first k runes
+	best := make([][]float64, n+1)
+
+	for k := range best {
+		best[k] = make([]float64, n+1)
+
+		for l := range best[k] {
+			best[k][l] = math.Inf(1)
+		}
+	}
+
+	best[0][0] = 0
+
+	for k := 1; k <= n; k++ {
+		for _, m := range matches[k] {
+			logGuesses := math.Log10(m.guesses)
+
+			for l := 1; l <= m.start+1; l++ {
+				if prev := best[m.start][l-1]; prev+logGuesses < best[k][l] {
+					best[k][l] = prev + logGuesses
+				}
+			}
+		}
+	}
+
+	// Like zxcvbn, an attacker also has to guess how many parts there are and in which order
+	total := math.Inf(1)
+
+	for l := 1; l <= n; l++ {
+		if math.IsInf(best[n][l], 1) {
+			continue
+		}
+
+		lgamma, _ := math.Lgamma(float64(l + 1))
+		product := lgamma/math.Ln10 + best[n][l]
+		parts := 4 * float64(l-1)
+
+		total = math.Min(total, logSum(product, parts))
+	}
+
+	return total + extra
+}
+
+// Returns log10(10^a + 10^b)
+func logSum(a, b float64) float64 {
+	high, low := math.Max(a, b), math.Min(a, b)
+	return high + math.Log10(1+math.Pow(10, low-high))
+}
+
+// Returns the matches of the password grouped by their end
+func findMatches(runes []rune, dictionary map[string]int) [][]strengthMatch {
+	n := len(runes)
+	matches := make([][]strengthMatch, n+1)
+
+	add := func(start, end int, guesses float64) {
+		// A part is never cheaper than guessing it from a few common alternatives
+		minGuesses := 10.0
+
+		if end-start > 1 {
+			minGuesses = 50
+		}
+
+		matches[end] = append(matches[end], strengthMatch{start: start, end: end, guesses: math.Max(guesses, minGuesses)})
+	}
+
+	lower := []rune(strings.ToLower(string(runes)))
+
+	if len(lower) != n {
+		lower = runes
+	}
+
+	for i := 0; i < n; i++ {
+		// Any characters, guessed one by one
+		for j := i + 1; j <= n; j++ {
+			add(i, j, math.Pow(bruteforceCardinality, float64(j-i)))
+		}
+
+		for j := i + 3; j <= n; j++ {
+			part := lower[i:j]
+			word := string(part)
+			variations := caseVariations(runes[i:j])
+
+			if rank, ok := dictionary[word]; ok {
+				add(i, j, float64(rank)*variations)
+			}
+
+			if rank, ok := dictionary[reverse(word)]; ok {
+				add(i, j, float64(rank)*variations*2)
+			}
+
+			if unleet := leetReplacer.Replace(word); unleet != word {
+				if rank, ok := dictionary[unleet]; ok {
+					add(i, j, float64(rank)*variations*2)
+				}
+			}
+
+			if isRepeat(part) {
+				add(i, j, cardinality(part[0])*float64(j-i))
+			}
+
+			if delta, ok := sequenceDelta(part); ok {
+				add(i, j, sequenceGuesses(part, delta))
+			}
+
+			if j-i >= 4 && isKeyboardRun(part) {
+				add(i, j, 94*4*float64(j-i))
+			}
+
+			if j-i == 4 {
+				if year, ok := parseYear(word); ok {
+					add(i, j, math.Max(math.Abs(float64(year-2000)), 20))
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// Returns how many ways the letters could be capitalized like they are
+func caseVariations(runes []rune) float64 {
+	var upper, lower int
+
+	for _, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0, upper == 1 && unicode.IsUpper(runes[0]):
+		return 2
+	default:
+		return math.Pow(2, float64(min(upper, lower)))
+	}
+}
+
+func cardinality(r rune) float64 {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLetter(r):
+		return 26
+	default:
+		return 33
+	}
+}
+
+func isRepeat(runes []rune) bool {
+	for _, r := range runes[1:] {
+		if r != runes[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reports the step of a sequence of characters like "abc" or "97531"
+func sequenceDelta(runes []rune) (rune, bool) {
+	delta := runes[1] - runes[0]
+
+	if delta == 0 || delta > 5 || delta < -5 {
+		return 0, false
+	}
+
+	for i := 2; i < len(runes); i++ {
+		if runes[i]-runes[i-1] != delta {
+			return 0, false
+		}
+	}
+
+	return delta, true
+}
+
+func sequenceGuesses(runes []rune, delta rune) float64 {
+	var base float64
+
+	switch first := runes[0]; {
+	case first == 'a' || first == 'z' || first == '0' || first == '1' || first == '9':
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+
+	if delta < 0 {
+		base *= 2
+	}
+
+	return base * float64(len(runes))
+}
+
+// Reports whether the characters are adjacent keys of one keyboard row, in either direction
+func isKeyboardRun(runes []rune) bool {
+	word := string(runes)
+
+	for _, row := range keyboardRows {
+		if strings.Contains(row, word) || strings.Contains(row, reverse(word)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseYear(s string) (int, bool) {
+	year := 0
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+
+		year = year*10 + int(r-'0')
+	}
+
+	return year, year >= 1900 && year <= 2099
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
+
+// Ranks the words of a list with one word per line, starting at 1
+func rankedWords(list string) map[string]int {
+	words := make(map[string]int)
+	scanner := bufio.NewScanner(strings.NewReader(list))
+
+	for rank := 1; scanner.Scan(); rank++ {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			if _, ok := words[word]; !ok {
+				words[word] = rank
+			}
+		}
+	}
+
+	return words
+}
@@ -0,0 +1,41 @@
+//go:build !integration
+
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordStrength(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		min, max   int
+	}{
+		{"empty", "", nil, 0, 0},
+		{"common password", "password", nil, 0, 0},
+		{"capitalized common password", "Password", nil, 0, 0},
+		{"leet common password", "p4ssw0rd", nil, 0, 1},
+		{"reversed common password", "drowssap", nil, 0, 1},
+		{"keyboard run", "qwertyuiop", nil, 0, 1},
+		{"sequence", "abcdefgh", nil, 0, 1},
+		{"repeat", "aaaaaaaaaaaa", nil, 0, 1},
+		{"common password and year", "monkey1987", nil, 0, 2},
+		{"user input", "johndoe", []string{"johndoe"}, 0, 0},
+		{"random", "x7#Kp2!vQ9zL", nil, 4, 4},
+		{"passphrase", "correct horse battery staple", nil, 4, 4},
+		{"longer than analyzed", "x7#Kp2!vQ9zL" + strings.Repeat("a", 100), nil, 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := PasswordStrength(tt.password, tt.userInputs...)
+
+			if score < tt.min || score > tt.max {
+				t.Errorf("PasswordStrength(%q) = %d, want between %d and %d", tt.password, score, tt.min, tt.max)
+			}
+		})
+	}
+}
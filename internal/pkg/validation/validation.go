@@ -15,6 +15,13 @@ func IsValidEmail(email string) bool {
 	return re.MatchString(email)
 }
 
+var uuidRe = regexp.MustCompile(`^(?i:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+// Validates a UUID in its canonical form, e.g. before it is compared with a uuid column.
+func IsUUID(id string) bool {
+	return uuidRe.MatchString(id)
+}
+
 // Trims string fields of a struct.
 func TrimStructFields[T any](s T) {
 	v := reflect.ValueOf(s).Elem()
@@ -39,6 +39,29 @@ func TestIsValidEmail(t *testing.T) {
 	}
 }
 
+func TestIsUUID(t *testing.T) {
+	tests := []struct {
+		id       string
+		expected bool
+	}{
+		{"0b7e4a3c-2f1d-4e5a-9b8c-7d6e5f4a3b2c", true},
+		{"0B7E4A3C-2F1D-4E5A-9B8C-7D6E5F4A3B2C", true},
+		{"0b7e4a3c2f1d4e5a9b8c7d6e5f4a3b2c", false},
+		{"0b7e4a3c-2f1d-4e5a-9b8c-7d6e5f4a3b2", false},
+		{"0b7e4a3c-2f1d-4e5a-9b8c-7d6e5f4a3b2g", false},
+		{"not-a-uuid", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if result := IsUUID(tt.id); result != tt.expected {
+				t.Errorf("IsUUID(%q) = %v; want %v", tt.id, result, tt.expected)
+			}
+		})
+	}
+}
+
 // Define test structs
 type Address struct {
 	Street string `json:"street"`
@@ -0,0 +1,160 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCBOR = errors.New("invalid cbor")
+
+// Deepest nesting of arrays and maps accepted, authenticators never go beyond a few levels
+const maxCBORDepth = 16
+
+// Major types of RFC 8949
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborSimple = 7
+)
+
+// Decodes one CBOR data item and returns it along with the bytes that follow it.
+//
+// Only the subset used by WebAuthn is supported: integers (as int64), byte strings, text strings,
+// arrays, maps (as map[any]any) and the simple values false, true and null.
+// Indefinite lengths, tags and floats are rejected.
+func decodeCBOR(b []byte) (any, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, fmt.Errorf("%w: nested too deeply", ErrInvalidCBOR)
+	}
+
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCBOR)
+	}
+
+	major := b[0] >> 5
+	info := b[0] & 0x1f
+
+	if major == cborSimple {
+		switch info {
+		case 20:
+			return false, b[1:], nil
+		case 21:
+			return true, b[1:], nil
+		case 22:
+			return nil, b[1:], nil
+		default:
+			return nil, nil, fmt.Errorf("%w: unsupported simple value %d", ErrInvalidCBOR, info)
+		}
+	}
+
+	arg, rest, err := decodeCBORArgument(info, b[1:])
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUint:
+		if arg > 1<<63-1 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", ErrInvalidCBOR)
+		}
+		return int64(arg), rest, nil // #nosec G115 -- Checked above
+	case cborNegint:
+		if arg > 1<<63-1 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", ErrInvalidCBOR)
+		}
+		return -1 - int64(arg), rest, nil // #nosec G115 -- Checked above
+	case cborBytes, cborText:
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: string longer than the data", ErrInvalidCBOR)
+		}
+		if major == cborText {
+			return string(rest[:arg]), rest[arg:], nil
+		}
+		return append([]byte(nil), rest[:arg]...), rest[arg:], nil
+	case cborArray:
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: array longer than the data", ErrInvalidCBOR)
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item any
+			if item, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+	case cborMap:
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: map longer than the data", ErrInvalidCBOR)
+		}
+		m := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value any
+			if key, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("%w: unsupported map key %T", ErrInvalidCBOR, key)
+			}
+			if value, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, rest, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported major type %d", ErrInvalidCBOR, major)
+	}
+}
+
+// Decodes the argument that follows the initial byte of an item
+func decodeCBORArgument(info byte, b []byte) (uint64, []byte, error) {
+	var size int
+
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, fmt.Errorf("%w: unsupported additional information %d", ErrInvalidCBOR, info)
+	}
+
+	if len(b) < size {
+		return 0, nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCBOR)
+	}
+
+	var arg uint64
+
+	switch size {
+	case 1:
+		arg = uint64(b[0])
+	case 2:
+		arg = uint64(binary.BigEndian.Uint16(b))
+	case 4:
+		arg = uint64(binary.BigEndian.Uint32(b))
+	case 8:
+		arg = binary.BigEndian.Uint64(b)
+	}
+
+	return arg, b[size:], nil
+}
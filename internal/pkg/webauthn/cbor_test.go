@@ -0,0 +1,42 @@
+//go:build !integration
+
+package webauthn
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  any
+		err   error
+	}{
+		{"unsigned", []byte{0x18, 0x64}, int64(100), nil},
+		{"negative", []byte{0x38, 0x63}, int64(-100), nil},
+		{"bytes", []byte{0x43, 1, 2, 3}, []byte{1, 2, 3}, nil},
+		{"text", []byte{0x63, 'f', 'm', 't'}, "fmt", nil},
+		{"array", []byte{0x82, 0x01, 0xf5}, []any{int64(1), true}, nil},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x20, 0xf6}, map[any]any{int64(1): int64(2), int64(-1): nil}, nil},
+		{"truncated", []byte{0x43, 1, 2}, nil, ErrInvalidCBOR},
+		{"indefinite length", []byte{0x5f, 0x41, 1, 0xff}, nil, ErrInvalidCBOR},
+		{"float", []byte{0xf9, 0x3c, 0x00}, nil, ErrInvalidCBOR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := decodeCBOR(tt.input)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
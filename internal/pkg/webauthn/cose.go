@@ -0,0 +1,112 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported public key")
+
+// COSE algorithms (RFC 9053) accepted for credentials
+const (
+	AlgES256 int64 = -7
+	AlgRS256 int64 = -257
+)
+
+// COSE key parameters
+const (
+	coseKty    int64 = 1
+	coseAlg    int64 = 3
+	coseCrv    int64 = -1
+	coseX      int64 = -2
+	coseY      int64 = -3
+	coseRSAN   int64 = -1
+	coseRSAE   int64 = -2
+	coseKtyEC2 int64 = 2
+	coseKtyRSA int64 = 3
+	coseP256   int64 = 1
+)
+
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// Parses a COSE encoded public key of an ES256 or RS256 credential
+func parseCOSEKey(raw []byte) (*publicKey, error) {
+	item, rest, err := decodeCBOR(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrUnsupportedKey)
+	}
+
+	m, ok := item.(map[any]any)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: not a map", ErrUnsupportedKey)
+	}
+
+	kty, _ := m[coseKty].(int64)
+	alg, _ := m[coseAlg].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := m[coseCrv].(int64)
+		x, _ := m[coseX].([]byte)
+		y, _ := m[coseY].([]byte)
+
+		if crv != coseP256 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: invalid P-256 key", ErrUnsupportedKey)
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: point is not on the curve", ErrUnsupportedKey)
+		}
+
+		return &publicKey{alg: alg, key: key}, nil
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := m[coseRSAN].([]byte)
+		e, _ := m[coseRSAE].([]byte)
+
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: invalid RSA key", ErrUnsupportedKey)
+		}
+
+		return &publicKey{alg: alg, key: &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("%w: key type %d with algorithm %d", ErrUnsupportedKey, kty, alg)
+	}
+}
+
+// Reports whether the signature of the data is valid
+func (k *publicKey) verify(data, signature []byte) bool {
+	digest := sha256.Sum256(data)
+
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
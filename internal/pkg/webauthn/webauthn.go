@@ -0,0 +1,444 @@
+// Package webauthn implements the registration and authentication ceremonies of
+// Web Authentication (https://www.w3.org/TR/webauthn-2/) for passkeys.
+//
+// Attestation is not requested, so registered authenticators are not verified to be of a given make.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/security"
+)
+
+var (
+	ErrCeremonyExpired    = errors.New("the passkey request has expired, please try again")
+	ErrInvalidResponse    = errors.New("invalid authenticator response")
+	ErrSignCountRegressed = errors.New("the sign count of the credential did not increase, the authenticator may have been cloned")
+)
+
+// Flags of the authenticator data
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+// Bytes is binary data encoded as unpadded base64url in json, the encoding used by browsers.
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+
+	return nil
+}
+
+// WebAuthn runs the ceremonies for a relying party.
+type WebAuthn struct {
+	cfg config.WebAuthnConfig
+}
+
+func New(cfg config.WebAuthnConfig) *WebAuthn {
+	return &WebAuthn{cfg: cfg}
+}
+
+// Ceremony is the state of a ceremony kept on the server between its two steps.
+type Ceremony struct {
+	Challenge Bytes     `json:"challenge"`
+	UserID    string    `json:"user_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// User is the account a credential is registered for.
+type User struct {
+	ID          string
+	Name        string
+	DisplayName string
+	Credentials [][]byte
+}
+
+// Credential is a registered public key credential.
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte // COSE encoded
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+type relyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          Bytes  `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         Bytes    `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are the options of navigator.credentials.create().
+type CreationOptions struct {
+	RP                     relyingParty           `json:"rp"`
+	User                   userEntity             `json:"user"`
+	Challenge              Bytes                  `json:"challenge"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the options of navigator.credentials.get().
+type RequestOptions struct {
+	Challenge        Bytes                  `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the credential returned by navigator.credentials.create().
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes    `json:"clientDataJSON"`
+		AttestationObject Bytes    `json:"attestationObject"`
+		Transports        []string `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the credential returned by navigator.credentials.get().
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AuthenticatorData Bytes `json:"authenticatorData"`
+		Signature         Bytes `json:"signature"`
+		UserHandle        Bytes `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// LoginResult is the outcome of a successful authentication ceremony.
+type LoginResult struct {
+	SignCount    uint32
+	UserVerified bool
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (w *WebAuthn) newCeremony(userID string) (*Ceremony, error) {
+	challenge, err := security.GenerateRandomBytes(32)
+
+	if err != nil {
+		return nil, fmt.Errorf("generate challenge: %w", err)
+	}
+
+	return &Ceremony{
+		Challenge: challenge,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(w.cfg.Timeout),
+	}, nil
+}
+
+// BeginRegistration starts registering a passkey for the user.
+// The ceremony must be kept until FinishRegistration.
+func (w *WebAuthn) BeginRegistration(user User) (*CreationOptions, *Ceremony, error) {
+	ceremony, err := w.newCeremony(user.ID)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exclude := make([]CredentialDescriptor, 0, len(user.Credentials))
+
+	for _, id := range user.Credentials {
+		exclude = append(exclude, CredentialDescriptor{Type: "public-key", ID: id})
+	}
+
+	options := &CreationOptions{
+		RP:        relyingParty{ID: w.cfg.RPID, Name: w.cfg.RPName},
+		User:      userEntity{ID: Bytes(user.ID), Name: user.Name, DisplayName: user.DisplayName},
+		Challenge: ceremony.Challenge,
+		PubKeyCredParams: []credentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            w.cfg.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}
+
+	return options, ceremony, nil
+}
+
+// FinishRegistration verifies the response of the authenticator and returns the new credential.
+func (w *WebAuthn) FinishRegistration(ceremony *Ceremony, res *RegistrationResponse) (*Credential, error) {
+	if time.Now().After(ceremony.ExpiresAt) {
+		return nil, ErrCeremonyExpired
+	}
+
+	if res.Type != "public-key" {
+		return nil, fmt.Errorf("%w: unexpected credential type %q", ErrInvalidResponse, res.Type)
+	}
+
+	if err := w.verifyClientData(res.Response.ClientDataJSON, "webauthn.create", ceremony.Challenge); err != nil {
+		return nil, err
+	}
+
+	item, _, err := decodeCBOR(res.Response.AttestationObject)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: attestation object: %v", ErrInvalidResponse, err)
+	}
+
+	attestation, ok := item.(map[any]any)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object is not a map", ErrInvalidResponse)
+	}
+
+	rawAuthData, ok := attestation["authData"].([]byte)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: missing authenticator data", ErrInvalidResponse)
+	}
+
+	authData, err := w.parseAuthenticatorData(rawAuthData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.flags&flagAttestedCredData == 0 || len(authData.credentialID) == 0 {
+		return nil, fmt.Errorf("%w: missing attested credential data", ErrInvalidResponse)
+	}
+
+	if !bytes.Equal(authData.credentialID, res.RawID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrInvalidResponse)
+	}
+
+	if _, err := parseCOSEKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		AAGUID:     authData.aaguid,
+		Transports: res.Response.Transports,
+	}, nil
+}
+
+// BeginLogin starts an authentication ceremony. When no credentials are given, the browser
+// offers the passkeys it has for the relying party.
+func (w *WebAuthn) BeginLogin(credentials [][]byte) (*RequestOptions, *Ceremony, error) {
+	ceremony, err := w.newCeremony("")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allow := make([]CredentialDescriptor, 0, len(credentials))
+
+	for _, id := range credentials {
+		allow = append(allow, CredentialDescriptor{Type: "public-key", ID: id})
+	}
+
+	options := &RequestOptions{
+		Challenge:        ceremony.Challenge,
+		Timeout:          w.cfg.Timeout.Milliseconds(),
+		RPID:             w.cfg.RPID,
+		AllowCredentials: allow,
+		UserVerification: "preferred",
+	}
+
+	return options, ceremony, nil
+}
+
+// FinishLogin verifies the assertion made with the stored credential.
+// The returned sign count must be saved for the next ceremony.
+func (w *WebAuthn) FinishLogin(ceremony *Ceremony, res *AssertionResponse, credential *Credential) (*LoginResult, error) {
+	if time.Now().After(ceremony.ExpiresAt) {
+		return nil, ErrCeremonyExpired
+	}
+
+	if res.Type != "public-key" || !bytes.Equal(res.RawID, credential.ID) {
+		return nil, fmt.Errorf("%w: unexpected credential", ErrInvalidResponse)
+	}
+
+	if err := w.verifyClientData(res.Response.ClientDataJSON, "webauthn.get", ceremony.Challenge); err != nil {
+		return nil, err
+	}
+
+	authData, err := w.parseAuthenticatorData(res.Response.AuthenticatorData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parseCOSEKey(credential.PublicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(res.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), res.Response.AuthenticatorData...), clientDataHash[:]...)
+
+	if !key.verify(signed, res.Response.Signature) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidResponse)
+	}
+
+	// Authenticators without a counter always report zero
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return nil, ErrSignCountRegressed
+	}
+
+	return &LoginResult{
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+	}, nil
+}
+
+func (w *WebAuthn) verifyClientData(raw []byte, ceremonyType string, challenge []byte) error {
+	var data clientData
+
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: client data: %v", ErrInvalidResponse, err)
+	}
+
+	if data.Type != ceremonyType {
+		return fmt.Errorf("%w: unexpected ceremony %q", ErrInvalidResponse, data.Type)
+	}
+
+	expected := base64.RawURLEncoding.EncodeToString(challenge)
+
+	if subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(expected)) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrInvalidResponse)
+	}
+
+	if data.Origin != w.cfg.Origin || data.CrossOrigin {
+		return fmt.Errorf("%w: unexpected origin %q", ErrInvalidResponse, data.Origin)
+	}
+
+	return nil
+}
+
+// Parses the authenticator data and checks that it was made for this relying party with the user present
+func (w *WebAuthn) parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data too short", ErrInvalidResponse)
+	}
+
+	data := &authenticatorData{
+		rpIDHash:  b[:32],
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+
+	rpIDHash := sha256.Sum256([]byte(w.cfg.RPID))
+
+	if subtle.ConstantTimeCompare(data.rpIDHash, rpIDHash[:]) != 1 {
+		return nil, fmt.Errorf("%w: unexpected relying party", ErrInvalidResponse)
+	}
+
+	if data.flags&flagUserPresent == 0 {
+		return nil, fmt.Errorf("%w: user not present", ErrInvalidResponse)
+	}
+
+	if data.flags&flagAttestedCredData == 0 {
+		return data, nil
+	}
+
+	rest := b[37:]
+
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("%w: attested credential data too short", ErrInvalidResponse)
+	}
+
+	data.aaguid = rest[:16]
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+
+	if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+		return nil, fmt.Errorf("%w: invalid credential id", ErrInvalidResponse)
+	}
+
+	data.credentialID = rest[:idLength]
+	rest = rest[idLength:]
+
+	_, after, err := decodeCBOR(rest)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidResponse, err)
+	}
+
+	data.publicKey = rest[:len(rest)-len(after)]
+
+	return data, nil
+}
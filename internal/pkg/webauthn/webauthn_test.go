@@ -0,0 +1,156 @@
+//go:build !integration
+
+package webauthn_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/config"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/webauthn"
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/webauthn/webauthntest"
+)
+
+var cfg = config.WebAuthnConfig{
+	RPID:    "app.test",
+	RPName:  "App",
+	Origin:  "https://app.test",
+	Timeout: time.Minute,
+}
+
+var user = webauthn.User{ID: "9b2c6a58-7f4e-4d1e-8d8f-0a1b2c3d4e5f", Name: "jane@example.com", DisplayName: "jane@example.com"}
+
+// Registers a passkey on the authenticator and returns the stored credential
+func register(t *testing.T, w *webauthn.WebAuthn, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	options, ceremony, err := w.BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+
+	res, err := authenticator.Create(options)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	cred, err := w.FinishRegistration(ceremony, res)
+	if err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+
+	return cred
+}
+
+func TestCeremonies(t *testing.T) {
+	w := webauthn.New(cfg)
+	authenticator := webauthntest.New(cfg.Origin)
+	cred := register(t, w, authenticator)
+
+	if len(cred.ID) == 0 || len(cred.PublicKey) == 0 {
+		t.Fatalf("expected credential id and public key, got %+v", cred)
+	}
+
+	for i := 1; i <= 2; i++ {
+		options, ceremony, err := w.BeginLogin([][]byte{cred.ID})
+		if err != nil {
+			t.Fatalf("begin login: %v", err)
+		}
+
+		res, err := authenticator.Get(options)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+
+		if string(res.Response.UserHandle) != user.ID {
+			t.Errorf("expected user handle %q, got %q", user.ID, res.Response.UserHandle)
+		}
+
+		result, err := w.FinishLogin(ceremony, res, cred)
+		if err != nil {
+			t.Fatalf("finish login: %v", err)
+		}
+
+		if result.SignCount != uint32(i) || !result.UserVerified {
+			t.Errorf("expected sign count %d with user verified, got %+v", i, result)
+		}
+
+		cred.SignCount = result.SignCount
+	}
+}
+
+func TestFinishRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		modify func(*webauthn.Ceremony)
+		err    error
+	}{
+		{"wrong origin", "https://evil.test", func(*webauthn.Ceremony) {}, webauthn.ErrInvalidResponse},
+		{"wrong challenge", cfg.Origin, func(c *webauthn.Ceremony) { c.Challenge = []byte("other") }, webauthn.ErrInvalidResponse},
+		{"expired", cfg.Origin, func(c *webauthn.Ceremony) { c.ExpiresAt = time.Now().Add(-time.Second) }, webauthn.ErrCeremonyExpired},
+	}
+
+	w := webauthn.New(cfg)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, ceremony, err := w.BeginRegistration(user)
+			if err != nil {
+				t.Fatalf("begin registration: %v", err)
+			}
+
+			res, err := webauthntest.New(tt.origin).Create(options)
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+
+			tt.modify(ceremony)
+
+			if _, err := w.FinishRegistration(ceremony, res); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestFinishLoginRejects(t *testing.T) {
+	w := webauthn.New(cfg)
+	authenticator := webauthntest.New(cfg.Origin)
+	cred := register(t, w, authenticator)
+	other := register(t, w, webauthntest.New(cfg.Origin))
+
+	tests := []struct {
+		name   string
+		modify func(*webauthn.AssertionResponse, *webauthn.Credential)
+		err    error
+	}{
+		{"cloned authenticator", func(_ *webauthn.AssertionResponse, c *webauthn.Credential) { c.SignCount = 5 }, webauthn.ErrSignCountRegressed},
+		{"other key", func(_ *webauthn.AssertionResponse, c *webauthn.Credential) { c.PublicKey = other.PublicKey }, webauthn.ErrInvalidResponse},
+		{"tampered data", func(r *webauthn.AssertionResponse, _ *webauthn.Credential) { r.Response.AuthenticatorData[36]++ }, webauthn.ErrInvalidResponse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, ceremony, err := w.BeginLogin(nil)
+			if err != nil {
+				t.Fatalf("begin login: %v", err)
+			}
+
+			authenticator.SetSignCount(cred.ID, 2)
+
+			res, err := authenticator.Get(options)
+			if err != nil {
+				t.Fatalf("get: %v", err)
+			}
+
+			stored := *cred
+			tt.modify(res, &stored)
+
+			if _, err := w.FinishLogin(ceremony, res, &stored); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
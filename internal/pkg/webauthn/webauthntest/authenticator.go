@@ -0,0 +1,219 @@
+// Package webauthntest provides a software authenticator for testing WebAuthn ceremonies.
+package webauthntest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/ferdiebergado/go-fullstack-boilerplate/internal/pkg/webauthn"
+)
+
+var ErrNoCredential = errors.New("no credential for the relying party")
+
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+// Authenticator creates ES256 passkeys and makes assertions with them, like a browser and
+// a platform authenticator would for the given origin.
+type Authenticator struct {
+	Origin       string
+	UserVerified bool
+	credentials  []*credential
+}
+
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin, UserVerified: true}
+}
+
+// Create responds to navigator.credentials.create().
+func (a *Authenticator) Create(options *webauthn.CreationOptions) (*webauthn.RegistrationResponse, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	cred := &credential{id: id, rpID: options.RP.ID, userHandle: options.User.ID, key: key}
+	a.credentials = append(a.credentials, cred)
+
+	authData := a.authenticatorData(cred, 0x40)
+	authData = binary.BigEndian.AppendUint16(append(authData, make([]byte, 16)...), uint16(len(id)))
+	authData = append(authData, id...)
+	authData = append(authData, coseKey(&key.PublicKey)...)
+
+	var attestation bytes.Buffer
+	writeHeader(&attestation, 5, 3)
+	writeText(&attestation, "fmt")
+	writeText(&attestation, "none")
+	writeText(&attestation, "attStmt")
+	writeHeader(&attestation, 5, 0)
+	writeText(&attestation, "authData")
+	writeBytes(&attestation, authData)
+
+	res := &webauthn.RegistrationResponse{ID: base64.RawURLEncoding.EncodeToString(id), RawID: id, Type: "public-key"}
+	res.Response.ClientDataJSON = a.clientData("webauthn.create", options.Challenge)
+	res.Response.AttestationObject = attestation.Bytes()
+	res.Response.Transports = []string{"internal"}
+
+	return res, nil
+}
+
+// Get responds to navigator.credentials.get() with the first matching credential.
+func (a *Authenticator) Get(options *webauthn.RequestOptions) (*webauthn.AssertionResponse, error) {
+	cred := a.find(options)
+
+	if cred == nil {
+		return nil, ErrNoCredential
+	}
+
+	cred.signCount++
+
+	authData := a.authenticatorData(cred, 0)
+	clientData := a.clientData("webauthn.get", options.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := &webauthn.AssertionResponse{ID: base64.RawURLEncoding.EncodeToString(cred.id), RawID: cred.id, Type: "public-key"}
+	res.Response.ClientDataJSON = clientData
+	res.Response.AuthenticatorData = authData
+	res.Response.Signature = signature
+	res.Response.UserHandle = cred.userHandle
+
+	return res, nil
+}
+
+// SetSignCount overrides the counter of a credential, e.g. to simulate a cloned authenticator.
+func (a *Authenticator) SetSignCount(id []byte, count uint32) {
+	for _, cred := range a.credentials {
+		if bytes.Equal(cred.id, id) {
+			cred.signCount = count
+		}
+	}
+}
+
+func (a *Authenticator) find(options *webauthn.RequestOptions) *credential {
+	for _, cred := range a.credentials {
+		if cred.rpID != options.RPID {
+			continue
+		}
+
+		if len(options.AllowCredentials) == 0 {
+			return cred
+		}
+
+		for _, allowed := range options.AllowCredentials {
+			if bytes.Equal(allowed.ID, cred.id) {
+				return cred
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *Authenticator) authenticatorData(cred *credential, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(cred.rpID))
+
+	flags |= 0x01
+
+	if a.UserVerified {
+		flags |= 0x04
+	}
+
+	data := append(rpIDHash[:], flags)
+
+	return binary.BigEndian.AppendUint32(data, cred.signCount)
+}
+
+func (a *Authenticator) clientData(ceremonyType string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"type":        ceremonyType,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+
+	return data
+}
+
+// Encodes the public key as a COSE EC2 key
+func coseKey(key *ecdsa.PublicKey) []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+
+	var b bytes.Buffer
+	writeHeader(&b, 5, 5)
+	writeInt(&b, 1)
+	writeInt(&b, 2)
+	writeInt(&b, 3)
+	writeInt(&b, webauthn.AlgES256)
+	writeInt(&b, -1)
+	writeInt(&b, 1)
+	writeInt(&b, -2)
+	writeBytes(&b, x)
+	writeInt(&b, -3)
+	writeBytes(&b, y)
+
+	return b.Bytes()
+}
+
+func writeHeader(b *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		b.WriteByte(major<<5 | byte(n))
+	case n <= 0xff:
+		b.WriteByte(major<<5 | 24)
+		b.WriteByte(byte(n))
+	case n <= 0xffff:
+		b.WriteByte(major<<5 | 25)
+		b.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		b.WriteByte(major<<5 | 26)
+		b.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func writeInt(b *bytes.Buffer, n int64) {
+	if n < 0 {
+		writeHeader(b, 1, uint64(-1-n))
+		return
+	}
+
+	writeHeader(b, 0, uint64(n))
+}
+
+func writeBytes(b *bytes.Buffer, data []byte) {
+	writeHeader(b, 2, uint64(len(data)))
+	b.Write(data)
+}
+
+func writeText(b *bytes.Buffer, s string) {
+	writeHeader(b, 3, uint64(len(s)))
+	b.WriteString(s)
+}
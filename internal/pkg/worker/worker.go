@@ -0,0 +1,40 @@
+// Package worker runs the background jobs of the app until it shuts down.
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job does one run of a background job and returns how many records it handled.
+type Job func(ctx context.Context) (int64, error)
+
+// RunEvery runs the job every interval until the context is done, then marks it as done in the wait group.
+// Failed runs are logged and the job carries on at the next interval.
+func RunEvery(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, name string, job Job) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info(name+" started", "interval", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info(name + " stopped.")
+			return
+		case <-ticker.C:
+			count, err := job(ctx)
+
+			if err != nil {
+				slog.Error(name+" failed", "error", err)
+				continue
+			}
+
+			slog.Info(name+" finished", "count", count)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+//go:build !integration
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		runs atomic.Int32
+	)
+
+	wg.Add(1)
+
+	go RunEvery(ctx, &wg, time.Millisecond, "Test job", func(context.Context) (int64, error) {
+		// A failed run must not stop the job
+		if runs.Add(1) == 1 {
+			return 0, errors.New("failed")
+		}
+
+		return 1, nil
+	})
+
+	deadline := time.Now().Add(time.Second)
+
+	for runs.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected at least 3 runs, got %d", runs.Load())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the job to stop when the context is done")
+	}
+}
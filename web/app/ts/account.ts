@@ -0,0 +1,72 @@
+// Submits the account forms of the profile as JSON. The inputs are sent keyed
+// by their ids, which are the json field names the server reports errors for.
+import { clearFormErrors, handleFormErrors, updateSubmitBtn } from "./form";
+import { showNotification } from "./notification";
+
+document
+	.querySelectorAll<HTMLFormElement>("form.account-form")
+	.forEach((frm) => frm.addEventListener("submit", submitAccountForm));
+
+async function submitAccountForm(e: SubmitEvent) {
+	e.preventDefault();
+
+	const frm = e.currentTarget as HTMLFormElement;
+	const btn = frm.querySelector(
+		'button[type="submit"]'
+	) as HTMLButtonElement;
+	const inputCSRF = frm.querySelector(
+		'input[name="_csrf"]'
+	) as HTMLInputElement;
+
+	const btnAttrs = {
+		btn,
+		text: btn.textContent ?? "",
+		loadingText: btn.dataset.loadingText ?? "Saving...",
+	};
+
+	if (frm.dataset.confirm && !window.confirm(frm.dataset.confirm)) return;
+
+	updateSubmitBtn(btnAttrs, true);
+	clearFormErrors(frm);
+
+	const body: Record<string, string> = {};
+
+	frm.querySelectorAll<HTMLInputElement>("input[id]").forEach((input) => {
+		body[input.id] =
+			input.type === "password" ? input.value : input.value.trim();
+	});
+
+	try {
+		const res = await fetch(frm.action, {
+			method: "POST",
+			headers: {
+				"Content-Type": "application/json",
+				"X-XSRF-Token": inputCSRF.value,
+			},
+			body: JSON.stringify(body),
+		});
+
+		if (!res.ok) {
+			const { message, errors }: APIResponse<undefined> =
+				await res.json();
+
+			errors && handleFormErrors(errors, frm);
+			showNotification("error", message);
+			return;
+		}
+
+		const { message, data }: APIResponse<RedirectData> = await res.json();
+
+		showNotification("success", message);
+		frm.reset();
+
+		if (data) {
+			window.location.href = data.redirectUrl;
+		}
+	} catch (error) {
+		console.log("Error while submitting the form:", error);
+		if (error instanceof Error) showNotification("error", error.message);
+	} finally {
+		updateSubmitBtn(btnAttrs, false);
+	}
+}
@@ -0,0 +1,111 @@
+import {
+	clearFormErrors,
+	csrfToken,
+	handleFormErrors,
+	showFormError,
+	toggleError,
+	updateSubmitBtn,
+} from "./form";
+import { showNotification } from "./notification";
+import { isRequiredInputFilled, isValidEmail } from "./validation";
+
+const frmForgotPassword = document.getElementById(
+	"frmForgotPassword"
+) as HTMLFormElement;
+const inputEmail = frmForgotPassword.querySelector("#email") as HTMLInputElement;
+const btnForgotPassword = frmForgotPassword.querySelector(
+	"#btnForgotPassword"
+) as HTMLButtonElement;
+
+const btnAttrs = {
+	btn: btnForgotPassword,
+	text: "Send Reset Link",
+	loadingText: "Sending...",
+};
+
+type ValidationErrors = {
+	email: Errors;
+};
+
+const validationErrors: ValidationErrors = {
+	email: [],
+};
+
+let isLoading = false;
+
+frmForgotPassword.addEventListener("change", handleInputChange);
+frmForgotPassword.addEventListener("submit", requestReset);
+
+function handleInputChange(event: Event) {
+	const target = event.target as HTMLInputElement;
+	if (target.matches("#email")) {
+		toggleError(target, "");
+
+		if (!isValidEmail(target.value)) {
+			showFormError(target, ["Email must be a valid email address"]);
+		}
+	}
+}
+
+async function requestReset(e: SubmitEvent) {
+	e.preventDefault();
+	isLoading = true;
+	updateSubmitBtn(btnAttrs, isLoading);
+	clearFormErrors(frmForgotPassword);
+
+	if (!validate()) {
+		handleFormErrors(validationErrors, frmForgotPassword);
+		showNotification("error", "Invalid input!");
+		isLoading = false;
+		updateSubmitBtn(btnAttrs, isLoading);
+		return;
+	}
+
+	try {
+		const res = await fetch(frmForgotPassword.action, {
+			method: frmForgotPassword.method,
+			headers: {
+				"Content-Type": "application/json",
+				"X-XSRF-Token": csrfToken(),
+			},
+			body: JSON.stringify({
+				email: inputEmail.value.trim(),
+			}),
+		});
+
+		const { message, errors }: APIResponse<undefined> = await res.json();
+
+		if (!res.ok) {
+			errors && handleFormErrors(errors, frmForgotPassword);
+			showNotification("error", message);
+			return;
+		}
+
+		showNotification("success", message);
+		frmForgotPassword.reset();
+	} catch (error) {
+		console.log("Error while requesting a password reset:", error);
+		if (error instanceof Error) showNotification("error", error.message);
+	} finally {
+		isLoading = false;
+		updateSubmitBtn(btnAttrs, isLoading);
+	}
+}
+
+function validate(): boolean {
+	let isValid = true;
+
+	validationErrors.email = [];
+
+	if (!isRequiredInputFilled(inputEmail)) {
+		validationErrors.email.push("Email is required");
+		isValid = false;
+	}
+
+	if (!isValidEmail(inputEmail.value)) {
+		validationErrors.email.push("Email must be a valid email address");
+		isValid = false;
+	}
+
+	return isValid;
+}
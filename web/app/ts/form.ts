@@ -66,3 +66,12 @@ export function toggleError(input: HTMLInputElement, message: string) {
 		helpText.style.display = "none";
 	}
 }
+
+// Returns the csrf token that the server sent in the xsrf cookie, which is sent back in the X-XSRF-Token header
+export function csrfToken(): string {
+	const cookie = document.cookie
+		.split("; ")
+		.find((c) => c.startsWith("xsrf="));
+
+	return cookie ? decodeURIComponent(cookie.slice("xsrf=".length)) : "";
+}
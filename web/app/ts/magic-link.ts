@@ -0,0 +1,109 @@
+import {
+	clearFormErrors,
+	csrfToken,
+	handleFormErrors,
+	showFormError,
+	toggleError,
+	updateSubmitBtn,
+} from "./form";
+import { showNotification } from "./notification";
+import { isRequiredInputFilled, isValidEmail } from "./validation";
+
+const frmMagicLink = document.getElementById("frmMagicLink") as HTMLFormElement;
+const inputEmail = frmMagicLink.querySelector("#email") as HTMLInputElement;
+const btnMagicLink = frmMagicLink.querySelector(
+	"#btnMagicLink"
+) as HTMLButtonElement;
+
+const btnAttrs = {
+	btn: btnMagicLink,
+	text: "Send Sign In Link",
+	loadingText: "Sending...",
+};
+
+type ValidationErrors = {
+	email: Errors;
+};
+
+const validationErrors: ValidationErrors = {
+	email: [],
+};
+
+let isLoading = false;
+
+frmMagicLink.addEventListener("change", handleInputChange);
+frmMagicLink.addEventListener("submit", requestLink);
+
+function handleInputChange(event: Event) {
+	const target = event.target as HTMLInputElement;
+	if (target.matches("#email")) {
+		toggleError(target, "");
+
+		if (!isValidEmail(target.value)) {
+			showFormError(target, ["Email must be a valid email address"]);
+		}
+	}
+}
+
+async function requestLink(e: SubmitEvent) {
+	e.preventDefault();
+	isLoading = true;
+	updateSubmitBtn(btnAttrs, isLoading);
+	clearFormErrors(frmMagicLink);
+
+	if (!validate()) {
+		handleFormErrors(validationErrors, frmMagicLink);
+		showNotification("error", "Invalid input!");
+		isLoading = false;
+		updateSubmitBtn(btnAttrs, isLoading);
+		return;
+	}
+
+	try {
+		const res = await fetch(frmMagicLink.action, {
+			method: frmMagicLink.method,
+			headers: {
+				"Content-Type": "application/json",
+				"X-XSRF-Token": csrfToken(),
+			},
+			body: JSON.stringify({
+				email: inputEmail.value.trim(),
+			}),
+		});
+
+		const { message, errors }: APIResponse<undefined> = await res.json();
+
+		if (!res.ok) {
+			errors && handleFormErrors(errors, frmMagicLink);
+			showNotification("error", message);
+			return;
+		}
+
+		showNotification("success", message);
+		frmMagicLink.reset();
+	} catch (error) {
+		console.log("Error while requesting a sign in link:", error);
+		if (error instanceof Error) showNotification("error", error.message);
+	} finally {
+		isLoading = false;
+		updateSubmitBtn(btnAttrs, isLoading);
+	}
+}
+
+function validate(): boolean {
+	let isValid = true;
+
+	validationErrors.email = [];
+
+	if (!isRequiredInputFilled(inputEmail)) {
+		validationErrors.email.push("Email is required");
+		isValid = false;
+	}
+
+	if (!isValidEmail(inputEmail.value)) {
+		validationErrors.email.push("Email must be a valid email address");
+		isValid = false;
+	}
+
+	return isValid;
+}
@@ -0,0 +1,45 @@
+import { updateSubmitBtn } from "./form";
+import { showNotification } from "./notification";
+import { isPasskeySupported, registerPasskey } from "./webauthn";
+
+const frmPasskey = document.getElementById("frmPasskey") as HTMLFormElement;
+const inputName = frmPasskey.querySelector("#passkey-name") as HTMLInputElement;
+const inputCSRF = frmPasskey.querySelector(
+	'input[name="_csrf"]'
+) as HTMLInputElement;
+const btnPasskey = frmPasskey.querySelector("#btnPasskey") as HTMLButtonElement;
+
+const btnAttrs = {
+	btn: btnPasskey,
+	text: "Add a passkey",
+	loadingText: "Waiting for your device...",
+};
+
+if (!isPasskeySupported()) {
+	frmPasskey.hidden = true;
+} else {
+	frmPasskey.addEventListener("submit", addPasskey);
+}
+
+async function addPasskey(e: SubmitEvent) {
+	e.preventDefault();
+	updateSubmitBtn(btnAttrs, true);
+
+	try {
+		const { message, data } = await registerPasskey(
+			inputName.value.trim(),
+			inputCSRF.value
+		);
+
+		showNotification("success", message);
+
+		if (data) {
+			window.location.href = data.redirectUrl;
+		}
+	} catch (error) {
+		console.log("Error while adding a passkey:", error);
+		if (error instanceof Error) showNotification("error", error.message);
+	} finally {
+		updateSubmitBtn(btnAttrs, false);
+	}
+}
@@ -0,0 +1,114 @@
+import {
+	clearFormErrors,
+	csrfToken,
+	handleFormErrors,
+	updateSubmitBtn,
+} from "./form";
+import { showNotification } from "./notification";
+import { isRequiredInputFilled } from "./validation";
+
+const frmResetPassword = document.getElementById(
+	"frmResetPassword"
+) as HTMLFormElement;
+const inputToken = frmResetPassword.querySelector("#token") as HTMLInputElement;
+const inputPassword = frmResetPassword.querySelector(
+	"#password"
+) as HTMLInputElement;
+const inputRetypePass = frmResetPassword.querySelector(
+	"#password_confirmation"
+) as HTMLInputElement;
+const btnResetPassword = frmResetPassword.querySelector(
+	"#btnResetPassword"
+) as HTMLButtonElement;
+
+const btnAttrs = {
+	btn: btnResetPassword,
+	text: "Reset Password",
+	loadingText: "Resetting...",
+};
+
+type ValidationErrors = {
+	password: Errors;
+	password_confirmation: Errors;
+};
+
+const validationErrors: ValidationErrors = {
+	password: [],
+	password_confirmation: [],
+};
+
+let isLoading = false;
+
+frmResetPassword.addEventListener("submit", resetPassword);
+
+async function resetPassword(e: SubmitEvent) {
+	e.preventDefault();
+	isLoading = true;
+	updateSubmitBtn(btnAttrs, isLoading);
+	clearFormErrors(frmResetPassword);
+
+	if (!validate()) {
+		handleFormErrors(validationErrors, frmResetPassword);
+		showNotification("error", "Invalid input!");
+		isLoading = false;
+		updateSubmitBtn(btnAttrs, isLoading);
+		return;
+	}
+
+	try {
+		const res = await fetch(frmResetPassword.action, {
+			method: frmResetPassword.method,
+			headers: {
+				"Content-Type": "application/json",
+				"X-XSRF-Token": csrfToken(),
+			},
+			body: JSON.stringify({
+				token: inputToken.value,
+				password: inputPassword.value.trim(),
+				password_confirmation: inputRetypePass.value.trim(),
+			}),
+		});
+
+		if (!res.ok) {
+			const { message, errors }: APIResponse<undefined> =
+				await res.json();
+
+			errors && handleFormErrors(errors, frmResetPassword);
+			showNotification("error", message);
+		} else {
+			const { message, data }: APIResponse<RedirectData> =
+				await res.json();
+
+			showNotification("success", message);
+
+			if (data) {
+				window.location.href = data.redirectUrl;
+			}
+		}
+	} catch (error) {
+		console.log("Error while resetting the password:", error);
+		if (error instanceof Error) showNotification("error", error.message);
+	} finally {
+		isLoading = false;
+		updateSubmitBtn(btnAttrs, isLoading);
+	}
+}
+
+function validate(): boolean {
+	let isValid = true;
+
+	validationErrors.password = [];
+	validationErrors.password_confirmation = [];
+
+	if (!isRequiredInputFilled(inputPassword)) {
+		validationErrors.password.push("Password is required");
+		isValid = false;
+	}
+
+	if (inputPassword.value !== inputRetypePass.value) {
+		validationErrors.password_confirmation.push("Passwords do not match");
+		isValid = false;
+	}
+
+	return isValid;
+}
@@ -1,5 +1,6 @@
 import {
 	clearFormErrors,
+	csrfToken,
 	handleFormErrors,
 	showFormError,
 	toggleError,
@@ -7,11 +8,16 @@ import {
 } from "./form";
 import { showNotification } from "./notification";
 import { isRequiredInputFilled, isValidEmail } from "./validation";
+import { isPasskeySupported, signInWithPasskey } from "./webauthn";
 
 const frmSignin = document.getElementById("frmSignin") as HTMLFormElement;
 const inputEmail = frmSignin.querySelector("#email") as HTMLInputElement;
 const inputPassword = frmSignin.querySelector("#password") as HTMLInputElement;
+const inputRemember = frmSignin.querySelector("#remember") as HTMLInputElement;
 const btnSignin = frmSignin.querySelector("#btnSignin") as HTMLButtonElement;
+const btnPasskeySignin = frmSignin.querySelector(
+	"#btnPasskeySignin"
+) as HTMLButtonElement;
 
 const btnAttrs = {
 	btn: btnSignin,
@@ -33,6 +39,11 @@ let isLoading = false;
 frmSignin.addEventListener("change", handleInputChange);
 frmSignin.addEventListener("submit", signInUser);
 
+if (isPasskeySupported()) {
+	btnPasskeySignin.hidden = false;
+	btnPasskeySignin.addEventListener("click", signInUserWithPasskey);
+}
+
 function handleInputChange(event: Event) {
 	const target = event.target as HTMLInputElement;
 	if (target.matches("#email")) {
@@ -65,10 +76,12 @@ async function signInUser(e: SubmitEvent) {
 			method: frmSignin.method,
 			headers: {
 				"Content-Type": "application/json",
+				"X-XSRF-Token": csrfToken(),
 			},
 			body: JSON.stringify({
 				email: inputEmail.value.trim(),
 				password: inputPassword.value.trim(),
+				remember: inputRemember.checked,
 			}),
 		});
 
@@ -98,6 +111,31 @@ async function signInUser(e: SubmitEvent) {
 	}
 }
 
+async function signInUserWithPasskey() {
+	const passkeyBtnAttrs = {
+		btn: btnPasskeySignin,
+		text: "Sign in with a passkey",
+		loadingText: "Waiting for your device...",
+	};
+
+	updateSubmitBtn(passkeyBtnAttrs, true);
+
+	try {
+		const { message, data } = await signInWithPasskey();
+
+		showNotification("success", message);
+
+		if (data) {
+			window.location.href = data.redirectUrl;
+		}
+	} catch (error) {
+		console.log("Error during passkey sign-in:", error);
+		if (error instanceof Error) showNotification("error", error.message);
+	} finally {
+		updateSubmitBtn(passkeyBtnAttrs, false);
+	}
+}
+
 function validate(): boolean {
 	let isValid = true;
 
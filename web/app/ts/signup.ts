@@ -1,5 +1,6 @@
 import {
 	clearFormErrors,
+	csrfToken,
 	handleFormErrors,
 	showFormError,
 	toggleError,
@@ -14,6 +15,9 @@ const inputPassword = frmSignup.querySelector("#password") as HTMLInputElement;
 const inputRetypePass = frmSignup.querySelector(
 	"#password_confirmation"
 ) as HTMLInputElement;
+const inputInvitation = frmSignup.querySelector(
+	"#invitation"
+) as HTMLInputElement;
 const btnSignup = frmSignup.querySelector("#btnSignup") as HTMLButtonElement;
 
 const btnAttrs = {
@@ -76,11 +80,13 @@ async function signUpUser(e: SubmitEvent) {
 			method: frmSignup.method,
 			headers: {
 				"Content-Type": "application/json",
+				"X-XSRF-Token": csrfToken(),
 			},
 			body: JSON.stringify({
 				email: inputEmail.value.trim(),
 				password: inputPassword.value.trim(),
 				password_confirmation: inputRetypePass.value.trim(),
+				invitation: inputInvitation.value,
 			}),
 		});
 
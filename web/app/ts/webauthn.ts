@@ -0,0 +1,170 @@
+// Helpers for the WebAuthn ceremonies. The server sends and expects binary
+// values as unpadded base64url strings.
+
+import { csrfToken as readCSRFToken } from "./form";
+
+type CredentialDescriptorJSON = {
+	type: PublicKeyCredentialType;
+	id: string;
+	transports?: AuthenticatorTransport[];
+};
+
+type CreationOptionsJSON = Omit<
+	PublicKeyCredentialCreationOptions,
+	"challenge" | "user" | "excludeCredentials"
+> & {
+	challenge: string;
+	user: { id: string; name: string; displayName: string };
+	excludeCredentials: CredentialDescriptorJSON[];
+};
+
+type RequestOptionsJSON = Omit<
+	PublicKeyCredentialRequestOptions,
+	"challenge" | "allowCredentials"
+> & {
+	challenge: string;
+	allowCredentials: CredentialDescriptorJSON[];
+};
+
+export function isPasskeySupported(): boolean {
+	return (
+		window.PublicKeyCredential !== undefined &&
+		navigator.credentials !== undefined
+	);
+}
+
+function toBase64URL(buffer: ArrayBuffer): string {
+	const bytes = new Uint8Array(buffer);
+	let binary = "";
+	bytes.forEach((b) => (binary += String.fromCharCode(b)));
+
+	return btoa(binary)
+		.replace(/\+/g, "-")
+		.replace(/\//g, "_")
+		.replace(/=+$/, "");
+}
+
+function fromBase64URL(value: string): ArrayBuffer {
+	const base64 = value.replace(/-/g, "+").replace(/_/g, "/");
+	const binary = atob(base64.padEnd(Math.ceil(base64.length / 4) * 4, "="));
+	const bytes = new Uint8Array(binary.length);
+
+	for (let i = 0; i < binary.length; i++) {
+		bytes[i] = binary.charCodeAt(i);
+	}
+
+	return bytes.buffer;
+}
+
+function toDescriptor(
+	descriptor: CredentialDescriptorJSON
+): PublicKeyCredentialDescriptor {
+	return { ...descriptor, id: fromBase64URL(descriptor.id) };
+}
+
+async function postJSON<T>(
+	url: string,
+	body: unknown,
+	csrfToken?: string
+): Promise<APIResponse<T>> {
+	const headers: Record<string, string> = {
+		"Content-Type": "application/json",
+	};
+
+	headers["X-XSRF-Token"] = csrfToken ?? readCSRFToken();
+
+	const res = await fetch(url, {
+		method: "POST",
+		headers,
+		body: JSON.stringify(body),
+	});
+
+	const data: APIResponse<T> = await res.json();
+
+	if (!res.ok) throw new Error(data.message);
+
+	return data;
+}
+
+// Creates a passkey for the signed in user and saves it on the server.
+export async function registerPasskey(
+	name: string,
+	csrfToken: string
+): Promise<APIResponse<RedirectData>> {
+	const { data: options } = await postJSON<CreationOptionsJSON>(
+		"/api/webauthn/register/begin",
+		{},
+		csrfToken
+	);
+
+	if (!options) throw new Error("Missing passkey options");
+
+	const credential = (await navigator.credentials.create({
+		publicKey: {
+			...options,
+			challenge: fromBase64URL(options.challenge),
+			user: { ...options.user, id: fromBase64URL(options.user.id) },
+			excludeCredentials: options.excludeCredentials.map(toDescriptor),
+		},
+	})) as PublicKeyCredential | null;
+
+	if (!credential) throw new Error("No passkey was created");
+
+	const response = credential.response as AuthenticatorAttestationResponse;
+
+	return postJSON<RedirectData>(
+		"/api/webauthn/register/finish",
+		{
+			name,
+			credential: {
+				id: credential.id,
+				rawId: toBase64URL(credential.rawId),
+				type: credential.type,
+				response: {
+					clientDataJSON: toBase64URL(response.clientDataJSON),
+					attestationObject: toBase64URL(response.attestationObject),
+					transports: response.getTransports
+						? response.getTransports()
+						: [],
+				},
+			},
+		},
+		csrfToken
+	);
+}
+
+// Signs in with one of the passkeys the browser has for the site.
+export async function signInWithPasskey(): Promise<APIResponse<RedirectData>> {
+	const { data: options } = await postJSON<RequestOptionsJSON>(
+		"/api/webauthn/login/begin",
+		{}
+	);
+
+	if (!options) throw new Error("Missing passkey options");
+
+	const credential = (await navigator.credentials.get({
+		publicKey: {
+			...options,
+			challenge: fromBase64URL(options.challenge),
+			allowCredentials: options.allowCredentials.map(toDescriptor),
+		},
+	})) as PublicKeyCredential | null;
+
+	if (!credential) throw new Error("No passkey was selected");
+
+	const response = credential.response as AuthenticatorAssertionResponse;
+
+	return postJSON<RedirectData>("/api/webauthn/login/finish", {
+		id: credential.id,
+		rawId: toBase64URL(credential.rawId),
+		type: credential.type,
+		response: {
+			clientDataJSON: toBase64URL(response.clientDataJSON),
+			authenticatorData: toBase64URL(response.authenticatorData),
+			signature: toBase64URL(response.signature),
+			userHandle: response.userHandle
+				? toBase64URL(response.userHandle)
+				: undefined,
+		},
+	});
+}